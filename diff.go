@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// diffDocument is a document compared by diff. Its articles a and b are
+// extracted by the old and the new model, or read from the output of the
+// old and the new run. They are nil if no article was extracted.
+type diffDocument struct {
+	name           string
+	a, b           *util.Article
+	spans          []util.DiffSpan
+	words          int // words of both articles
+	added, removed int
+}
+
+func newDiffDocument(name string, a, b *util.Article) *diffDocument {
+	result := &diffDocument{name: name, a: a, b: b}
+	wordsA, wordsB := articleWords(a), articleWords(b)
+	result.words = len(wordsA) + len(wordsB)
+	result.spans = util.DiffWords(wordsA, wordsB)
+	for _, span := range result.spans {
+		switch span.Op {
+		case util.DiffInsert:
+			result.added += len(span.Words)
+		case util.DiffDelete:
+			result.removed += len(span.Words)
+		}
+	}
+	return result
+}
+
+// articleWords returns the words of the text of article.
+func articleWords(article *util.Article) []string {
+	if article == nil {
+		return nil
+	}
+	return strings.Fields(article.Content())
+}
+
+// change returns the fraction of the words of both articles that were added
+// or removed.
+func (d *diffDocument) change() float64 {
+	if d.words == 0 {
+		return 0
+	}
+	return float64(d.added+d.removed) / float64(d.words)
+}
+
+// changed returns true if the articles differ at all.
+func (d *diffDocument) changed() bool {
+	return d.added+d.removed > 0 || (d.a == nil) != (d.b == nil)
+}
+
+// material returns true if the articles differ in at least minChange of
+// their words or only one of them was extracted.
+func (d *diffDocument) material(minChange float64) bool {
+	return (d.a == nil) != (d.b == nil) || (d.changed() && d.change() >= minChange)
+}
+
+// wordCount formats the number of words of article, or a dash if there's no
+// article.
+func wordCount(article *util.Article) string {
+	if article == nil {
+		return "-"
+	}
+	return fmt.Sprint(len(articleWords(article)))
+}
+
+// wordHunks returns the word differences of spans in the style of git's word
+// diff, with up to context equal words around them. Differences closer than
+// twice the context share a hunk.
+func wordHunks(spans []util.DiffSpan, context int) []string {
+	result := make([]string, 0)
+	hunk := make([]string, 0)
+	for i, span := range spans {
+		text := strings.Join(span.Words, " ")
+		switch span.Op {
+		case util.DiffDelete:
+			hunk = append(hunk, "[-"+text+"-]")
+		case util.DiffInsert:
+			hunk = append(hunk, "{+"+text+"+}")
+		case util.DiffEqual:
+			first, last := i == 0, i == len(spans)-1
+			if !first && !last && len(span.Words) <= 2*context {
+				hunk = append(hunk, span.Words...)
+				continue
+			}
+			n := context
+			if n > len(span.Words) {
+				n = len(span.Words)
+			}
+			if !first {
+				hunk = append(hunk, span.Words[:n]...)
+				if n < len(span.Words) {
+					hunk = append(hunk, "...")
+				}
+				result = append(result, strings.Join(hunk, " "))
+				hunk = hunk[:0]
+			}
+			if !last {
+				if n < len(span.Words) {
+					hunk = append(hunk, "...")
+				}
+				hunk = append(hunk, span.Words[len(span.Words)-n:]...)
+			}
+		}
+	}
+	if len(hunk) > 0 {
+		result = append(result, strings.Join(hunk, " "))
+	}
+	return result
+}
+
+// diffModels extracts the articles of the corpus files with the options
+// opts and the models a and b, which name model files or the weights of opts
+// if empty.
+func diffModels(files []string, a, b, columns string, opts model.Options, limits html.Options) []*diffDocument {
+	extractor := func(path string) *model.Extractor {
+		opts := opts
+		if path != "" {
+			opts.Weights = readWeights(path, columns)
+		}
+		return model.NewExtractorOptions(opts)
+	}
+	extractA, extractB := extractor(a), extractor(b)
+	// Extraction labels the chunks of the document, so both models get a
+	// document of their own.
+	extract := func(ext *model.Extractor, path string) *util.Article {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		opts := limits
+		opts.URL = path
+		doc, err := html.NewDocumentOptions(f, opts)
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			return nil
+		}
+		article, err := ext.Extract(doc)
+		if err != nil {
+			return nil
+		}
+		return article
+	}
+	result := make([]*diffDocument, 0, len(files))
+	for _, path := range files {
+		result = append(result, newDiffDocument(path, extract(extractA, path), extract(extractB, path)))
+	}
+	return result
+}
+
+// articleRecord is an article or input error printed by -json. The text is
+// decoded by type, so the articles read back have the same text as the
+// articles printed.
+type articleRecord struct {
+	util.Article
+	Error string `json:"error"`
+	Text  []struct {
+		Type        string     `json:"type"`
+		Text        string     `json:"text"`
+		Rows        [][]string `json:"rows"`
+		Attribution string     `json:"attribution"`
+		Source      string     `json:"source"`
+		Provider    string     `json:"provider"`
+		URL         string     `json:"url"`
+	} `json:"text"`
+}
+
+// readArticles reads the articles printed by -json from the file at path.
+// It returns the articles by URL and the URLs in the order read. Inputs
+// without an article map to nil.
+func readArticles(path string) (map[string]*util.Article, []string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	articles := make(map[string]*util.Article)
+	order := make([]string, 0)
+	dec := json.NewDecoder(f)
+	for {
+		record := new(articleRecord)
+		if err := dec.Decode(record); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		article := &record.Article
+		for _, v := range record.Text {
+			switch v.Type {
+			case "heading":
+				article.Append(util.Heading(v.Text))
+			case "code":
+				article.Append(util.Code(v.Text))
+			case "table":
+				article.Append(util.Table(v.Rows))
+			case "quote":
+				article.Append(util.Quote{Text: v.Text, Attribution: v.Attribution, Source: v.Source})
+			case "embed":
+				article.Append(util.Embed{Provider: v.Provider, URL: v.URL})
+			default:
+				article.Append(util.Paragraph(v.Text))
+			}
+		}
+		if record.Error != "" {
+			article = nil
+		}
+		location := record.URL
+		if location == "" {
+			location = "-"
+		}
+		if _, ok := articles[location]; !ok {
+			order = append(order, location)
+		}
+		articles[location] = article
+	}
+	return articles, order
+}
+
+// diffArticles pairs the articles of the -json output files a and b by URL.
+func diffArticles(a, b string) []*diffDocument {
+	articlesA, orderA := readArticles(a)
+	articlesB, orderB := readArticles(b)
+	result := make([]*diffDocument, 0, len(orderA))
+	for _, location := range orderA {
+		result = append(result, newDiffDocument(location, articlesA[location], articlesB[location]))
+	}
+	for _, location := range orderB {
+		if _, ok := articlesA[location]; !ok {
+			result = append(result, newDiffDocument(location, nil, articlesB[location]))
+		}
+	}
+	return result
+}
+
+// diff compares the articles extracted from a corpus by two models, or by
+// two runs whose -json output is given, so a retrained model or a new
+// version can be reviewed before it's rolled out. It prints the word
+// differences of the changed documents, the documents that changed
+// materially and aggregate statistics. Like diff(1), it exits with status 1
+// if documents changed materially.
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	modelA := flags.String("a", "", "old model file, the -weights model if empty")
+	modelB := flags.String("b", "", "new model file, the -weights model if empty")
+	articles := flags.Bool("articles", false, "compare two files of articles printed by -json instead of two models")
+	minChange := flags.Float64("min-change", 0.05, "fraction of changed words making a document change materially")
+	contextLen := flags.Int("context", 5, "number of equal words printed around word differences")
+	words := flags.Bool("words", true, "print the word differences of the changed documents")
+	options := optionFlags(flags)
+	limits := limitFlags(flags, 0)
+	flags.Parse(args)
+
+	var docs []*diffDocument
+	if *articles {
+		if flags.NArg() != 2 {
+			log.Fatal("diff -articles needs two files of articles")
+		}
+		docs = diffArticles(flags.Arg(0), flags.Arg(1))
+	} else {
+		if *modelA == *modelB {
+			log.Fatal("diff needs two different models -a and -b")
+		}
+		// The -columns flag of the options names the columns of -a and -b
+		// as well.
+		columns := flags.Lookup("columns").Value.String()
+		docs = diffModels(corpusFiles(flags.Args()), *modelA, *modelB, columns, options(), limits())
+	}
+
+	material := make([]*diffDocument, 0)
+	changed, lost, gained, added, removed := 0, 0, 0, 0, 0
+	var changeSum, confidenceA, confidenceB float64
+	countA, countB := 0, 0
+	for _, doc := range docs {
+		if doc.a != nil {
+			confidenceA += float64(doc.a.Confidence)
+			countA++
+		}
+		if doc.b != nil {
+			confidenceB += float64(doc.b.Confidence)
+			countB++
+		}
+		if !doc.changed() {
+			continue
+		}
+		changed++
+		added, removed = added+doc.added, removed+doc.removed
+		changeSum += doc.change()
+		switch {
+		case doc.b == nil:
+			lost++
+		case doc.a == nil:
+			gained++
+		}
+		if doc.material(*minChange) {
+			material = append(material, doc)
+		}
+		fmt.Printf("%s\t%s -> %s words\t+%d -%d\t%.1f%%\n", doc.name, wordCount(doc.a), wordCount(doc.b), doc.added, doc.removed, 100*doc.change())
+		if *words {
+			for _, hunk := range wordHunks(doc.spans, *contextLen) {
+				fmt.Printf("\t%s\n", hunk)
+			}
+		}
+	}
+
+	if len(material) > 0 {
+		sort.SliceStable(material, func(i, j int) bool {
+			return material[i].change() > material[j].change()
+		})
+		fmt.Printf("\nchanged materially:\n")
+		for _, doc := range material {
+			switch {
+			case doc.a == nil:
+				fmt.Printf("\tnew article\t%s\n", doc.name)
+			case doc.b == nil:
+				fmt.Printf("\tlost article\t%s\n", doc.name)
+			default:
+				fmt.Printf("\t%.1f%%\t%s\n", 100*doc.change(), doc.name)
+			}
+		}
+	}
+
+	mean := func(sum float64, n int) float64 {
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+	fmt.Println()
+	fmt.Printf("%-20s %d\n", "documents", len(docs))
+	fmt.Printf("%-20s %d\n", "unchanged", len(docs)-changed)
+	fmt.Printf("%-20s %d\n", "changed", changed)
+	fmt.Printf("%-20s %d\n", "changed materially", len(material))
+	fmt.Printf("%-20s %d\n", "articles lost", lost)
+	fmt.Printf("%-20s %d\n", "articles gained", gained)
+	fmt.Printf("%-20s %d\n", "words added", added)
+	fmt.Printf("%-20s %d\n", "words removed", removed)
+	fmt.Printf("%-20s %.2f%%\n", "mean change", 100*mean(changeSum, len(docs)))
+	fmt.Printf("%-20s %.3f -> %.3f\n", "mean confidence", mean(confidenceA, countA), mean(confidenceB, countB))
+	if len(material) > 0 {
+		os.Exit(1)
+	}
+}
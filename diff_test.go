@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// span returns the diff span of the words of text.
+func span(op util.DiffOp, text string) util.DiffSpan {
+	return util.DiffSpan{Op: op, Words: strings.Fields(text)}
+}
+
+func TestWordHunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans []util.DiffSpan
+		want  []string
+	}{
+		{"no spans", nil, []string{}},
+		{"equal", []util.DiffSpan{span(util.DiffEqual, "a b c")}, []string{}},
+		{"new article", []util.DiffSpan{span(util.DiffInsert, "a b")}, []string{"{+a b+}"}},
+		{"lost article", []util.DiffSpan{span(util.DiffDelete, "a b")}, []string{"[-a b-]"}},
+		{
+			"separate hunks",
+			[]util.DiffSpan{
+				span(util.DiffEqual, "a b c d e f g"),
+				span(util.DiffDelete, "x"),
+				span(util.DiffInsert, "y"),
+				span(util.DiffEqual, "h i j k l m n o p q"),
+				span(util.DiffInsert, "z"),
+				span(util.DiffEqual, "r s"),
+			},
+			[]string{"... f g [-x-] {+y+} h i ...", "... p q {+z+} r s"},
+		},
+		{
+			"shared hunk",
+			[]util.DiffSpan{
+				span(util.DiffDelete, "x"),
+				span(util.DiffEqual, "a b c d"),
+				span(util.DiffInsert, "y"),
+				span(util.DiffEqual, "e f g"),
+			},
+			[]string{"[-x-] a b c d {+y+} e f ..."},
+		},
+	}
+	for _, test := range tests {
+		if got := wordHunks(test.spans, 2); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReadArticles(t *testing.T) {
+	article := &util.Article{URL: "https://example.com/a", Title: "A story", Confidence: 0.75}
+	article.Append(util.Heading("A story"))
+	article.Append(util.Paragraph("The council met on Tuesday."))
+	article.Append(util.Table{{"Year", "Budget"}, {"2024", "1.2m"}})
+	article.Append(util.Quote{Text: "We will decide next week.", Attribution: "The mayor"})
+	article.Append(util.Code("x := 1"))
+	article.Append(util.Embed{Provider: "youtube", URL: "https://www.youtube.com/embed/x"})
+	replaced := &util.Article{URL: "https://example.com/b"}
+	replaced.Append(util.Paragraph("An old text."))
+	replacing := &util.Article{URL: "https://example.com/b"}
+	replacing.Append(util.Paragraph("A new text."))
+	stdin := &util.Article{}
+	stdin.Append(util.Paragraph("Read from standard input."))
+
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, record := range []interface{}{
+		article,
+		newInputError("https://example.com/missing", stageFetch, util.ErrDisallowed),
+		replaced,
+		stdin,
+		replacing,
+	} {
+		if err := enc.Encode(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "newscat-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "articles.jsonl")
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	articles, order := readArticles(path)
+	if want := []string{"https://example.com/a", "https://example.com/missing", "https://example.com/b", "-"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %q, want %q", order, want)
+	}
+	if got := articles["https://example.com/a"]; got == nil || got.Content() != article.Content() || got.Confidence != article.Confidence {
+		t.Errorf("got article %v, want %v", got, article)
+	}
+	if got, ok := articles["https://example.com/missing"]; !ok || got != nil {
+		t.Errorf("failed input: got %v, %v", got, ok)
+	}
+	if got := articles["https://example.com/b"]; got == nil || got.Content() != replacing.Content() {
+		t.Errorf("repeated input: got %v", got)
+	}
+	if got := articles["-"]; got == nil || got.Content() != stdin.Content() {
+		t.Errorf("standard input: got %v", got)
+	}
+}
+
+func TestDiffModelsOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "newscat-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "story.html")
+	if err := ioutil.WriteFile(path, []byte(testPage(5)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both sides extract with the options given.
+	opts := model.DefaultOptions
+	docs := diffModels([]string{path}, "", "", "", opts, html.Options{})
+	if len(docs) != 1 || docs[0].a == nil || docs[0].b == nil || docs[0].changed() {
+		t.Fatalf("default options: got %+v", docs)
+	}
+	opts.Threshold = 1
+	docs = diffModels([]string{path}, "", "", "", opts, html.Options{})
+	if len(docs) != 1 || docs[0].a != nil || docs[0].b != nil {
+		t.Errorf("threshold 1: got %+v", docs[0])
+	}
+}
@@ -0,0 +1,104 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// Tables with at least that many rows or columns are data tables, unless
+// they contain other tables.
+const (
+	dataTableRows    = 10
+	dataTableColumns = 5
+)
+
+// isDataTable returns true if the table n holds data rather than being used
+// to lay out the page. The rules follow the ones Firefox uses to decide
+// whether tables are announced as tables to screen readers.
+func isDataTable(n *html.Node) bool {
+	switch {
+	case getAttr(n, "role") == "presentation", getAttr(n, "datatable") == "0":
+		return false
+	case getAttr(n, "summary") != "":
+		return true
+	}
+	nested, structure := false, false
+	iterateNode(n, func(c *html.Node) int {
+		if c == n || c.Type != html.ElementNode {
+			return IterNext
+		}
+		switch c.DataAtom {
+		case atom.Table:
+			nested = true
+			return IterStop
+		case atom.Caption, atom.Colgroup, atom.Col, atom.Thead, atom.Tfoot, atom.Th:
+			structure = true
+		}
+		return IterNext
+	})
+	if nested {
+		return false
+	}
+	if structure {
+		return true
+	}
+	rows := tableRows(n)
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	switch {
+	case len(rows) <= 1 || columns <= 1:
+		return false
+	case len(rows) >= dataTableRows || columns >= dataTableColumns:
+		return true
+	}
+	return len(rows)*columns > dataTableRows
+}
+
+// tableRows returns the text of the cells of the table n, row by row.
+// Tables nested in cells aren't descended into.
+func tableRows(n *html.Node) [][]string {
+	rows := make([][]string, 0)
+	iterateNode(n, func(c *html.Node) int {
+		if c.Type != html.ElementNode {
+			return IterNext
+		}
+		switch c.DataAtom {
+		case atom.Table:
+			if c != n {
+				return IterSkip
+			}
+		case atom.Tr:
+			row := make([]string, 0)
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					row = append(row, getText(cell))
+				}
+			}
+			rows = append(rows, row)
+			return IterSkip
+		}
+		return IterNext
+	})
+	return rows
+}
+
+// codeText returns the text of the code block n verbatim, without trailing
+// whitespace. Line breaks are kept, even if they are <br> elements.
+func codeText(n *html.Node) string {
+	var sb strings.Builder
+	iterateNode(n, func(c *html.Node) int {
+		switch {
+		case c.Type == html.TextNode:
+			sb.WriteString(c.Data)
+		case c.DataAtom == atom.Br:
+			sb.WriteByte('\n')
+		}
+		return IterNext
+	})
+	return strings.TrimRight(strings.TrimLeft(sb.String(), "\r\n"), " \t\r\n")
+}
@@ -0,0 +1,34 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	page := `<html><head></head><body>
+		<table><tr><td><p>Layout cell with text.</p></td></tr></table>
+		<table><tr><th>Name</th><th>Votes</th></tr><tr><td>Smith</td><td>42</td></tr></table>
+		<pre>
+func main() {
+	println("hi")
+}
+</pre></body></html>`
+
+	doc, err := NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(doc.Chunks))
+	}
+	if doc.Chunks[0].IsTable() || doc.Chunks[0].Text.String() != "Layout cell with text." {
+		t.Errorf("layout table treated as data table")
+	}
+	if table := doc.Chunks[1].Table; len(table) != 2 || strings.Join(table[1], ",") != "Smith,42" {
+		t.Errorf("unexpected data table %q", table)
+	}
+	if code := doc.Chunks[2].Code; code != "func main() {\n\tprintln(\"hi\")\n}" {
+		t.Errorf("unexpected code block %q", code)
+	}
+}
@@ -0,0 +1,112 @@
+package html
+
+import (
+	"bufio"
+	"bytes"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Number of bytes examined to detect the charset of a document. The HTML5
+// spec limits the prescan for meta elements to 1024 bytes, but plenty of news
+// sites declare their charset after kilobytes of inline scripts and styles.
+const sniffLen = 8192
+
+// newUTF8Reader returns a reader that converts the content of r to UTF-8 and
+// the name of the charset detected. The optional contentType is the value of
+// the HTTP Content-Type header and serves as charset hint. The byte order
+// mark is removed, so it doesn't end up in the text.
+func newUTF8Reader(r io.Reader, contentType string) (io.Reader, string, error) {
+	buf := bufio.NewReaderSize(r, sniffLen)
+	preview, err := buf.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+	enc, name := detectCharset(preview, contentType)
+	if enc != encoding.Nop {
+		buf = bufio.NewReader(enc.NewDecoder().Reader(buf))
+	}
+	if bom, _ := buf.Peek(len(utf8BOM)); string(bom) == utf8BOM {
+		buf.Discard(len(utf8BOM))
+	}
+	return buf, name, nil
+}
+
+// Byte order mark of UTF-8, which is U+FEFF encoded as UTF-8.
+const utf8BOM = "\xef\xbb\xbf"
+
+// detectCharset determines the charset of the document starting with content.
+// Byte order marks and Content-Type charset parameters are trusted most, then
+// come meta elements. If the document doesn't declare its charset, the
+// content is sniffed for valid UTF-8. Everything else is assumed to be
+// windows-1252, the superset of ISO-8859-1 browsers use for undeclared pages.
+func detectCharset(content []byte, contentType string) (encoding.Encoding, string) {
+	if enc, name, certain := charset.DetermineEncoding(content, contentType); certain {
+		return enc, name
+	}
+	if enc, name := metaCharset(content); enc != nil {
+		return enc, name
+	}
+	// Remove a partial rune at the end of content, so cutting the preview
+	// doesn't invalidate UTF-8 text.
+	for i := len(content) - 1; i >= 0 && i > len(content)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(content[i]) {
+			if !utf8.FullRune(content[i:]) {
+				content = content[:i]
+			}
+			break
+		}
+	}
+	if utf8.Valid(content) {
+		return encoding.Nop, "utf-8"
+	}
+	return charmap.Windows1252, "windows-1252"
+}
+
+// metaCharset returns the charset declared by a meta element found in
+// content, either as <meta charset="..."> or as
+// <meta http-equiv="Content-Type" content="text/html; charset=...">.
+func metaCharset(content []byte) (encoding.Encoding, string) {
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return nil, ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if atom.Lookup(name) != atom.Meta {
+				continue
+			}
+			label, httpEquiv, value := "", "", ""
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "charset":
+					label = string(val)
+				case "http-equiv":
+					httpEquiv = strings.ToLower(string(val))
+				case "content":
+					value = string(val)
+				}
+			}
+			if label == "" && httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(value); err == nil {
+					label = params["charset"]
+				}
+			}
+			if label != "" {
+				if enc, name := charset.Lookup(label); enc != nil {
+					return enc, name
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	body := "<body><p>Caf\xe9 au lait co\xfbte 2 \x80.</p></body></html>"
+	tests := []struct {
+		name, page, contentType, charset string
+	}{
+		{"meta charset", `<html><head><meta charset="iso-8859-1"></head>` + body, "", "windows-1252"},
+		{"meta http-equiv", `<html><head><meta http-equiv="Content-Type" content="text/html; charset=windows-1252"></head>` + body, "", "windows-1252"},
+		{"late meta", `<html><head><script>` + strings.Repeat("var x = 1;\n", 400) + `</script><meta charset="windows-1252"></head>` + body, "", "windows-1252"},
+		{"content type", "<html><head></head>" + body, "text/html; charset=ISO-8859-1", "windows-1252"},
+		{"undeclared", "<html><head></head>" + body, "", "windows-1252"},
+		{"utf-8 bom", "\xef\xbb\xbf<html><head><meta charset=\"iso-8859-1\"></head><body><p>Café au lait coûte 2 €.</p></body></html>", "text/html; charset=ISO-8859-1", "utf-8"},
+		{"utf-16le bom", utf16le("\ufeff<html><head></head><body><p>Café au lait coûte 2 €.</p></body></html>"), "", "utf-16le"},
+		{"undeclared utf-8", "<html><head></head><body><p>Café au lait coûte 2 €.</p></body></html>", "", "utf-8"},
+	}
+	for _, test := range tests {
+		doc, err := NewDocumentContentType(strings.NewReader(test.page), test.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if doc.Charset != test.charset {
+			t.Errorf("%s: charset %q, want %q", test.name, doc.Charset, test.charset)
+		}
+		if len(doc.Chunks) != 1 || doc.Chunks[0].Text.String() != "Café au lait coûte 2 €." {
+			t.Errorf("%s: unexpected chunks %v", test.name, doc.Chunks)
+		}
+	}
+}
+
+// utf16le encodes s in UTF-16 little endian.
+func utf16le(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteByte(byte(r))
+		b.WriteByte(byte(r >> 8))
+	}
+	return b.String()
+}
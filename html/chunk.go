@@ -5,6 +5,7 @@ import (
 	"golang.org/x/net/html/atom"
 	"errors"
 	"github.com/slyrz/newscat/util"
+	"net/url"
 	"strings"
 )
 
@@ -30,6 +31,14 @@ type Chunk struct {
 	Classes   []string   // list of classes this chunk belongs to
 	Ancestors int        // bitmask of the ancestors of this chunk
 	LinkText  float32    // link text to normal text ratio.
+	Density   float32    // letters per element of the block node.
+	Depth     int        // number of ancestors of the base node.
+	Included  bool       // chunk belongs to an element matching an include selector
+	Link      string     // resolved target if this chunk is the text of a link
+	Table     [][]string // cells of a data table, row by row
+	Code      string     // verbatim text of a code block
+	Path      string     // XPath of the base node in the original document, if requested
+	Quote     *Quote     // quotation the chunk belongs to, if any
 }
 
 // The list of inline elements was taken from:
@@ -79,33 +88,89 @@ func getParentBlock(n *html.Node) *html.Node {
 	return n
 }
 
+// resolveLink returns the target of href resolved against the base URL of
+// the document. Links to fragments of the document itself and javascript
+// links don't lead anywhere, so an empty string is returned for them.
+func (doc *Document) resolveLink(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	link, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if doc.base != nil {
+		link = doc.base.ResolveReference(link)
+	}
+	return link.String()
+}
+
+// Number of chunks allocated at once by newChunk.
+const chunkSlabLen = 64
+
+// newChunk returns an empty chunk whose text uses the language of doc. The
+// chunks are taken from slabs of the document, so a document with many
+// chunks does few allocations.
+func (doc *Document) newChunk() *Chunk {
+	if len(doc.chunkSlab) == 0 {
+		doc.chunkSlab = make([]Chunk, chunkSlabLen)
+		doc.textSlab = util.NewTexts(chunkSlabLen, doc.Language)
+	}
+	chunk := &doc.chunkSlab[0]
+	chunk.Text = &doc.textSlab[0]
+	doc.chunkSlab, doc.textSlab = doc.chunkSlab[1:], doc.textSlab[1:]
+	return chunk
+}
+
+// hasText returns true if a text node of n contains non-whitespace
+// characters.
+func hasText(n *html.Node) bool {
+	if n.Type == html.TextNode && strings.TrimSpace(n.Data) != "" {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasText(c) {
+			return true
+		}
+	}
+	return false
+}
+
 func NewChunk(doc *Document, n *html.Node) (*Chunk, error) {
-	chunk := new(Chunk)
-	chunk.Text = util.NewText()
+	// We don't allow orphaned Chunks and don't produce Chunks without text.
+	// Both are checked before taking a chunk from the slab, since most text
+	// nodes are whitespace only.
+	if n.Type == html.TextNode && n.Parent == nil {
+		return nil, ErrNoParent
+	}
+	if !hasText(n) {
+		return nil, ErrNoText
+	}
+	chunk := doc.newChunk()
 
 	switch n.Type {
 	// If an ElementNode was passed, create Text property using all
 	// TextNode children.
 	case html.ElementNode:
 		chunk.Base = n
+		switch n.DataAtom {
+		case atom.A:
+			chunk.Link = doc.resolveLink(getAttr(n, "href"))
+		case atom.Table:
+			chunk.Table = tableRows(n)
+		case atom.Pre:
+			chunk.Code = codeText(n)
+		}
 	// If a TextNode was passed, use the parent ElementNode for the
 	// base field.
 	case html.TextNode:
-		// We don't allow orphaned Chunks.
-		if n.Parent == nil {
-			return nil, ErrNoParent
-		}
 		chunk.Base = n.Parent
 	}
 
 	// Write the text of all TextNodes of n to chunk.Text.
 	iterateText(n, chunk.Text.WriteString)
 
-	// Don't produce Chunks without text.
-	if chunk.Text.Len() == 0 {
-		return nil, ErrNoText
-	}
-
 	// Now we detect the HTML block and container of the base node. The block
 	// is the first block-level element found when ascending from base node.
 	// The container is the first block-level element found when ascending
@@ -143,8 +208,17 @@ func NewChunk(doc *Document, n *html.Node) (*Chunk, error) {
 		chunk.Container = chunk.Block
 	}
 
+	for p := chunk.Base.Parent; p != nil; p = p.Parent {
+		chunk.Depth++
+	}
+	if doc.opts.Paths {
+		chunk.Path = doc.NodePath(chunk.Base)
+	}
+
 	// Remember the ancestors in our chunk.
 	chunk.Ancestors = doc.ancestors
+	chunk.Included = doc.included
+	chunk.Quote = doc.quote
 
 	// Calculate the ratio between text inside links and text outside links
 	// for the current element's block node. This is useful to determine the
@@ -158,13 +232,16 @@ func NewChunk(doc *Document, n *html.Node) (*Chunk, error) {
 	//
 	//   <li><a>See also: ...</a></li>
 	//
-	linkText := doc.linkText[chunk.Block]
-	normText := doc.normText[chunk.Block]
+	count := doc.counts[chunk.Block]
+	linkText, normText := count.linkText, count.normText
 	if normText == 0 && linkText == 0 {
 		chunk.LinkText = 0.0
 	} else {
 		chunk.LinkText = float32(linkText) / float32(linkText+normText)
 	}
+	if tags := count.tags; tags > 0 {
+		chunk.Density = float32(linkText+normText) / float32(tags)
+	}
 
 	// Detect the classes of the current node. We use the good old class
 	// attribute and the new HTML5 microdata (itemprop attribute) to determine
@@ -238,3 +315,13 @@ func (ch *Chunk) IsHeading() bool {
 		return false
 	}
 }
+
+// IsTable returns true if the chunk is a data table.
+func (ch *Chunk) IsTable() bool {
+	return len(ch.Table) > 0
+}
+
+// IsCode returns true if the chunk is a code block.
+func (ch *Chunk) IsCode() bool {
+	return ch.Code != ""
+}
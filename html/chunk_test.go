@@ -0,0 +1,32 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkLink(t *testing.T) {
+	page := `<html><head></head><body><p>Read
+		<a href="../report.html">the report</a>,
+		<a href="#footnote">the footnote</a> and
+		<a href="javascript:void(0)">nothing</a>.</p></body></html>`
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: "https://example.com/news/today.html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := make(map[string]string)
+	for _, chunk := range doc.Chunks {
+		links[chunk.Text.String()] = chunk.Link
+	}
+	for text, want := range map[string]string{
+		"Read":         "",
+		"the report":   "https://example.com/report.html",
+		"the footnote": "",
+		"nothing":      "",
+	} {
+		if got, ok := links[text]; !ok || got != want {
+			t.Errorf("link of %q is %q, want %q", text, got, want)
+		}
+	}
+}
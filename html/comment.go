@@ -0,0 +1,148 @@
+package html
+
+import (
+	"github.com/slyrz/newscat/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Class names and ids of single comments, e.g. WordPress uses
+	// <li class="comment" id="comment-42">.
+	commentClass = regexp.MustCompile(`(?i)^(comment|comment[-_]?(item|entry|container|wrapper)|reply)$`)
+	commentId    = regexp.MustCompile(`(?i)^(comment|reply)[-_]?\d+$`)
+
+	commentAuthorClass  = regexp.MustCompile(`(?i)(author|user[-_]?name|nickname|^fn$)`)
+	commentNameClass    = regexp.MustCompile(`(?i)^(fn|name|nickname)$`)
+	commentTimeClass    = regexp.MustCompile(`(?i)(date|time|timestamp|posted)`)
+	commentContentClass = regexp.MustCompile(`(?i)(content|text|message)`)
+	commentIgnoreClass  = regexp.MustCompile(`(?i)(meta|action|reply[-_]?link|vote|avatar|report)`)
+)
+
+// hasClass returns true if one of the class names of n matches r.
+func hasClass(n *html.Node, r *regexp.Regexp) bool {
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		if r.MatchString(class) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment returns true if n contains a single comment.
+func isComment(n *html.Node) bool {
+	// Inline elements like <a class="reply"> are commonly used for buttons.
+	if n.Type != html.ElementNode || inlineElement[n.DataAtom] {
+		return false
+	}
+	if strings.HasSuffix(getAttr(n, "itemtype"), "schema.org/Comment") {
+		return true
+	}
+	return hasClass(n, commentClass) || commentId.MatchString(getAttr(n, "id"))
+}
+
+// findComments returns the comment threads found below n. Comments nested
+// inside other comments are returned as replies.
+func findComments(n *html.Node) []*util.Comment {
+	result := make([]*util.Comment, 0)
+	iterateNode(n, func(c *html.Node) int {
+		if c == n || !isComment(c) {
+			return IterNext
+		}
+		if comment := newComment(c); comment != nil {
+			result = append(result, comment)
+		}
+		return IterSkip
+	})
+	return result
+}
+
+// newComment creates the comment stored in the element n. It returns nil if
+// the comment has neither text nor replies.
+func newComment(n *html.Node) *util.Comment {
+	comment := &util.Comment{Replies: findComments(n)}
+
+	// Find author, timestamp and content elements. Nested comments are
+	// skipped, they were handled above.
+	var author, time, content *html.Node
+	iterateNode(n, func(c *html.Node) int {
+		if c.Type != html.ElementNode || c == n {
+			return IterNext
+		}
+		if isComment(c) {
+			return IterSkip
+		}
+		prop := getAttr(c, "itemprop")
+		switch {
+		case author == nil && (prop == "author" || hasClass(c, commentAuthorClass)):
+			author = c
+			return IterSkip
+		case time == nil && (c.DataAtom == atom.Time || prop == "dateCreated" || prop == "datePublished" || hasClass(c, commentTimeClass)):
+			time = c
+			return IterSkip
+		case content == nil && (prop == "text" || hasClass(c, commentContentClass)):
+			content = c
+			return IterSkip
+		}
+		return IterNext
+	})
+
+	if author != nil {
+		// Author elements often contain additional text like "says:", so
+		// prefer the name if it's marked up separately.
+		name := author
+		iterateNode(author, func(c *html.Node) int {
+			if c.Type == html.ElementNode && (getAttr(c, "itemprop") == "name" || hasClass(c, commentNameClass)) {
+				name = c
+				return IterStop
+			}
+			return IterNext
+		})
+		comment.Author = getText(name)
+	}
+	if time != nil {
+		if comment.Time = getAttr(time, "datetime"); comment.Time == "" {
+			// The time element might be wrapped by a link or span.
+			iterateNode(time, func(c *html.Node) int {
+				if c.DataAtom == atom.Time {
+					comment.Time = getAttr(c, "datetime")
+					return IterStop
+				}
+				return IterNext
+			})
+		}
+		if comment.Time == "" {
+			comment.Time = getText(time)
+		}
+	}
+
+	// Without a dedicated content element, the comment text is made of all
+	// text that is neither author, timestamp, metadata nor reply.
+	if content == nil {
+		content = n
+	}
+	words := make([]string, 0)
+	iterateNode(content, func(c *html.Node) int {
+		switch c.Type {
+		case html.ElementNode:
+			switch {
+			case c == content:
+			case c == author || c == time || isComment(c) || hasClass(c, commentIgnoreClass):
+				return IterSkip
+			case c.DataAtom == atom.Script || c.DataAtom == atom.Style || c.DataAtom == atom.Button:
+				return IterSkip
+			}
+		case html.TextNode:
+			words = append(words, strings.Fields(c.Data)...)
+		}
+		return IterNext
+	})
+	comment.Text = strings.Join(words, " ")
+
+	if comment.Text == "" && len(comment.Replies) == 0 {
+		return nil
+	}
+	return comment
+}
@@ -0,0 +1,79 @@
+package html
+
+import (
+	"github.com/slyrz/newscat/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	const page = `<html><head><title>Story</title></head><body>
+		<div class="article"><p>The council met on Tuesday to discuss the budget.</p></div>
+		<ol class="comment-list">
+			<li class="comment" id="comment-1">
+				<div class="comment-meta">
+					<span class="comment-author"><img class="avatar" src="a.png"><b class="fn">Alice</b> says:</span>
+					<a href="#comment-1"><time datetime="2024-05-01T10:00:00Z">May 1</time></a>
+				</div>
+				<div class="comment-content"><p>Great article.</p></div>
+				<a class="comment-reply-link" href="?reply=1">Reply</a>
+				<ol class="children">
+					<li class="comment" id="comment-2">
+						<span class="comment-author">Bob</span>
+						<span class="comment-date">May 2</span>
+						<p>I disagree.</p>
+						<button>Like</button>
+					</li>
+				</ol>
+			</li>
+			<li id="comment-3" class="comment"><div class="comment-actions">Report</div></li>
+			<li itemscope itemtype="https://schema.org/Comment">
+				<span itemprop="author">Carol</span>
+				<div itemprop="text">Thanks!</div>
+			</li>
+		</ol>
+		</body></html>`
+
+	want := []*util.Comment{
+		{
+			Author: "Alice",
+			Time:   "2024-05-01T10:00:00Z",
+			Text:   "Great article.",
+			Replies: []*util.Comment{
+				{Author: "Bob", Time: "May 2", Text: "I disagree.", Replies: []*util.Comment{}},
+			},
+		},
+		{Author: "Carol", Text: "Thanks!", Replies: []*util.Comment{}},
+	}
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc.Comments, want) {
+		t.Errorf("got comments %s", formatComments(doc.Comments))
+	}
+	for _, chunk := range doc.Chunks {
+		if text := chunk.Text.String(); strings.Contains(text, "disagree") {
+			t.Errorf("comment found in chunk %q", text)
+		}
+	}
+
+	doc, err = NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Comments != nil {
+		t.Errorf("got comments %s without Options.Comments", formatComments(doc.Comments))
+	}
+}
+
+// formatComments formats comments and their replies for error messages.
+func formatComments(comments []*util.Comment) string {
+	parts := make([]string, 0, len(comments))
+	for _, c := range comments {
+		parts = append(parts, "{"+c.Author+"|"+c.Time+"|"+c.Text+" "+formatComments(c.Replies)+"}")
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
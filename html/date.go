@@ -0,0 +1,87 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+	"time"
+)
+
+// Layouts tried when parsing publication dates, after the layouts passed
+// by the options.
+var dateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Meta element names and properties holding publication dates.
+var dateMeta = map[string]bool{
+	"article:published_time": true,
+	"date":                   true,
+	"dc.date":                true,
+	"dc.date.issued":         true,
+	"pubdate":                true,
+	"publishdate":            true,
+	"sailthru.date":          true,
+}
+
+// findDate returns the unparsed publication date of the document. The date
+// selector of the options is trusted most, followed by meta elements and
+// microdata.
+func (doc *Document) findDate() string {
+	result := ""
+	if doc.opts.Date != nil {
+		if n := findFirst(doc.html, doc.opts.Date); n != nil {
+			if result = getAttr(n, "datetime"); result == "" {
+				result = getText(n)
+			}
+			if result != "" {
+				return result
+			}
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode {
+			return IterNext
+		}
+		switch {
+		case n.DataAtom == atom.Meta:
+			key := getAttr(n, "property")
+			if key == "" {
+				key = getAttr(n, "name")
+			}
+			if dateMeta[strings.ToLower(key)] {
+				result = getAttr(n, "content")
+			}
+		case getAttr(n, "itemprop") == "datePublished":
+			if result = getAttr(n, "content"); result == "" {
+				result = getAttr(n, "datetime")
+			}
+		}
+		if result != "" {
+			return IterStop
+		}
+		return IterNext
+	})
+	return result
+}
+
+// parseDate parses the publication date s using the date formats of the
+// options first. It returns the zero time if s can't be parsed.
+func (doc *Document) parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, formats := range [][]string{doc.opts.DateFormats, dateFormats} {
+		for _, format := range formats {
+			if t, err := time.Parse(format, s); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
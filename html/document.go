@@ -1,11 +1,19 @@
 package html
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/slyrz/newscat/util"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 	"io"
+	"log/slog"
+	"net/url"
+	"runtime/debug"
+	"sync"
+	"time"
 	"unicode"
 )
 
@@ -19,50 +27,287 @@ var (
 // Document is a parsed HTML document that extracts the document title and
 // holds unexported pointers to the html, head and body nodes.
 type Document struct {
-	Title  *util.Text // the <title>...</title> text.
-	Chunks []*Chunk   // all chunks found in this document.
+	Title   *util.Text // the <title>...</title> text.
+	Chunks  []*Chunk   // all chunks found in this document.
+	Charset string     // the charset the document was transcoded from.
+
+	// Title candidates found in the document, best first. The Title field
+	// holds the text of the best candidate.
+	Titles []*TitleCandidate
+
+	// Title as found in the og:title metadata or the title element,
+	// including site names. The random forest was trained with headings
+	// compared to this title.
+	RawTitle *util.Text
+
+	// Location of the next page if the document is a page of a paginated
+	// article. It's the unresolved href value as found in the document.
+	NextPage string
+
+	// AMP is true if the document is an AMP page. AMPPage and CanonicalPage
+	// hold the locations of the AMP and the canonical variant of the
+	// document, or empty strings. The AMP variant is declared by a link
+	// element, the canonical variant by a link element or the og:url
+	// property.
+	AMP           bool
+	AMPPage       string
+	CanonicalPage string
+
+	// Location of the lead image declared by the metadata of the document,
+	// like og:image, or an empty string.
+	Image string
+
+	// Publication date of the document or the zero time if unknown.
+	Date time.Time
+
+	// Tags and keywords declared by the metadata of the document.
+	Tags []string
+
+	// Types the document declares for itself by og:type and schema.org
+	// markup, lowercase, like "article" or "blogposting".
+	Types []string
+
+	// Truncated is true if parts of the document were dropped, because it
+	// exceeded the byte or chunk limit of the options.
+	Truncated bool
+
+	// User comments found in the document if Options.Comments is set.
+	Comments []*util.Comment
+
+	// Videos and social media posts embedded in the body, in document order.
+	Embeds []*Embed
+
+	// Quotations of the body, in document order.
+	Quotes []*Quote
+
+	// Language of the document or nil if unknown. It determines the rules
+	// used to calculate the text statistics of chunks.
+	Language *util.Language
 
 	// Unexported fields.
 	html *html.Node // the <html>...</html> part
 	head *html.Node // the <head>...</head> part
 	body *html.Node // the <body>...</body> part
 
+	opts Options
+	base *url.URL // URL the links are resolved against, if any
+
 	// State variables used during parsing.
-	ancestors int                // bitmask to track specific ancestor types
-	linkText  map[*html.Node]int // length of text inside <a></a> tags
-	normText  map[*html.Node]int // length of text outside <a></a> tags
+	ancestors int                       // bitmask to track specific ancestor types
+	included  bool                      // inside an element matching an include selector
+	quote     *Quote                    // quotation of the element being parsed
+	quotes    map[*html.Node]*Quote     // quotations by their elements
+	counts    map[*html.Node]nodeCount  // text and elements inside of nodes
+	positions map[*html.Node]int        // position among the siblings of the same type
+	parents   map[*html.Node]*html.Node // parents in the original document
+
+	// Chunks and their texts are handed out from these slabs, which saves
+	// allocating every chunk separately.
+	chunkSlab []Chunk
+	textSlab  []util.Text
+}
+
+// nodeCount holds the numbers counted for a node by countText.
+type nodeCount struct {
+	linkText int // length of text inside <a></a> tags
+	normText int // length of text outside <a></a> tags
+	tags     int // number of elements, including the node itself
+}
+
+// nodeCountPool holds the maps of nodeCounts. They are needed while parsing
+// the body only, so documents return them afterwards.
+var nodeCountPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[*html.Node]nodeCount)
+	},
+}
+
+// Options control how documents are parsed.
+type Options struct {
+	// The HTTP Content-Type header of the document. Its charset parameter
+	// is used to transcode the document, unless the data starts with a byte
+	// order mark.
+	ContentType string
+
+	// Elements matching one of the Include selectors are never ignored and
+	// their chunks are marked as included. Elements matching one of the
+	// Exclude selectors are removed before the body is parsed.
+	Include []*Selector
+	Exclude []*Selector
+
+	// Site-specific hints, usually taken from a Rule. Title, Date and
+	// NextPage select the elements holding the title, the publication date
+	// and the link to the next page; they take precedence over the
+	// heuristics. DateFormats are the time layouts the date is parsed with.
+	Title       *Selector
+	Date        *Selector
+	NextPage    *Selector
+	DateFormats []string
+
+	// Comments enables collecting the user comments of the document into
+	// its Comments field. Comments are excluded from the chunks either way.
+	Comments bool
+
+	// Paths enables recording the XPaths of the chunks and embeds in the
+	// original document. NodePath only matches the original document for
+	// documents parsed with Paths.
+	Paths bool
+
+	// Limits guarding against huge documents; zero means unlimited.
+	// Documents exceeding MaxBytes or MaxChunks are truncated, documents
+	// exceeding MaxNodes are rejected with ErrTooManyNodes. Nodes are
+	// counted while reading already, so the parsing of huge documents stops
+	// early.
+	MaxBytes  int64
+	MaxNodes  int
+	MaxChunks int
+
+	// Location of the document. The targets of links are resolved against
+	// the href of the base element, resolved against URL, or URL itself. If
+	// neither is an absolute URL, like the name of a local file, the
+	// canonical location declared by the document takes the place of URL.
+	// The canonical location never overrides an absolute URL, because the
+	// links of variants like AMP pages are relative to the variant.
+	URL string
+
+	// Logger of the parsing, if not nil.
+	Logger *slog.Logger
 }
 
 // NewDocument parses the HTML data provided through an io.Reader interface.
+// The data is transcoded to UTF-8 based on the charset the document declares
+// or, if it declares none, the charset guessed from its content.
 func NewDocument(r io.Reader) (*Document, error) {
-	root, err := html.Parse(r)
+	return NewDocumentOptions(r, Options{})
+}
+
+// NewDocumentContentType works like NewDocument, but uses the charset
+// parameter of contentType, usually taken from the HTTP Content-Type header,
+// unless the data starts with a byte order mark.
+func NewDocumentContentType(r io.Reader, contentType string) (*Document, error) {
+	return NewDocumentOptions(r, Options{ContentType: contentType})
+}
+
+// NewDocumentOptions works like NewDocument, but parses the document as
+// requested by opts.
+func NewDocumentOptions(r io.Reader, opts Options) (*Document, error) {
+	return NewDocumentContext(context.Background(), r, opts)
+}
+
+// NewDocumentContext works like NewDocumentOptions, but stops reading and
+// parsing the document once ctx is done. It returns the error of ctx then.
+func NewDocumentContext(ctx context.Context, r io.Reader, opts Options) (*Document, error) {
+	start := time.Now()
+	root, limit, name, err := parse(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := newDocument(ctx, root, opts)
 	if err != nil {
 		return nil, err
 	}
+	doc.Charset = name
+	doc.Truncated = doc.Truncated || limit != nil && limit.truncated
+	doc.logParsed(start)
+	return doc, nil
+}
+
+// logParsed logs the document, whose parsing started at start.
+func (doc *Document) logParsed(start time.Time) {
+	logger := util.Logger(doc.opts.Logger)
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []interface{}{
+		"url", doc.opts.URL,
+		"elapsed", time.Since(start),
+		"chunks", len(doc.Chunks),
+		"title", doc.Title.String(),
+	}
+	if doc.Charset != "" {
+		attrs = append(attrs, "charset", doc.Charset)
+	}
+	if doc.Language != nil {
+		attrs = append(attrs, "language", doc.Language.Code)
+	}
+	if doc.Truncated {
+		attrs = append(attrs, "truncated", true)
+	}
+	logger.Debug("parsed document", attrs...)
+}
+
+// URL returns the location of the document given by the options.
+func (doc *Document) URL() string {
+	return doc.opts.URL
+}
+
+// NewDocumentNode works like NewDocumentOptions, but takes the already parsed
+// HTML n, which saves parsing the document again. n is either a complete
+// document, the html element, or a fragment of the body, like the element
+// holding the article. The tree of n is modified: elements that never contain
+// article text are removed from it.
+func NewDocumentNode(n *html.Node, opts Options) (*Document, error) {
+	return NewDocumentNodeContext(context.Background(), n, opts)
+}
+
+// NewDocumentNodeContext works like NewDocumentNode, but stops parsing the
+// document once ctx is done. It returns the error of ctx then.
+func NewDocumentNodeContext(ctx context.Context, n *html.Node, opts Options) (*Document, error) {
+	start := time.Now()
+	doc, err := newDocument(ctx, n, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc.logParsed(start)
+	return doc, nil
+}
+
+// newDocument creates the document of the parsed HTML root. Trees breaking
+// the assumptions of the parsing return ErrMalformed instead of panicking;
+// the panic is logged with its stack trace, so the bug can be found.
+func newDocument(ctx context.Context, root *html.Node, opts Options) (result *Document, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.MaxNodes > 0 && countNodes(root, opts.MaxNodes) > opts.MaxNodes {
+		return nil, ErrTooManyNodes
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			util.Logger(opts.Logger).Error("parsing panicked", "url", opts.URL, "panic", r, "stack", string(debug.Stack()))
+			result, err = nil, fmt.Errorf("%w: %v", ErrMalformed, r)
+		}
+	}()
+	trimHints(root)
 
 	doc := &Document{
-		Title:    util.NewText(),
-		Chunks:   make([]*Chunk, 0, 512),
-		linkText: make(map[*html.Node]int),
-		normText: make(map[*html.Node]int),
+		Title:  util.NewText(),
+		Chunks: make([]*Chunk, 0, 512),
+		opts:   opts,
 	}
 
-	// Assign the fields html, head and body from the HTML page.
-	iterateNode(root, func(n *html.Node) int {
-		switch n.DataAtom {
-		case atom.Html:
-			doc.html = n
+	if root.Type == html.ElementNode && root.DataAtom != atom.Html {
+		// Fragments of the body lack the html, head and body elements. The
+		// root of the fragment takes their place.
+		doc.html, doc.head, doc.body = root, root, root
+	} else {
+		// Assign the fields html, head and body from the HTML page.
+		iterateNode(root, func(n *html.Node) int {
+			switch n.DataAtom {
+			case atom.Html:
+				doc.html = n
+				return IterNext
+			case atom.Body:
+				doc.body = n
+				return IterSkip
+			case atom.Head:
+				doc.head = n
+				return IterSkip
+			}
+			// Keep going as long as we're missing some nodes.
 			return IterNext
-		case atom.Body:
-			doc.body = n
-			return IterSkip
-		case atom.Head:
-			doc.head = n
-			return IterSkip
-		}
-		// Keep going as long as we're missing some nodes.
-		return IterNext
-	})
+		})
+	}
 
 	switch {
 	case doc.html == nil:
@@ -73,44 +318,56 @@ func NewDocument(r io.Reader) (*Document, error) {
 		return nil, ErrNoBody
 	}
 
-	// Detect the document title: First check if the document provides
-	// Open Graph metadata; if so, use the metadata rather than the
-	// value of the title element, because the metadata tends to be a tad
-	// cleaner.
-	title := ""
-	iterateNode(doc.head, func(n *html.Node) int {
-		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
-			prop, content := "", ""
-			for _, attr := range n.Attr {
-				switch attr.Key {
-				case "property":
-					prop = attr.Val
-				case "content":
-					content = attr.Val
-				}
-			}
-			if prop == "og:title" && content != "" {
-				title = content
-				return IterStop
-			}
-		}
-		return IterNext
-	})
-	if title != "" {
-		doc.Title.WriteString(title)
-	} else {
-		iterateNode(doc.head, func(n *html.Node) int {
-			if n.Type == html.ElementNode && n.DataAtom == atom.Title {
-				iterateText(n, doc.Title.WriteString)
-				return IterStop
-			}
-			return IterNext
-		})
+	// Links are resolved against the base element, the location of the
+	// document or, if it has none, its canonical location. They are
+	// resolved once the base is known.
+	doc.findBase()
+	doc.findCanonical()
+
+	// Detect the document title: The title element, metadata and the
+	// first headings compete for it.
+	doc.Titles = doc.findTitles()
+	if len(doc.Titles) > 0 {
+		doc.Title = doc.Titles[0].Text
 	}
+	doc.RawTitle = doc.findRawTitle()
+	doc.Date = doc.parseDate(doc.findDate())
+	doc.Tags = doc.findTags()
+	doc.Types = doc.findTypes()
+	doc.findVariants()
+	doc.Image = doc.findImage()
 
+	// Search pagination links before cleaning the body, because they are
+	// often part of nav elements.
+	doc.NextPage = doc.findNextPage()
+
+	// Comments are excluded from the chunks, but they are collected
+	// separately if requested. Comment sections often reside in removed
+	// elements.
+	if doc.opts.Comments {
+		doc.Comments = findComments(doc.body)
+	}
+	// Paths refer to the original document, so the positions are counted
+	// before quotes are moved and the body is cleaned.
+	if doc.opts.Paths {
+		doc.positions = make(map[*html.Node]int)
+		doc.parents = make(map[*html.Node]*html.Node)
+		doc.countPositions(doc.html)
+	}
+	doc.findQuotes(doc.body)
 	doc.cleanBody(doc.body, 0)
+	doc.Language = doc.detectLanguage()
+	doc.counts = nodeCountPool.Get().(map[*html.Node]nodeCount)
+	defer doc.releaseCounts()
 	doc.countText(doc.body, false)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	doc.parseBody(doc.body)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc.findPullQuotes()
 
 	// Now we link the chunks.
 	min, max := 0, len(doc.Chunks)-1
@@ -125,6 +382,80 @@ func NewDocument(r io.Reader) (*Document, error) {
 	return doc, nil
 }
 
+// releaseCounts returns the map of nodeCounts to the pool once the body is
+// parsed.
+func (doc *Document) releaseCounts() {
+	for n := range doc.counts {
+		delete(doc.counts, n)
+	}
+	nodeCountPool.Put(doc.counts)
+	doc.counts = nil
+}
+
+// newDataReader returns the reader of the HTML data r transcoded to UTF-8
+// and limited as requested by opts, the limit of the bytes read from r and
+// the name of the charset of r.
+func newDataReader(ctx context.Context, r io.Reader, opts Options) (io.Reader, *limitReader, string, error) {
+	r = &contextReader{ctx: ctx, r: r}
+	var limit *limitReader
+	if opts.MaxBytes > 0 {
+		limit = &limitReader{r: r, n: opts.MaxBytes}
+		r = limit
+	}
+	r, name, err := newUTF8Reader(r, opts.ContentType)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if opts.MaxNodes > 0 {
+		r = newNodeLimitReader(r, opts.MaxNodes)
+	}
+	return r, limit, name, nil
+}
+
+// contextReader is an io.Reader that fails with the error of its context
+// once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// detectLanguage determines the language of the document. The lang attribute
+// of the html element is trusted most. If it's missing or names an unknown
+// language, the language is detected from the body text.
+func (doc *Document) detectLanguage() *util.Language {
+	for _, attr := range doc.html.Attr {
+		switch attr.Key {
+		case "lang", "xml:lang":
+			if lang := util.LookupLanguage(attr.Val); lang != nil {
+				return lang
+			}
+		}
+	}
+
+	// Don't look at more text than this.
+	const maxSample = 1 << 14
+
+	sample := new(bytes.Buffer)
+	iterateNode(doc.body, func(n *html.Node) int {
+		if n.Type == html.TextNode {
+			sample.WriteString(n.Data)
+			sample.WriteByte(' ')
+		}
+		if sample.Len() > maxSample {
+			return IterStop
+		}
+		return IterNext
+	})
+	return util.LookupLanguage(util.DetectLanguage(sample.String()))
+}
+
 const (
 	// We remember a few special node types when descending into their
 	// children.
@@ -132,6 +463,13 @@ const (
 	AncestorAside
 	AncestorBlockquote
 	AncestorList
+	AncestorNav
+	AncestorHeader
+	AncestorFooter
+	AncestorMain
+	AncestorSection
+	AncestorFigure // figure and figcaption
+	AncestorTable
 )
 
 // countText counts the text inside of links and the text outside of links
@@ -143,10 +481,15 @@ func (doc *Document) countText(n *html.Node, insideLink bool) (linkText int, nor
 	if n.Type == html.ElementNode && n.DataAtom == atom.A {
 		insideLink = true
 	}
+	tags := 0
+	if n.Type == html.ElementNode {
+		tags = 1
+	}
 	for s := n.FirstChild; s != nil; s = s.NextSibling {
 		linkTextChild, normTextChild := doc.countText(s, insideLink)
 		linkText += linkTextChild
 		normText += normTextChild
+		tags += doc.counts[s].tags
 	}
 	if n.Type == html.TextNode {
 		count := 0
@@ -161,8 +504,7 @@ func (doc *Document) countText(n *html.Node, insideLink bool) (linkText int, nor
 			normText += count
 		}
 	}
-	doc.linkText[n] = linkText
-	doc.normText[n] = normText
+	doc.counts[n] = nodeCount{linkText, normText, tags}
 	return
 }
 
@@ -193,10 +535,51 @@ var removeElements = map[atom.Atom]bool{
 	atom.Video:      true,
 }
 
-// cleanBody removes unwanted HTML elements from the HTML body.
+// Elements removed even inside of included elements. They never contain
+// article text.
+var removeAlways = map[atom.Atom]bool{
+	atom.Audio:    true,
+	atom.Button:   true,
+	atom.Canvas:   true,
+	atom.Frame:    true,
+	atom.Iframe:   true,
+	atom.Map:      true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Option:   true,
+	atom.Output:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Textarea: true,
+	atom.Video:    true,
+}
+
+// matchAny returns true if n matches one of the selectors.
+func matchAny(selectors []*Selector, n *html.Node) bool {
+	for _, sel := range selectors {
+		if sel.Match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanBody removes unwanted HTML elements from the HTML body. Elements
+// matching an exclude selector are removed as well, whereas included elements
+// only lose children that never contain article text.
 func (doc *Document) cleanBody(n *html.Node, level int) {
 	// removeNode returns true if a node should be removed from HTML document.
 	removeNode := func(c *html.Node, level int) bool {
+		switch {
+		case matchAny(doc.opts.Exclude, c):
+			return true
+		case doc.findEmbed(c) != nil:
+			return false
+		case doc.included || matchAny(doc.opts.Include, c):
+			return removeAlways[c.DataAtom]
+		}
 		return removeElements[c.DataAtom]
 	}
 
@@ -211,7 +594,10 @@ func (doc *Document) cleanBody(n *html.Node, level int) {
 			if removeNode(curr, level) {
 				n.RemoveChild(curr)
 			} else {
+				included := doc.included
+				doc.included = included || matchAny(doc.opts.Include, curr)
 				doc.cleanBody(curr, level+1)
+				doc.included = included
 			}
 		}
 	}
@@ -246,9 +632,23 @@ var (
 func (doc *Document) parseBody(n *html.Node) {
 	switch n.Type {
 	case html.ElementNode:
+		// Parse included elements and their children in included state.
+		if !doc.included && matchAny(doc.opts.Include, n) {
+			doc.included = true
+			doc.parseBody(n)
+			doc.included = false
+			return
+		}
+		// Embedded videos and posts don't contain article text, but we
+		// remember where they are.
+		if embed := doc.findEmbed(n); embed != nil {
+			doc.Embeds = append(doc.Embeds, embed)
+			return
+		}
 		// We ignore the node if it has some nasty classes/ids/itemprops or if
-		// its style attribute contains "display: none".
-		if n.DataAtom != atom.Body && n.DataAtom != atom.Article {
+		// its style attribute contains "display: none". Included nodes are
+		// never ignored.
+		if n != doc.body && n.DataAtom != atom.Article && !doc.included {
 			for _, attr := range n.Attr {
 				switch attr.Key {
 				case "id", "class", "itemprop":
@@ -271,7 +671,14 @@ func (doc *Document) parseBody(n *html.Node) {
 		// would make things unnecessary complicated and our results noisy.
 		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.A:
 			if chunk, err := NewChunk(doc, n); err == nil {
-				doc.Chunks = append(doc.Chunks, chunk)
+				doc.addChunk(chunk)
+			}
+			return
+		// Code blocks and data tables are kept in one piece as well, so their
+		// whitespace and cells survive.
+		case atom.Pre:
+			if chunk, err := NewChunk(doc, n); err == nil {
+				doc.addChunk(chunk)
 			}
 			return
 		// Now mask the element type, but only if it isn't already set.
@@ -286,17 +693,44 @@ func (doc *Document) parseBody(n *html.Node) {
 			ancestorMask = AncestorBlockquote &^ doc.ancestors
 		case atom.Ul, atom.Ol:
 			ancestorMask = AncestorList &^ doc.ancestors
+		case atom.Nav:
+			ancestorMask = AncestorNav &^ doc.ancestors
+		case atom.Header:
+			ancestorMask = AncestorHeader &^ doc.ancestors
+		case atom.Footer:
+			ancestorMask = AncestorFooter &^ doc.ancestors
+		case atom.Main:
+			ancestorMask = AncestorMain &^ doc.ancestors
+		case atom.Section:
+			ancestorMask = AncestorSection &^ doc.ancestors
+		case atom.Figure, atom.Figcaption:
+			ancestorMask = AncestorFigure &^ doc.ancestors
+		case atom.Table:
+			ancestorMask = AncestorTable &^ doc.ancestors
+			if isDataTable(n) {
+				doc.ancestors |= ancestorMask
+				if chunk, err := NewChunk(doc, n); err == nil {
+					doc.addChunk(chunk)
+				}
+				doc.ancestors &^= ancestorMask
+				return
+			}
 		}
 		// Add our mask to the ancestor bitmask.
 		doc.ancestors |= ancestorMask
+		quote := doc.quote
+		if q, ok := doc.quotes[n]; ok {
+			doc.quote = q
+		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
 			doc.parseBody(c)
 		}
+		doc.quote = quote
 		// Remove our mask from the ancestor bitmask.
 		doc.ancestors &^= ancestorMask
 	case html.TextNode:
 		if chunk, err := NewChunk(doc, n); err == nil {
-			doc.Chunks = append(doc.Chunks, chunk)
+			doc.addChunk(chunk)
 		}
 	}
 }
@@ -308,10 +742,25 @@ type TextStat struct {
 	Count     int // number of texts used to calculate this stats
 }
 
+// textStatSlab hands out TextStats allocated at once.
+type textStatSlab []TextStat
+
+// New returns a TextStat holding the stats of chunk. The slab must be large
+// enough, since growing it would move the TextStats handed out before.
+func (slab *textStatSlab) New(chunk *Chunk) *TextStat {
+	*slab = append(*slab, TextStat{chunk.Text.Words, chunk.Text.Sentences, 1})
+	return &(*slab)[len(*slab)-1]
+}
+
 // GetClassStats groups the document chunks by their classes (defined by the
 // class attribute of HTML nodes) and calculates TextStats for each class.
 func (doc *Document) GetClassStats() map[string]*TextStat {
-	result := make(map[string]*TextStat)
+	classes := 0
+	for _, chunk := range doc.Chunks {
+		classes += len(chunk.Classes)
+	}
+	result := make(map[string]*TextStat, classes)
+	slab := make(textStatSlab, 0, classes)
 	for _, chunk := range doc.Chunks {
 		for _, class := range chunk.Classes {
 			if stat, ok := result[class]; ok {
@@ -319,13 +768,21 @@ func (doc *Document) GetClassStats() map[string]*TextStat {
 				stat.Sentences += chunk.Text.Sentences
 				stat.Count += 1
 			} else {
-				result[class] = &TextStat{chunk.Text.Words, chunk.Text.Sentences, 1}
+				result[class] = slab.New(chunk)
 			}
 		}
 	}
 	return result
 }
 
+// ancestorStatPool holds the maps GetClusterStats uses to count the
+// TextStats of the ancestors of chunks.
+var ancestorStatPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[*html.Node]*TextStat)
+	},
+}
+
 // GetClusterStats groups the document chunks by common ancestors and
 // calculates TextStats for each group of chunks.
 func (doc *Document) GetClusterStats() map[*Chunk]*TextStat {
@@ -333,7 +790,14 @@ func (doc *Document) GetClusterStats() map[*Chunk]*TextStat {
 	const maxAncestors = 3
 
 	// Count TextStats for Chunk ancestors.
-	ancestorStat := make(map[*html.Node]*TextStat)
+	ancestorStat := ancestorStatPool.Get().(map[*html.Node]*TextStat)
+	defer func() {
+		for node := range ancestorStat {
+			delete(ancestorStat, node)
+		}
+		ancestorStatPool.Put(ancestorStat)
+	}()
+	slab := make(textStatSlab, 0, maxAncestors*len(doc.Chunks))
 	for _, chunk := range doc.Chunks {
 		node, count := chunk.Block, 0
 		for node != nil && count < maxAncestors {
@@ -342,14 +806,14 @@ func (doc *Document) GetClusterStats() map[*Chunk]*TextStat {
 				stat.Sentences += chunk.Text.Sentences
 				stat.Count += 1
 			} else {
-				ancestorStat[node] = &TextStat{chunk.Text.Words, chunk.Text.Sentences, 1}
+				ancestorStat[node] = slab.New(chunk)
 			}
 			node, count = node.Parent, count+1
 		}
 	}
 
 	// Generate result. For each chunk pick the best TextStats from its ancestors.
-	result := make(map[*Chunk]*TextStat)
+	result := make(map[*Chunk]*TextStat, len(doc.Chunks))
 	for _, chunk := range doc.Chunks {
 		node := chunk.Block
 		if node == nil {
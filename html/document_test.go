@@ -0,0 +1,70 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestDocumentNode(t *testing.T) {
+	fragment := `<div class="story"><h1>Headline</h1><p>First paragraph.</p>
+		<div class="comment">Spam</div><p>Second paragraph.</p></div>`
+
+	root, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewDocumentNode(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Chunks) != 3 {
+		t.Errorf("document: got %d chunks, want 3", len(doc.Chunks))
+	}
+
+	root, err = html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	div := root.FirstChild.LastChild.FirstChild
+	doc, err = NewDocumentNode(div, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, len(doc.Chunks))
+	for i, chunk := range doc.Chunks {
+		texts[i] = chunk.Text.String()
+	}
+	if got := strings.Join(texts, "|"); got != "Headline|First paragraph.|Second paragraph." {
+		t.Errorf("fragment: got chunks %q", got)
+	}
+	if doc.Title.String() != "Headline" {
+		t.Errorf("fragment: got title %q", doc.Title.String())
+	}
+}
+
+// benchmarkPage returns a news page with navigation, an article of n
+// paragraphs and a footer.
+func benchmarkPage(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>A benchmark story</title></head><body>`)
+	b.WriteString(`<div class="nav"><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li></ul></div>`)
+	b.WriteString(`<div class="article"><h1>A benchmark story</h1>`)
+	for i := 0; i < n; i++ {
+		b.WriteString(`<p class="text">The council met on Tuesday to discuss the budget. Members argued about
+			the <a href="/roads">road repairs</a> for hours, but no decision was reached. The vote
+			was postponed until next week, when the mayor returns.</p>`)
+	}
+	b.WriteString(`</div><div class="footer"><p>Copyright 2024 <a href="/about">About us</a></p></div></body></html>`)
+	return b.String()
+}
+
+func BenchmarkNewDocument(b *testing.B) {
+	page := benchmarkPage(50)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDocument(strings.NewReader(page)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strings"
+)
+
+// Embed is an embedded video or social media post found in the body.
+type Embed struct {
+	Provider string // "youtube", "vimeo", "twitter" or "instagram"
+	URL      string // resolved location of the embedded content
+	Position int    // index of the first chunk following the embed
+	Path     string // XPath of the embedding element in the original document, if requested
+}
+
+// Hosts of the embedded content, including their subdomains.
+var embedProviders = map[string]string{
+	"youtube.com":          "youtube",
+	"youtube-nocookie.com": "youtube",
+	"youtu.be":             "youtube",
+	"vimeo.com":            "vimeo",
+	"twitter.com":          "twitter",
+	"x.com":                "twitter",
+	"instagram.com":        "instagram",
+}
+
+// Classes of the blockquote elements the embed scripts of Twitter and
+// Instagram replace by the actual posts.
+var embedClass = regexp.MustCompile(`^(twitter-tweet|twitter-video|instagram-media)$`)
+
+// embedProvider returns the provider hosting the content at location or an
+// empty string if the provider is unknown.
+func embedProvider(location string) string {
+	host := location
+	if i := strings.Index(host, "//"); i >= 0 {
+		host = host[i+2:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	for host = strings.ToLower(host); host != ""; {
+		if provider, ok := embedProviders[host]; ok {
+			return provider
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return ""
+}
+
+// findEmbed returns the embed n represents or nil if n isn't an iframe of a
+// known provider or a blockquote of a social media post.
+func (doc *Document) findEmbed(n *html.Node) *Embed {
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	location := ""
+	switch {
+	case n.DataAtom == atom.Iframe:
+		if location = getAttr(n, "src"); location == "" {
+			location = getAttr(n, "data-src")
+		}
+	case n.DataAtom == atom.Blockquote && hasClass(n, embedClass):
+		if location = getAttr(n, "data-instgrm-permalink"); location != "" {
+			break
+		}
+		// The link to the post itself comes last, after the links of the
+		// post text.
+		iterateNode(n, func(c *html.Node) int {
+			if c.DataAtom == atom.A {
+				if href := getAttr(c, "href"); embedProvider(href) != "" {
+					location = href
+				}
+			}
+			return IterNext
+		})
+	default:
+		return nil
+	}
+	if location = doc.resolveLink(location); strings.HasPrefix(location, "//") {
+		location = "https:" + location
+	}
+	if provider := embedProvider(location); provider != "" {
+		embed := &Embed{Provider: provider, URL: location, Position: len(doc.Chunks)}
+		if doc.opts.Paths {
+			embed.Path = doc.NodePath(n)
+		}
+		return embed
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeds(t *testing.T) {
+	page := `<html><head></head><body>
+		<p>First paragraph.</p>
+		<iframe src="//www.youtube-nocookie.com/embed/abc"></iframe>
+		<iframe src="https://ads.example.com/frame"></iframe>
+		<blockquote class="twitter-tweet"><p>Text <a href="https://x.com/hashtag/news">#news</a></p>
+		<a href="https://x.com/someone/status/1">May 1</a></blockquote>
+		<blockquote class="instagram-media" data-instgrm-permalink="https://www.instagram.com/p/xyz/"></blockquote>
+		<p>Second paragraph.</p></body></html>`
+
+	doc, err := NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Embed{
+		{Provider: "youtube", URL: "https://www.youtube-nocookie.com/embed/abc", Position: 1},
+		{Provider: "twitter", URL: "https://x.com/someone/status/1", Position: 1},
+		{Provider: "instagram", URL: "https://www.instagram.com/p/xyz/", Position: 1},
+	}
+	if len(doc.Embeds) != len(want) {
+		t.Fatalf("got %d embeds, want %d", len(doc.Embeds), len(want))
+	}
+	for i, embed := range doc.Embeds {
+		if embed.Provider != want[i].Provider || embed.URL != want[i].URL || embed.Position != want[i].Position {
+			t.Errorf("embed %d is %+v, want %+v", i, *embed, want[i])
+		}
+	}
+	if len(doc.Chunks) != 2 {
+		t.Errorf("got %d chunks, want 2", len(doc.Chunks))
+	}
+}
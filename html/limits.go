@@ -0,0 +1,102 @@
+package html
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/html"
+	"io"
+)
+
+// ErrTooManyNodes is returned if a document exceeds the node limit of its
+// options.
+var ErrTooManyNodes = errors.New("document contains too many nodes")
+
+// limitReader reads up to n bytes from r and remembers if r had more data.
+type limitReader struct {
+	r         io.Reader
+	n         int64
+	truncated bool
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		// Peek a single byte to tell exhausted and truncated data apart.
+		var b [1]byte
+		if n, _ := lr.r.Read(b[:]); n > 0 {
+			lr.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
+}
+
+// nodeLimitReader passes the HTML data of a tokenizer through and fails with
+// ErrTooManyNodes once the tags and texts read exceed max. The parser builds
+// a node of nearly every one of them, so huge documents are rejected before
+// their tree takes up memory. Whitespace is often dropped by the parser and
+// isn't counted.
+type nodeLimitReader struct {
+	z     *html.Tokenizer
+	max   int
+	nodes int
+	buf   []byte // data of the last token not read yet
+	err   error
+}
+
+func newNodeLimitReader(r io.Reader, max int) *nodeLimitReader {
+	return &nodeLimitReader{z: html.NewTokenizer(r), max: max}
+}
+
+func (nr *nodeLimitReader) Read(p []byte) (int, error) {
+	for len(nr.buf) == 0 {
+		if nr.err != nil {
+			return 0, nr.err
+		}
+		switch nr.z.Next() {
+		case html.ErrorToken:
+			nr.err = nr.z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken, html.CommentToken, html.DoctypeToken:
+			nr.nodes++
+		case html.TextToken:
+			if len(bytes.TrimSpace(nr.z.Raw())) > 0 {
+				nr.nodes++
+			}
+		}
+		if nr.nodes > nr.max {
+			nr.err, nr.buf = ErrTooManyNodes, nil
+			return 0, nr.err
+		}
+		nr.buf = append(nr.buf[:0], nr.z.Raw()...)
+	}
+	n := copy(p, nr.buf)
+	nr.buf = nr.buf[n:]
+	return n, nil
+}
+
+// countNodes returns the number of nodes below n, including n, but stops
+// counting once max is exceeded.
+func countNodes(n *html.Node, max int) int {
+	result := 0
+	iterateNode(n, func(*html.Node) int {
+		if result++; result > max {
+			return IterStop
+		}
+		return IterNext
+	})
+	return result
+}
+
+// addChunk appends chunk to the chunks of the document unless the chunk
+// limit of the options is reached.
+func (doc *Document) addChunk(chunk *Chunk) {
+	if doc.opts.MaxChunks > 0 && len(doc.Chunks) >= doc.opts.MaxChunks {
+		doc.Truncated = true
+		return
+	}
+	doc.Chunks = append(doc.Chunks, chunk)
+}
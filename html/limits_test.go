@@ -0,0 +1,95 @@
+package html
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	page := "<html><head></head><body>" + strings.Repeat("<p>Some text.</p>", 100) + "</body></html>"
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Truncated || len(doc.Chunks) != 100 {
+		t.Errorf("unlimited: got %d chunks, truncated %v", len(doc.Chunks), doc.Truncated)
+	}
+
+	doc, err = NewDocumentOptions(strings.NewReader(page), Options{MaxChunks: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Truncated || len(doc.Chunks) != 10 {
+		t.Errorf("max chunks: got %d chunks, truncated %v", len(doc.Chunks), doc.Truncated)
+	}
+
+	doc, err = NewDocumentOptions(strings.NewReader(page), Options{MaxBytes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Truncated || len(doc.Chunks) >= 100 {
+		t.Errorf("max bytes: got %d chunks, truncated %v", len(doc.Chunks), doc.Truncated)
+	}
+
+	doc, err = NewDocumentOptions(strings.NewReader(page), Options{MaxBytes: int64(len(page))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Truncated {
+		t.Error("max bytes: document of exact size truncated")
+	}
+
+	if _, err = NewDocumentOptions(strings.NewReader(page), Options{MaxNodes: 50}); err != ErrTooManyNodes {
+		t.Errorf("max nodes: expected ErrTooManyNodes, got %v", err)
+	}
+}
+
+// endlessReader returns an endless stream of paragraphs and counts the bytes
+// read.
+type endlessReader struct {
+	n int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	const para = "<p>Some text.</p>\n"
+	n := 0
+	for ; n < len(p); n++ {
+		p[n] = para[(r.n+n)%len(para)]
+	}
+	r.n += n
+	return n, nil
+}
+
+func TestNodeLimitReader(t *testing.T) {
+	page := "<!DOCTYPE html><html><head><title>Title</title></head><body>\n" +
+		strings.Repeat("<p>Some <b>text</b>.</p>\n", 100) + "<!-- end --></body></html>"
+
+	// The data passes through unchanged.
+	var buf strings.Builder
+	if _, err := io.Copy(&buf, newNodeLimitReader(strings.NewReader(page), 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != page {
+		t.Errorf("got data %q", buf.String())
+	}
+
+	// Doctype, html, head, title and its text, body, 5 nodes of every
+	// paragraph and the comment.
+	if _, err := io.Copy(io.Discard, newNodeLimitReader(strings.NewReader(page), 507)); err != nil {
+		t.Errorf("exact limit: got error %v", err)
+	}
+	if _, err := io.Copy(io.Discard, newNodeLimitReader(strings.NewReader(page), 506)); err != ErrTooManyNodes {
+		t.Errorf("exceeded limit: got error %v, want ErrTooManyNodes", err)
+	}
+
+	// Endless documents are rejected once the limit is reached.
+	r := new(endlessReader)
+	if _, err := NewDocumentOptions(r, Options{MaxNodes: 1000}); err != ErrTooManyNodes {
+		t.Errorf("endless document: got error %v, want ErrTooManyNodes", err)
+	}
+	if r.n > 1<<20 {
+		t.Errorf("endless document: read %d bytes", r.n)
+	}
+}
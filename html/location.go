@@ -0,0 +1,137 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// findBase sets the URL the links of the document are resolved against: the
+// href of the first base element, resolved against the location given by
+// the options, or the location itself.
+func (doc *Document) findBase() {
+	doc.base = nil
+	if doc.opts.URL != "" {
+		doc.base, _ = url.Parse(doc.opts.URL)
+	}
+	if href := doc.baseHref(); href != nil {
+		if doc.base != nil {
+			href = doc.base.ResolveReference(href)
+		}
+		doc.base = href
+	}
+}
+
+// baseHref returns the href of the first base element of the document or nil
+// if there's none.
+func (doc *Document) baseHref() (result *url.URL) {
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Base {
+			return IterNext
+		}
+		href := strings.TrimSpace(getAttr(n, "href"))
+		if href == "" {
+			return IterNext
+		}
+		result, _ = url.Parse(href)
+		return IterStop
+	})
+	return result
+}
+
+// absoluteBase returns true if links resolved against the base URL of the
+// document are absolute. Local files and standard input have no absolute
+// location.
+func (doc *Document) absoluteBase() bool {
+	return doc.base != nil && doc.base.IsAbs() && doc.base.Host != ""
+}
+
+// findCanonical sets the canonical location of the document, declared by
+// its canonical link or else its og:url property. If the document has no
+// absolute location, its links are resolved against the canonical location,
+// so they become absolute as well.
+func (doc *Document) findCanonical() {
+	link, property := "", ""
+	iterateNode(doc.html, func(n *html.Node) int {
+		switch {
+		case n.Type != html.ElementNode:
+		case n.DataAtom == atom.Link && link == "":
+			for _, rel := range strings.Fields(getAttr(n, "rel")) {
+				if strings.EqualFold(rel, "canonical") {
+					link = getAttr(n, "href")
+				}
+			}
+		case n.DataAtom == atom.Meta && property == "" && getAttr(n, "property") == "og:url":
+			property = getAttr(n, "content")
+		}
+		return IterNext
+	})
+	canonical := doc.resolveLink(link)
+	if canonical == "" {
+		canonical = doc.resolveLink(property)
+	}
+	if canonical == "" {
+		return
+	}
+	u, err := url.Parse(canonical)
+	if err != nil {
+		return
+	}
+	// The fragment never identifies another page.
+	u.Fragment, u.RawFragment = "", ""
+	doc.CanonicalPage = u.String()
+	if !doc.absoluteBase() && u.IsAbs() && u.Host != "" {
+		// The name of a local file has nothing to do with the page, the href
+		// of the base element does.
+		doc.base = u
+		if href := doc.baseHref(); href != nil {
+			doc.base = u.ResolveReference(href)
+		}
+	}
+}
+
+// Properties of meta elements naming the lead image of the document, best
+// first.
+var imageProperties = []string{"og:image:secure_url", "og:image:url", "og:image", "twitter:image", "twitter:image:src"}
+
+// findImage returns the resolved location of the lead image declared by the
+// metadata of the document or an empty string if there's none.
+func (doc *Document) findImage() string {
+	found := make(map[string]string)
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode {
+			return IterNext
+		}
+		switch n.DataAtom {
+		case atom.Meta:
+			key := getAttr(n, "property")
+			if key == "" {
+				key = getAttr(n, "name")
+			}
+			if key = strings.ToLower(key); found[key] == "" {
+				found[key] = getAttr(n, "content")
+			}
+		case atom.Link:
+			for _, rel := range strings.Fields(getAttr(n, "rel")) {
+				if strings.EqualFold(rel, "image_src") && found["image_src"] == "" {
+					found["image_src"] = getAttr(n, "href")
+				}
+			}
+		}
+		return IterNext
+	})
+	for _, key := range append(imageProperties, "image_src") {
+		image := doc.resolveLink(found[key])
+		if image == "" {
+			continue
+		}
+		// Protocol-relative locations stay so without an absolute base;
+		// images are served by HTTPS nowadays.
+		if strings.HasPrefix(image, "//") {
+			image = "https:" + image
+		}
+		return image
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocation(t *testing.T) {
+	page := `<html><head><base href="/static/">
+		<meta property="og:url" content="https://www.example.com/news/story#top">
+		<meta name="twitter:image" content="img/twitter.jpg">
+		<meta property="og:image" content="img/lead.jpg"></head>
+		<body><p>Text with <a href="more.html">a link</a> in it.</p></body></html>`
+
+	tests := []struct {
+		url, canonical, image, link string
+	}{
+		// Links are resolved against the base element and the location,
+		// even if the canonical location differs.
+		{"https://example.com/a/b.html", "https://www.example.com/news/story", "https://example.com/static/img/lead.jpg", "https://example.com/static/more.html"},
+		{"https://amp.example.com/news/story", "https://www.example.com/news/story", "https://amp.example.com/static/img/lead.jpg", "https://amp.example.com/static/more.html"},
+		// Local files are resolved against the canonical location.
+		{"story.html", "https://www.example.com/news/story", "https://www.example.com/static/img/lead.jpg", "https://www.example.com/static/more.html"},
+		{"", "https://www.example.com/news/story", "https://www.example.com/static/img/lead.jpg", "https://www.example.com/static/more.html"},
+	}
+	for _, test := range tests {
+		doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: test.url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.CanonicalPage != test.canonical {
+			t.Errorf("%q: canonical %q, want %q", test.url, doc.CanonicalPage, test.canonical)
+		}
+		if doc.Image != test.image {
+			t.Errorf("%q: image %q, want %q", test.url, doc.Image, test.image)
+		}
+		link := ""
+		for _, chunk := range doc.Chunks {
+			if chunk.Link != "" {
+				link = chunk.Link
+			}
+		}
+		if link != test.link {
+			t.Errorf("%q: link %q, want %q", test.url, link, test.link)
+		}
+	}
+
+	doc, err := NewDocument(strings.NewReader(`<html><head><link rel="image_src" href="//cdn.example.com/a.jpg"></head><body><p>Text</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.CanonicalPage != "" || doc.Image != "https://cdn.example.com/a.jpg" {
+		t.Errorf("unexpected location: %q %q", doc.CanonicalPage, doc.Image)
+	}
+}
+
+func TestLocationImage(t *testing.T) {
+	tests := []struct {
+		url, head, image string
+	}{
+		{"", `<meta property="og:image" content="//cdn.example.com/a.jpg">`, "https://cdn.example.com/a.jpg"},
+		{"story.html", `<meta property="og:image" content="//cdn.example.com/a.jpg">`, "https://cdn.example.com/a.jpg"},
+		{"http://example.com/story", `<meta property="og:image" content="//cdn.example.com/a.jpg">`, "http://cdn.example.com/a.jpg"},
+		{"", `<link rel="canonical" href="http://example.com/story"><meta property="og:image" content="//cdn.example.com/a.jpg">`, "http://cdn.example.com/a.jpg"},
+		{"", `<link rel="canonical" href="https://example.com/story"><meta property="og:image" content="/a.jpg">`, "https://example.com/a.jpg"},
+		{"https://example.com/story", `<meta name="twitter:image" content="">`, ""},
+	}
+	for _, test := range tests {
+		page := `<html><head>` + test.head + `</head><body><p>Text</p></body></html>`
+		doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: test.url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Image != test.image {
+			t.Errorf("%q %s: image %q, want %q", test.url, test.head, doc.Image, test.image)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package html
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"strings"
+)
+
+// ErrMalformed is returned if a document is too broken to be parsed. It
+// wraps the cause.
+var ErrMalformed = errors.New("malformed document")
+
+// Depth the elements of documents nested too deeply for the parser are
+// flattened to. The parser rejects documents with more than 512 open
+// elements, but reopens formatting elements by itself, so some room is left.
+const maxFlatDepth = 256
+
+// Maximum length of the attributes examined by the regular expressions,
+// like class and id. Longer values are cut, because matching huge values
+// takes seconds, although they are never meaningful.
+const maxHintLen = 1024
+
+// Maximum number of bytes of data that can't be read again kept to parse
+// documents nested too deeply again. Documents longer than that are too
+// costly to keep and rejected with ErrMalformed if they are nested too
+// deeply.
+const maxKeptBytes = 1 << 20
+
+// parse parses the HTML data r as requested by opts, like newDataReader.
+// Documents nested too deeply for the parser are flattened and parsed again,
+// because a few hundred unclosed elements of broken templates shouldn't make
+// the article unreadable. Seekable data is read again for that, other data
+// is kept while it's parsed, up to maxKeptBytes.
+func parse(ctx context.Context, r io.Reader, opts Options) (*html.Node, *limitReader, string, error) {
+	offset := int64(-1)
+	if seeker, ok := r.(io.Seeker); ok {
+		if n, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			offset = n
+		}
+	}
+	data, limit, name, err := newDataReader(ctx, r, opts)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var kept *keptBuffer
+	in := &errorReader{r: data}
+	if offset < 0 {
+		kept = &keptBuffer{max: maxKeptBytes}
+		in.r = io.TeeReader(data, kept)
+	}
+	root, err := html.Parse(in)
+	if err == nil || in.err != nil {
+		return root, limit, name, err
+	}
+
+	// The parser failed by itself, which it only does if the elements are
+	// nested too deeply.
+	if kept != nil && kept.full {
+		return nil, nil, "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	} else if kept != nil {
+		data = io.MultiReader(&kept.buf, data)
+	} else if _, err := r.(io.Seeker).Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, "", err
+	} else if data, limit, name, err = newDataReader(ctx, r, opts); err != nil {
+		return nil, nil, "", err
+	}
+	flat := &errorReader{r: newFlattenReader(data, maxFlatDepth)}
+	if root, err = html.Parse(flat); err != nil && flat.err == nil {
+		return nil, nil, "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return root, limit, name, err
+}
+
+// keptBuffer keeps the data written to it, unless it's longer than max
+// bytes. Then it drops the data and remembers that it's full.
+type keptBuffer struct {
+	buf  bytes.Buffer
+	max  int
+	full bool
+}
+
+func (kb *keptBuffer) Write(p []byte) (int, error) {
+	if kb.full {
+		return len(p), nil
+	}
+	if kb.buf.Len()+len(p) > kb.max {
+		kb.full = true
+		kb.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return kb.buf.Write(p)
+}
+
+// errorReader remembers the first error of r other than io.EOF, which tells
+// errors of the data apart from errors of the parser.
+type errorReader struct {
+	r   io.Reader
+	err error
+}
+
+func (er *errorReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	if err != nil && err != io.EOF && er.err == nil {
+		er.err = err
+	}
+	return n, err
+}
+
+// flattenReader passes the HTML data of a tokenizer through, removing the
+// start and end tags of elements nested deeper than depth. The content of
+// the elements is kept.
+type flattenReader struct {
+	z       *html.Tokenizer
+	depth   int
+	open    []string       // names of the elements kept open
+	dropped map[string]int // number of open elements dropped by name
+	buf     []byte         // data of the tokens passed but not read yet
+}
+
+func newFlattenReader(r io.Reader, depth int) *flattenReader {
+	return &flattenReader{
+		z:       html.NewTokenizer(r),
+		depth:   depth,
+		open:    make([]string, 0, depth),
+		dropped: make(map[string]int),
+	}
+}
+
+func (fr *flattenReader) Read(p []byte) (int, error) {
+	for len(fr.buf) == 0 {
+		if err := fr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, fr.buf)
+	fr.buf = fr.buf[n:]
+	return n, nil
+}
+
+// next reads the next token into buf, unless it's dropped.
+func (fr *flattenReader) next() error {
+	tt := fr.z.Next()
+	switch tt {
+	case html.ErrorToken:
+		return fr.z.Err()
+	case html.StartTagToken:
+		name, _ := fr.z.TagName()
+		if keepOpen(atom.Lookup(name)) {
+			break
+		}
+		if len(fr.open) >= fr.depth {
+			fr.dropped[string(name)]++
+			return nil
+		}
+		fr.open = append(fr.open, string(name))
+	case html.EndTagToken:
+		name, _ := fr.z.TagName()
+		if fr.dropped[string(name)] > 0 {
+			fr.dropped[string(name)]--
+			return nil
+		}
+		for i := len(fr.open) - 1; i >= 0; i-- {
+			if fr.open[i] == string(name) {
+				fr.open = fr.open[:i]
+				break
+			}
+		}
+	}
+	fr.buf = append(fr.buf[:0], fr.z.Raw()...)
+	return nil
+}
+
+// keepOpen returns true if start tags of a are never dropped by flatten:
+// void elements don't nest and the content of raw text elements would turn
+// into text without them.
+func keepOpen(a atom.Atom) bool {
+	switch a {
+	case atom.Area, atom.Base, atom.Br, atom.Col, atom.Embed, atom.Hr, atom.Img,
+		atom.Input, atom.Link, atom.Meta, atom.Param, atom.Source, atom.Track, atom.Wbr:
+		return true
+	case atom.Script, atom.Style, atom.Textarea, atom.Title, atom.Xmp, atom.Iframe,
+		atom.Noembed, atom.Noframes, atom.Noscript, atom.Plaintext:
+		return true
+	}
+	return false
+}
+
+// trimHints cuts the attributes examined by the regular expressions below
+// n to maxHintLen bytes.
+func trimHints(n *html.Node) {
+	iterateNode(n, func(n *html.Node) int {
+		for i := range n.Attr {
+			attr := &n.Attr[i]
+			if len(attr.Val) <= maxHintLen {
+				continue
+			}
+			switch attr.Key {
+			case "id", "class", "itemprop", "style", "name", "role":
+				val := attr.Val[:maxHintLen]
+				// Don't leave half a class name.
+				if i := strings.LastIndexAny(val, " \t\n"); i > 0 {
+					val = val[:i]
+				}
+				attr.Val = val
+			}
+		}
+		return IterNext
+	})
+}
@@ -0,0 +1,164 @@
+package html
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const malformedText = "The council met on Tuesday to discuss the budget."
+
+// malformedPages returns broken and hostile pages that hold malformedText.
+func malformedPages() map[string]string {
+	para := "<p>" + malformedText + "</p>"
+	return map[string]string{
+		"deep divs":          "<html><body>" + strings.Repeat("<div>", 2000) + para + "</body></html>",
+		"deep closed divs":   "<html><body>" + strings.Repeat("<div>", 600) + para + strings.Repeat("</div>", 600) + "</body></html>",
+		"deep formatting":    "<html><body>" + strings.Repeat("<b><i>", 3000) + para + "</body></html>",
+		"deep tables":        "<html><body>" + strings.Repeat("<table><tr><td>", 300) + para + "</body></html>",
+		"deep fonts":         "<html><body>" + strings.Repeat("<font><center>", 2000) + para + "</body></html>",
+		"deep svg":           "<html><body><svg>" + strings.Repeat("<g>", 1000) + "</svg>" + para + "</body></html>",
+		"deep script":        "<html><body>" + strings.Repeat("<div>", 600) + "<script>var p = '<p>no text</p>';</script>" + para + "</body></html>",
+		"stray end tags":     "<html><body>" + strings.Repeat("</div></span>", 5000) + para + "</body></html>",
+		"unterminated tag":   "<html><body>" + para + "<p>Open <a href=\"x",
+		"unterminated attr":  "<html><body>" + para + "<div class=\"a",
+		"unterminated note":  "<html><body>" + para + "<!-- " + para,
+		"unterminated code":  "<html><body>" + para + "<script>var x = 1; " + para,
+		"missing tags":       para,
+		"null bytes":         "<html><body>\x00" + para + "\x00<p\x00>\x00</p></body></html>",
+		"huge attribute":     "<html><body><div class=\"" + strings.Repeat("a", 4<<20) + "\">" + para + "</div></body></html>",
+		"many classes":       "<html><body><div class=\"" + strings.Repeat("a b ", 1<<18) + "\">" + para + "</div></body></html>",
+		"many attributes":    "<html><body><div " + strings.Repeat("a=b ", 100000) + ">" + para + "</div></body></html>",
+		"huge style":         "<html><body><div style=\"" + strings.Repeat("color: red; ", 1<<18) + "\">" + para + "</div></body></html>",
+		"huge title":         "<html><head><title>" + strings.Repeat("t ", 1<<18) + "</title></head><body>" + para + "</body></html>",
+		"open comments":      "<html><body>" + strings.Repeat("<!-", 10000) + "-->" + para + "</body></html>",
+		"nested forms":       "<html><body>" + strings.Repeat("<form><table><tr><td><form>", 200) + para + "</body></html>",
+		"unquoted mess":      "<html><body><p class=a\"b'c id==x>" + malformedText + "</body></html>",
+		"template in table":  "<html><body><table><template><tr>" + para + "</template></table>" + para + "</body></html>",
+		"many lone brackets": "<html><body>" + strings.Repeat("<", 10000) + para + "</body></html>",
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for name, page := range malformedPages() {
+		doc, err := NewDocument(strings.NewReader(page))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		found := false
+		for _, chunk := range doc.Chunks {
+			found = found || strings.Contains(chunk.Text.String(), malformedText)
+		}
+		if !found {
+			t.Errorf("%s: text not found in %d chunks", name, len(doc.Chunks))
+		}
+	}
+}
+
+// onceReader hides the io.Seeker of its reader, so the data can be read
+// once only.
+type onceReader struct {
+	r io.Reader
+}
+
+func (or onceReader) Read(p []byte) (int, error) {
+	return or.r.Read(p)
+}
+
+// failingReader returns its data, then fails with err.
+func failingReader(data string, err error) io.Reader {
+	return io.MultiReader(strings.NewReader(data), iotest.ErrReader(err))
+}
+
+func TestParseDeep(t *testing.T) {
+	page := malformedPages()["deep divs"]
+	errRead := errors.New("connection reset")
+	tests := []struct {
+		name string
+		r    io.Reader
+		opts Options
+		err  error
+	}{
+		{"seekable", strings.NewReader(page), Options{}, nil},
+		{"not seekable", onceReader{strings.NewReader(page)}, Options{}, nil},
+		{"truncated", onceReader{strings.NewReader(page)}, Options{MaxBytes: int64(len(page) - 10)}, nil},
+		{"read error", failingReader(page, errRead), Options{}, errRead},
+		{"read error before", failingReader(page[:1000], errRead), Options{}, errRead},
+		{"too many nodes", strings.NewReader(page), Options{MaxNodes: 1000}, ErrTooManyNodes},
+	}
+	for _, test := range tests {
+		doc, err := NewDocumentOptions(test.r, test.opts)
+		if !errors.Is(err, test.err) || errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil && (len(doc.Chunks) != 1 || doc.Chunks[0].Text.String() != malformedText) {
+			t.Errorf("%s: got %d chunks", test.name, len(doc.Chunks))
+		}
+	}
+
+	// Data that can't be read again is kept up to maxKeptBytes only.
+	huge := "<html><body><!--" + strings.Repeat("x", maxKeptBytes) + "-->" + strings.Repeat("<div>", 2000) + "<p>" + malformedText + "</p></body></html>"
+	if _, err := NewDocument(onceReader{strings.NewReader(huge)}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("huge, not seekable: got error %v, want ErrMalformed", err)
+	}
+	if doc, err := NewDocument(strings.NewReader(huge)); err != nil || len(doc.Chunks) != 1 {
+		t.Errorf("huge, seekable: got %v", err)
+	}
+
+	// Seekable data is read again from where it started.
+	r := strings.NewReader("ignored" + page)
+	r.Seek(int64(len("ignored")), io.SeekStart)
+	if doc, err := NewDocument(r); err != nil || len(doc.Chunks) != 1 || doc.Chunks[0].Text.String() != malformedText {
+		t.Errorf("offset: got %v", err)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	page := "<div><div><div><b>deep</b></div></div><script>var s = '<div>';</script></div><br><p>up"
+	data, err := ioutil.ReadAll(newFlattenReader(strings.NewReader(page), 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<div><div>deep</div><script>var s = '<div>';</script></div><br><p>up"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+// FuzzChunks checks that the chunks of arbitrary data are segmented without
+// panics and form a consistent list.
+func FuzzChunks(f *testing.F) {
+	for _, page := range malformedPages() {
+		if len(page) < 1<<16 {
+			f.Add(page)
+		}
+	}
+	f.Add(benchmarkPage(3))
+	f.Add("<html><head><title>T</title></head><body><ul><li>a<li>b</ul><pre>x</pre><table><tr><td>1</table></body></html>")
+	f.Fuzz(func(t *testing.T, page string) {
+		doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: "https://example.com/a/b.html"})
+		if errors.Is(err, ErrMalformed) {
+			t.Fatal(err)
+		}
+		if err != nil {
+			return
+		}
+		for i, chunk := range doc.Chunks {
+			switch {
+			case chunk.Text == nil || chunk.Base == nil || chunk.Block == nil || chunk.Container == nil:
+				t.Fatalf("chunk %d lacks its text or nodes", i)
+			case i > 0 && chunk.Prev != doc.Chunks[i-1]:
+				t.Fatalf("chunk %d isn't linked to its predecessor", i)
+			case i+1 < len(doc.Chunks) && chunk.Next != doc.Chunks[i+1]:
+				t.Fatalf("chunk %d isn't linked to its successor", i)
+			case chunk.Text.Words < 0 || chunk.LinkText < 0:
+				t.Fatalf("chunk %d has %d words and link text %v", i, chunk.Text.Words, chunk.LinkText)
+			}
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package html
+
+import (
+	"encoding/json"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"sort"
+	"strings"
+)
+
+// findTypes returns the types the document declares for itself: the og:type
+// property and the schema.org types of microdata and JSON-LD, like "article"
+// or "newsarticle". Types are lowercase and schema.org types are stripped of
+// their URL prefix.
+func (doc *Document) findTypes() []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(t string) {
+		if i := strings.LastIndexByte(t, '/'); i >= 0 {
+			t = t[i+1:]
+		}
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" && !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode {
+			return IterNext
+		}
+		switch {
+		case n.DataAtom == atom.Meta && getAttr(n, "property") == "og:type":
+			add(getAttr(n, "content"))
+		case n.DataAtom == atom.Script && getAttr(n, "type") == "application/ld+json":
+			var v interface{}
+			if n.FirstChild != nil && json.Unmarshal([]byte(n.FirstChild.Data), &v) == nil {
+				findLinkedDataTypes(v, add)
+			}
+		}
+		for _, t := range strings.Fields(getAttr(n, "itemtype")) {
+			add(t)
+		}
+		return IterNext
+	})
+	return result
+}
+
+// findLinkedDataTypes calls add for the @type values of the JSON-LD value v
+// and the objects it contains.
+func findLinkedDataTypes(v interface{}, add func(string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch t := v["@type"].(type) {
+		case string:
+			add(t)
+		case []interface{}:
+			for _, t := range t {
+				if s, ok := t.(string); ok {
+					add(s)
+				}
+			}
+		}
+		// Visit the keys in order, so the types are found in the same order
+		// every time.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "@type" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			findLinkedDataTypes(v[key], add)
+		}
+	case []interface{}:
+		for _, child := range v {
+			findLinkedDataTypes(child, add)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypes(t *testing.T) {
+	page := `<html><head><meta property="og:type" content="article">
+		<script type="application/ld+json">{"@graph": [{"@type": "WebPage"},
+		{"@type": ["BlogPosting", "Article"], "author": {"@type": "Person"}}]}</script>
+		</head><body><div itemscope itemtype="https://schema.org/Article"><p>Text</p></div></body></html>`
+
+	doc, err := NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(doc.Types, ","); got != "article,webpage,blogposting,person" {
+		t.Errorf("got types %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package html
+
+import (
+	"github.com/slyrz/newscat/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strconv"
+	"strings"
+)
+
+var (
+	paginationNames = util.NewRegexFromWords(
+		"pagination",
+		"pager",
+		"paging",
+		"page[-_]?nav",
+		"page[-_]?links",
+		"pages",
+	)
+	currentNames = util.NewRegexFromWords(
+		"active",
+		"current",
+		"selected",
+	)
+	nextNames = util.NewRegex(`(?i)(^|[-_\s])next([-_\s]|$)`)
+
+	// Link texts of "next page" links. Texts are compared after removing
+	// everything but letters, so "Next »" matches "next".
+	nextTexts = map[string]bool{
+		"next":              true,
+		"next page":         true,
+		"weiter":            true,
+		"nächste":           true,
+		"nächste seite":     true,
+		"suivant":           true,
+		"page suivante":     true,
+		"siguiente":         true,
+		"página siguiente":  true,
+		"successiva":        true,
+		"pagina successiva": true,
+		"próxima":           true,
+		"próxima página":    true,
+		"volgende":          true,
+		"volgende pagina":   true,
+	}
+	// Symbols used as labels of "next page" links inside pagination
+	// elements. Outside of these, they often label "read more" links.
+	nextSymbols = map[string]bool{
+		">":  true,
+		">>": true,
+		"›":  true,
+		"»":  true,
+		"→":  true,
+	}
+)
+
+// getAttr returns the value of the attribute key of n.
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// getText returns the text of all TextNodes of n with normalized whitespace.
+func getText(n *html.Node) string {
+	words := make([]string, 0)
+	iterateText(n, func(s string) {
+		words = append(words, strings.Fields(s)...)
+	})
+	return strings.Join(words, " ")
+}
+
+// hasName returns true if the class or id attribute of n matches r.
+func hasName(n *html.Node, r *util.Regex) bool {
+	return r.In(getAttr(n, "class")) || r.In(getAttr(n, "id"))
+}
+
+// isNextText returns true if the text s is the label of a "next page" link.
+func isNextText(s string) bool {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !('a' <= r && r <= 'z') && r < 0x80 || r == '»' || r == '›'
+	})
+	return nextTexts[strings.Join(words, " ")]
+}
+
+// findNextPage returns the href of the link to the next page of a paginated
+// article or an empty string if the document isn't paginated. Links matching
+// the NextPage selector of the options are trusted most, followed by links
+// declared as next page by their rel attribute. Otherwise the
+// document is searched for pagination elements containing either a link
+// labeled "next" or numbered links.
+func (doc *Document) findNextPage() string {
+	result := ""
+	if doc.opts.NextPage != nil {
+		if n := findFirst(doc.html, doc.opts.NextPage); n != nil {
+			iterateNode(n, func(n *html.Node) int {
+				if result = getAttr(n, "href"); result != "" {
+					return IterStop
+				}
+				return IterNext
+			})
+			if result != "" {
+				return result
+			}
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Link || n.DataAtom == atom.A) {
+			for _, rel := range strings.Fields(getAttr(n, "rel")) {
+				if strings.EqualFold(rel, "next") && getAttr(n, "href") != "" {
+					result = getAttr(n, "href")
+					return IterStop
+				}
+			}
+		}
+		return IterNext
+	})
+	if result != "" {
+		return result
+	}
+	iterateNode(doc.body, func(n *html.Node) int {
+		if n.Type == html.ElementNode && hasName(n, paginationNames) {
+			if result = findNextInPagination(n); result != "" {
+				return IterStop
+			}
+			return IterSkip
+		}
+		return IterNext
+	})
+	return result
+}
+
+// hasLink returns true if n contains a link with an href.
+func hasLink(n *html.Node) bool {
+	found := false
+	iterateNode(n, func(c *html.Node) int {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A && getAttr(c, "href") != "" {
+			found = true
+			return IterStop
+		}
+		return IterNext
+	})
+	return found
+}
+
+// findNextInPagination returns the href of the next page link found in the
+// pagination element n.
+func findNextInPagination(n *html.Node) string {
+	current := 0
+	numbered := make(map[int]string)
+	result := ""
+	iterateNode(n, func(c *html.Node) int {
+		if c.Type != html.ElementNode {
+			return IterNext
+		}
+		text := getText(c)
+		number, err := strconv.Atoi(text)
+		if c.DataAtom == atom.A {
+			href := getAttr(c, "href")
+			switch {
+			case href == "":
+			case hasName(c, nextNames) || isNextText(text) || nextSymbols[text]:
+				result = href
+				return IterStop
+			case err == nil:
+				if hasName(c, currentNames) || getAttr(c, "aria-current") != "" {
+					current = number
+				} else {
+					numbered[number] = href
+				}
+			}
+			return IterSkip
+		}
+		// The current page is usually marked by a class or isn't a link at all.
+		if err == nil && (hasName(c, currentNames) || getAttr(c, "aria-current") != "" || !hasLink(c)) {
+			current = number
+			return IterSkip
+		}
+		return IterNext
+	})
+	if result != "" {
+		return result
+	}
+	if current == 0 {
+		current = 1
+	}
+	return numbered[current+1]
+}
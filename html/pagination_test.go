@@ -0,0 +1,65 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNextPage(t *testing.T) {
+	body := "<p>The council met on Tuesday to discuss the budget.</p>"
+	tests := []struct {
+		name, page, want string
+		opts             Options
+	}{
+		{"none", `<html><body>` + body + `<a href="/other">Other story</a></body></html>`, "", Options{}},
+		{"link rel", `<html><head><link rel="next" href="/story?page=2"></head><body>` + body + `</body></html>`, "/story?page=2", Options{}},
+		{"anchor rel", `<html><body>` + body + `<a rel="nofollow next" href="page2.html">More</a></body></html>`, "page2.html", Options{}},
+		{"next text", `<html><body>` + body + `<div class="pagination"><a href="/p/1">1</a><a href="/p/2">Next »</a></div></body></html>`, "/p/2", Options{}},
+		{"next class", `<html><body>` + body + `<ul class="pager"><li class="next"><a class="next" href="/p/3">›</a></li></ul></body></html>`, "/p/3", Options{}},
+		{"numbered", `<html><body>` + body + `<div class="page-nav"><span>1</span><a href="/p/2">2</a><a href="/p/3">3</a></div></body></html>`, "/p/2", Options{}},
+		{"numbered current", `<html><body>` + body + `<div class="pages"><a href="/p/1">1</a><a class="current" href="/p/2">2</a><a href="/p/3">3</a></div></body></html>`, "/p/3", Options{}},
+		{"last page", `<html><body>` + body + `<div class="pages"><a href="/p/1">1</a><a aria-current="page" href="/p/2">2</a></div></body></html>`, "", Options{}},
+		{"numbered indented", `<html><body>` + body + `<ul class="pagination">
+			<li>
+				<span>1</span>
+			</li>
+			<li>
+				<a href="/p/2">2</a>
+			</li>
+			<li>
+				<a href="/p/3">3</a>
+			</li>
+		</ul></body></html>`, "/p/2", Options{}},
+		{"numbered indented current", `<html><body>` + body + `<ul class="pagination">
+			<li>
+				<a href="/p/1">1</a>
+			</li>
+			<li>
+				2
+			</li>
+			<li>
+				<a href="/p/3">3</a>
+			</li>
+		</ul></body></html>`, "/p/3", Options{}},
+		// Symbols outside of pagination elements mostly label "read more" links.
+		{"symbol outside", `<html><body>` + body + `<a href="/more">»</a></body></html>`, "", Options{}},
+		{"selector", `<html><head><link rel="next" href="/wrong"></head><body>` + body + `<span class="more"><a href="/right">Continue</a></span></body></html>`, "/right", Options{NextPage: mustParseSelector(t, "span.more")}},
+	}
+	for _, test := range tests {
+		doc, err := NewDocumentOptions(strings.NewReader(test.page), test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.NextPage != test.want {
+			t.Errorf("%s: next page %q, want %q", test.name, doc.NextPage, test.want)
+		}
+	}
+}
+
+func mustParseSelector(t *testing.T, s string) *Selector {
+	sel, err := ParseSelector(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
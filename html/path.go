@@ -0,0 +1,75 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"strconv"
+	"strings"
+)
+
+// countPositions remembers the parent of every element below n and its
+// position among its siblings of the same type. Moving quotations and
+// cleaning the body modify the tree, so both have to be recorded before, or
+// paths wouldn't match the original document anymore.
+func (doc *Document) countPositions(n *html.Node) {
+	counts := make(map[string]int)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			counts[c.Data]++
+			doc.positions[c] = counts[c.Data]
+			doc.parents[c] = n
+			doc.countPositions(c)
+		}
+	}
+}
+
+// position returns the position of the element n among its siblings of the
+// same type, starting at 1.
+func (doc *Document) position(n *html.Node) int {
+	if pos, ok := doc.positions[n]; ok {
+		return pos
+	}
+	// Ancestors of document fragments weren't counted, but they weren't
+	// modified either.
+	pos := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode && s.Data == n.Data {
+			pos++
+		}
+	}
+	return pos
+}
+
+// parent returns the parent of n in the original document. Quotations moved
+// out of their figures still have them as parents.
+func (doc *Document) parent(n *html.Node) *html.Node {
+	if p, ok := doc.parents[n]; ok {
+		return p
+	}
+	return n.Parent
+}
+
+// NodePath returns the XPath of the element n in the original document, like
+// "/html[1]/body[1]/div[2]/p[1]". It can be used to find the element in a
+// browser, which builds the same tree from the page.
+func (doc *Document) NodePath(n *html.Node) string {
+	// Collect the elements from the root down, then write all steps to a
+	// single buffer.
+	var buf [16]*html.Node
+	nodes := buf[:0]
+	for ; n != nil && n.Type == html.ElementNode; n = doc.parent(n) {
+		nodes = append(nodes, n)
+	}
+	var path strings.Builder
+	path.Grow(16*len(nodes) + 1)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		path.WriteByte('/')
+		path.WriteString(nodes[i].Data)
+		path.WriteByte('[')
+		path.WriteString(strconv.Itoa(doc.position(nodes[i])))
+		path.WriteByte(']')
+	}
+	if path.Len() == 0 {
+		return "/"
+	}
+	return path.String()
+}
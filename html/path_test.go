@@ -0,0 +1,43 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkPath(t *testing.T) {
+	page := `<html><head></head><body>
+		<nav><p>Menu</p></nav>
+		<div><script>var x;</script><p>Teaser</p></div>
+		<div><p>First</p><p>Second <b>bold</b></p></div></body></html>`
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{Paths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]string)
+	for _, chunk := range doc.Chunks {
+		paths[chunk.Text.String()] = chunk.Path
+	}
+	for text, want := range map[string]string{
+		"Teaser": "/html[1]/body[1]/div[1]/p[1]",
+		"First":  "/html[1]/body[1]/div[2]/p[1]",
+		"Second": "/html[1]/body[1]/div[2]/p[2]",
+		"bold":   "/html[1]/body[1]/div[2]/p[2]/b[1]",
+	} {
+		if got := paths[text]; got != want {
+			t.Errorf("path of %q is %q, want %q", text, got, want)
+		}
+	}
+
+	// Without Paths, the positions aren't counted at all.
+	doc, err = NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range doc.Chunks {
+		if chunk.Path != "" {
+			t.Errorf("path of %q is %q without Paths", chunk.Text.String(), chunk.Path)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Quote is a quotation set off from the text, like a blockquote. The
+// chunks of the quotation point to it.
+type Quote struct {
+	Attribution string // person or work the quotation is attributed to
+	Source      string // resolved location of the cited source
+	Pull        bool   // pull quote repeating the article text for emphasis
+}
+
+// Classes and ids of elements holding pull quotes.
+var pullQuoteNames = regexp.MustCompile(`(?i)pull[-_]?quote`)
+
+// Dashes introducing the attribution of a quotation.
+const attributionDashes = "—–―-~ \t\r\n"
+
+// Maximum number of words of an attribution following a dash.
+const maxAttributionWords = 12
+
+// Minimum fraction of the word shingles of a quote found in the text around
+// it, which makes it a pull quote.
+const minPullQuoteOverlap = 0.8
+
+// Number of words per shingle compared to find pull quotes.
+const pullQuoteShingle = 4
+
+// findQuotes finds the quotations below n and takes their attributions out
+// of the tree, so they don't end up in the quoted text. It runs before the
+// body is cleaned, because attributions often reside in footer and
+// figcaption elements, and moves quotations out of their figures for the
+// same reason.
+func (doc *Document) findQuotes(n *html.Node) {
+	// The quotations are collected first, since moving them would derail
+	// the iteration.
+	nodes := make([]*html.Node, 0)
+	iterateNode(n, func(c *html.Node) int {
+		switch {
+		case c.Type != html.ElementNode:
+			return IterNext
+		case c.DataAtom != atom.Blockquote && !isPullQuote(c):
+			return IterNext
+		case doc.findEmbed(c) == nil:
+			nodes = append(nodes, c)
+		}
+		return IterSkip
+	})
+	for _, c := range nodes {
+		quote := &Quote{Source: doc.resolveLink(getAttr(c, "cite")), Pull: isPullQuote(c)}
+		quote.Attribution = takeAttribution(c)
+		if figure := c.Parent; c.DataAtom == atom.Blockquote && figure != nil && figure.DataAtom == atom.Figure && figure.Parent != nil {
+			for f := figure.FirstChild; f != nil && quote.Attribution == ""; f = f.NextSibling {
+				if f.DataAtom == atom.Figcaption {
+					quote.Attribution = strings.TrimLeft(getText(f), attributionDashes)
+				}
+			}
+			quote.Pull = quote.Pull || isPullQuote(figure)
+			figure.RemoveChild(c)
+			figure.Parent.InsertBefore(c, figure)
+		}
+		if doc.quotes == nil {
+			doc.quotes = make(map[*html.Node]*Quote)
+		}
+		doc.quotes[c] = quote
+		doc.Quotes = append(doc.Quotes, quote)
+	}
+}
+
+// isPullQuote returns true if the class or id of n names a pull quote.
+func isPullQuote(n *html.Node) bool {
+	return hasClass(n, pullQuoteNames) || pullQuoteNames.MatchString(getAttr(n, "id"))
+}
+
+// takeAttribution removes the attribution from the quotation n and returns
+// it. Attributions are held by footer and cite elements at the top level
+// of the quotation or follow a dash at its end.
+func takeAttribution(n *html.Node) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom == atom.Footer || c.DataAtom == atom.Cite {
+			n.RemoveChild(c)
+			return strings.TrimLeft(getText(c), attributionDashes)
+		}
+	}
+	// The attribution never is all of the quotation.
+	first, last := n.FirstChild, n.LastChild
+	for first != nil && first.Type == html.TextNode && strings.TrimSpace(first.Data) == "" {
+		first = first.NextSibling
+	}
+	for last != nil && last.Type == html.TextNode && strings.TrimSpace(last.Data) == "" {
+		last = last.PrevSibling
+	}
+	if last == nil || last == first {
+		return ""
+	}
+	text := getText(last)
+	if r, _ := utf8.DecodeRuneInString(text); !strings.ContainsRune("—–―-~", r) {
+		return ""
+	}
+	text = strings.TrimLeft(text, attributionDashes)
+	if words := len(strings.Fields(text)); words == 0 || words > maxAttributionWords {
+		return ""
+	}
+	n.RemoveChild(last)
+	return text
+}
+
+// findPullQuotes marks the quotations repeating the text outside of the
+// quotations as pull quotes.
+func (doc *Document) findPullQuotes() {
+	if len(doc.Quotes) == 0 {
+		return
+	}
+	words := make(map[*Quote][]string)
+	body := make([]string, 0)
+	for _, chunk := range doc.Chunks {
+		if chunk.Quote != nil {
+			words[chunk.Quote] = append(words[chunk.Quote], shingleWords(chunk.Text.String())...)
+		} else {
+			body = append(body, shingleWords(chunk.Text.String())...)
+		}
+	}
+	shingles := make(map[string]bool)
+	for i := 0; i+pullQuoteShingle <= len(body); i++ {
+		shingles[strings.Join(body[i:i+pullQuoteShingle], " ")] = true
+	}
+	for quote, quoted := range words {
+		total, found := 0, 0
+		for i := 0; i+pullQuoteShingle <= len(quoted); i++ {
+			total++
+			if shingles[strings.Join(quoted[i:i+pullQuoteShingle], " ")] {
+				found++
+			}
+		}
+		if total > 0 && float64(found) >= minPullQuoteOverlap*float64(total) {
+			quote.Pull = true
+		}
+	}
+}
+
+// shingleWords returns the lowercased words of s without punctuation, since
+// pull quotes often differ from the text in quotation marks and ellipses.
+func shingleWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
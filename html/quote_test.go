@@ -0,0 +1,59 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuotes(t *testing.T) {
+	page := `<html><head></head><body><article>
+		<p>On Tuesday, the budget passed with a narrow majority of seven votes to six.</p>
+		<blockquote cite="/speech.html"><p>We finally have a budget.</p><footer>— Jane Smith</footer></blockquote>
+		<blockquote><p>Roads come first.</p><p>– Tom Miller, engineer</p></blockquote>
+		<figure><blockquote><p>Nobody asked the riders.</p></blockquote><figcaption>Ann Lee</figcaption></figure>
+		<blockquote><p>"The budget passed with a narrow majority of seven votes to six."</p></blockquote>
+		<div class="article-pullquote"><p>We finally have a budget.</p></div>
+		<blockquote><p>- Not an attribution, but the whole quote.</p></blockquote>
+		<blockquote class="twitter-tweet"><p>Budget!</p><a href="https://twitter.com/mayor/status/1">May 1</a></blockquote>
+		</article></body></html>`
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: "https://example.com/news/a.html", Paths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paths refer to the page as it was, before quotes were taken out of
+	// their figures.
+	const article = "/html[1]/body[1]/article[1]/"
+	want := []struct {
+		text string
+		Quote
+		path string
+	}{
+		{"We finally have a budget.", Quote{"Jane Smith", "https://example.com/speech.html", false}, article + "blockquote[1]/p[1]"},
+		{"Roads come first.", Quote{"Tom Miller, engineer", "", false}, article + "blockquote[2]/p[1]"},
+		{"Nobody asked the riders.", Quote{"Ann Lee", "", false}, article + "figure[1]/blockquote[1]/p[1]"},
+		{`"The budget passed with a narrow majority of seven votes to six."`, Quote{"", "", true}, article + "blockquote[3]/p[1]"},
+		{"We finally have a budget.", Quote{"", "", true}, article + "div[1]/p[1]"},
+		{"- Not an attribution, but the whole quote.", Quote{"", "", false}, article + "blockquote[4]/p[1]"},
+	}
+	quoted := make([]*Chunk, 0)
+	for _, chunk := range doc.Chunks {
+		if chunk.Quote != nil {
+			quoted = append(quoted, chunk)
+		}
+	}
+	if len(quoted) != len(want) || len(doc.Quotes) != len(want) {
+		t.Fatalf("got %d quoted chunks of %d quotes, want %d", len(quoted), len(doc.Quotes), len(want))
+	}
+	for i, chunk := range quoted {
+		if got := chunk.Text.String(); got != want[i].text || *chunk.Quote != want[i].Quote {
+			t.Errorf("quote %d is %q %+v, want %q %+v", i, got, *chunk.Quote, want[i].text, want[i].Quote)
+		}
+		if chunk.Path != want[i].path {
+			t.Errorf("quote %d has path %q, want %q", i, chunk.Path, want[i].path)
+		}
+	}
+	if len(doc.Embeds) != 1 {
+		t.Errorf("got %d embeds, want the tweet", len(doc.Embeds))
+	}
+}
@@ -0,0 +1,102 @@
+package html
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+)
+
+// A Rule holds the site-specific hints for the pages of a host. Its fields
+// override or extend the corresponding Options.
+type Rule struct {
+	Title       *Selector   `json:"title"`        // element containing the title
+	Date        *Selector   `json:"date"`         // element containing the publication date
+	DateFormats []string    `json:"date_formats"` // Go time layouts of the publication date
+	NextPage    *Selector   `json:"next_page"`    // link to the next page
+	Include     []*Selector `json:"include"`      // elements whose text is always extracted
+	Exclude     []*Selector `json:"exclude"`      // elements whose text is never extracted
+}
+
+// Rules maps hostnames to their Rule. A rule for a host applies to its
+// subdomains as well.
+type Rules map[string]*Rule
+
+// LoadRules reads rules encoded as JSON object from r. Example:
+//
+//	{
+//	  "example.com": {
+//	    "title": "h1.headline",
+//	    "date": "span.published",
+//	    "date_formats": ["02.01.2006 15:04"],
+//	    "next_page": "a.next-page",
+//	    "include": ["#article-body"],
+//	    "exclude": [".related-stories", ".newsletter"]
+//	  }
+//	}
+func LoadRules(r io.Reader) (Rules, error) {
+	rules := make(Rules)
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	// Hostnames are case-insensitive.
+	for host, rule := range rules {
+		if lower := strings.ToLower(host); lower != host {
+			delete(rules, host)
+			rules[lower] = rule
+		}
+	}
+	return rules, nil
+}
+
+// Lookup returns the rule for host or nil if there's none. If host has no
+// rule, its parent domains are searched, so "www.example.com" gets the rule
+// of "example.com".
+func (rules Rules) Lookup(host string) *Rule {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for host != "" {
+		if rule, ok := rules[host]; ok {
+			return rule
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return nil
+}
+
+// Apply merges the rule into opts. Selectors of the rule replace the ones
+// of opts, except for include and exclude selectors, which are added.
+func (rule *Rule) Apply(opts *Options) {
+	if rule.Title != nil {
+		opts.Title = rule.Title
+	}
+	if rule.Date != nil {
+		opts.Date = rule.Date
+	}
+	if rule.NextPage != nil {
+		opts.NextPage = rule.NextPage
+	}
+	opts.DateFormats = append(append([]string{}, rule.DateFormats...), opts.DateFormats...)
+	opts.Include = append(append([]*Selector{}, opts.Include...), rule.Include...)
+	opts.Exclude = append(append([]*Selector{}, opts.Exclude...), rule.Exclude...)
+}
+
+// UnmarshalJSON parses the selector from its JSON string representation.
+func (sel *Selector) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseSelector(s)
+	if err != nil {
+		return err
+	}
+	*sel = *parsed
+	return nil
+}
@@ -0,0 +1,59 @@
+package html
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `{
+	"Example.com": {
+		"title": "h1.headline",
+		"date": ".published",
+		"date_formats": ["02.01.2006 15:04"],
+		"next_page": ".pager .forward",
+		"exclude": [".related"]
+	}
+}`
+
+const testPage = `<html><head><title>Example | Site</title></head><body>
+<h1 class="headline">The Headline</h1>
+<span class="published">24.12.2015 18:30</span>
+<div class="pager"><a class="forward" href="/page/2">More</a></div>
+</body></html>`
+
+func TestRules(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"example.com", "www.example.com:8080", "EXAMPLE.COM"} {
+		if rules.Lookup(host) == nil {
+			t.Errorf("%q: no rule found", host)
+		}
+	}
+	for _, host := range []string{"", "com", "example.org", "notexample.com"} {
+		if rules.Lookup(host) != nil {
+			t.Errorf("%q: unexpected rule", host)
+		}
+	}
+	if _, err := LoadRules(strings.NewReader(`{"example.com": {"title": "h1:first"}}`)); err == nil {
+		t.Error("expected error for unsupported selector")
+	}
+
+	opts := Options{}
+	rules.Lookup("example.com").Apply(&opts)
+	doc, err := NewDocumentOptions(strings.NewReader(testPage), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := doc.Title.String(); title != "The Headline" {
+		t.Errorf("wrong title %q", title)
+	}
+	if date := time.Date(2015, 12, 24, 18, 30, 0, 0, time.UTC); !doc.Date.Equal(date) {
+		t.Errorf("wrong date %v", doc.Date)
+	}
+	if doc.NextPage != "/page/2" {
+		t.Errorf("wrong next page %q", doc.NextPage)
+	}
+}
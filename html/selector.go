@@ -0,0 +1,397 @@
+package html
+
+import (
+	"errors"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Errors returned by ParseSelector.
+var (
+	ErrEmptySelector = errors.New("empty selector")
+	ErrBadSelector   = errors.New("unsupported selector syntax")
+)
+
+// A Selector is a parsed CSS selector. Supported are type, universal, id,
+// class and attribute selectors as well as the descendant, child, adjacent
+// sibling and general sibling combinators. Selectors may be grouped by
+// commas. Pseudo-classes and pseudo-elements are not supported.
+type Selector struct {
+	groups []complexSelector
+}
+
+// complexSelector is a sequence of compound selectors joined by combinators.
+// The combinator at index i connects compounds i-1 and i.
+type complexSelector struct {
+	compounds   []compoundSelector
+	combinators []byte
+}
+
+type compoundSelector struct {
+	tag   string // empty or "*" matches all elements
+	id    string
+	class []string
+	attr  []attrSelector
+}
+
+type attrSelector struct {
+	key string
+	op  string // empty if only the presence of the attribute is checked
+	val string
+}
+
+// ParseSelector parses the CSS selector s.
+func ParseSelector(s string) (*Selector, error) {
+	p := &selectorParser{s: s}
+	sel := new(Selector)
+	for {
+		group, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		sel.groups = append(sel.groups, group)
+		p.skipSpace()
+		if p.eof() {
+			return sel, nil
+		}
+		if p.s[p.i] != ',' {
+			return nil, ErrBadSelector
+		}
+		p.i++
+	}
+}
+
+// Match returns true if the element node n matches the selector.
+func (sel *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for i := range sel.groups {
+		if sel.groups[i].match(n, len(sel.groups[i].compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// findFirst returns the first node below n, including n, that matches sel or
+// nil if there's none.
+func findFirst(n *html.Node, sel *Selector) *html.Node {
+	var result *html.Node
+	iterateNode(n, func(n *html.Node) int {
+		if sel.Match(n) {
+			result = n
+			return IterStop
+		}
+		return IterNext
+	})
+	return result
+}
+
+// String returns the selector in CSS syntax.
+func (sel *Selector) String() string {
+	groups := make([]string, len(sel.groups))
+	for i, group := range sel.groups {
+		parts := make([]string, 0, 2*len(group.compounds))
+		for j, compound := range group.compounds {
+			if j > 0 {
+				if c := group.combinators[j]; c != ' ' {
+					parts = append(parts, string(c))
+				}
+			}
+			parts = append(parts, compound.String())
+		}
+		groups[i] = strings.Join(parts, " ")
+	}
+	return strings.Join(groups, ", ")
+}
+
+func (c *compoundSelector) String() string {
+	result := c.tag
+	if c.id != "" {
+		result += "#" + c.id
+	}
+	for _, class := range c.class {
+		result += "." + class
+	}
+	for _, attr := range c.attr {
+		if attr.op == "" {
+			result += "[" + attr.key + "]"
+		} else {
+			result += "[" + attr.key + attr.op + "\"" + attr.val + "\"]"
+		}
+	}
+	if result == "" {
+		result = "*"
+	}
+	return result
+}
+
+// match checks the selector right to left, starting with the compound at
+// index i matched against n.
+func (cs *complexSelector) match(n *html.Node, i int) bool {
+	if !cs.compounds[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch cs.combinators[i] {
+	case ' ':
+		for p := n.Parent; p != nil; p = p.Parent {
+			if p.Type == html.ElementNode && cs.match(p, i-1) {
+				return true
+			}
+		}
+	case '>':
+		if p := n.Parent; p != nil && p.Type == html.ElementNode {
+			return cs.match(p, i-1)
+		}
+	case '+':
+		if s := prevElement(n); s != nil {
+			return cs.match(s, i-1)
+		}
+	case '~':
+		for s := prevElement(n); s != nil; s = prevElement(s) {
+			if cs.match(s, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *compoundSelector) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && getAttr(n, "id") != c.id {
+		return false
+	}
+	if len(c.class) > 0 {
+		classes := strings.Fields(getAttr(n, "class"))
+		for _, want := range c.class {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attr {
+		if !attr.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *attrSelector) match(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key != a.key {
+			continue
+		}
+		switch a.op {
+		case "":
+			return true
+		case "=":
+			return attr.Val == a.val
+		case "~=":
+			for _, word := range strings.Fields(attr.Val) {
+				if word == a.val {
+					return true
+				}
+			}
+			return false
+		case "|=":
+			return attr.Val == a.val || strings.HasPrefix(attr.Val, a.val+"-")
+		case "^=":
+			return a.val != "" && strings.HasPrefix(attr.Val, a.val)
+		case "$=":
+			return a.val != "" && strings.HasSuffix(attr.Val, a.val)
+		case "*=":
+			return a.val != "" && strings.Contains(attr.Val, a.val)
+		}
+	}
+	return false
+}
+
+type selectorParser struct {
+	s string
+	i int
+}
+
+func (p *selectorParser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.i
+	for !p.eof() && strings.IndexByte(" \t\n\r\f", p.s[p.i]) >= 0 {
+		p.i++
+	}
+	return p.i > start
+}
+
+func isIdentChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func (p *selectorParser) parseIdent() (string, error) {
+	start := p.i
+	for !p.eof() && isIdentChar(p.s[p.i]) {
+		p.i++
+	}
+	if p.i == start {
+		return "", ErrBadSelector
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	result := complexSelector{}
+	combinator := byte(0)
+	for {
+		space := p.skipSpace()
+		if p.eof() || p.s[p.i] == ',' {
+			break
+		}
+		if c := p.s[p.i]; c == '>' || c == '+' || c == '~' {
+			if len(result.compounds) == 0 || combinator != 0 {
+				return result, ErrBadSelector
+			}
+			combinator = c
+			p.i++
+			continue
+		}
+		// Compounds separated by whitespace only are descendants.
+		if len(result.compounds) > 0 && combinator == 0 {
+			if !space {
+				return result, ErrBadSelector
+			}
+			combinator = ' '
+		}
+		compound, err := p.parseCompound()
+		if err != nil {
+			return result, err
+		}
+		result.compounds = append(result.compounds, compound)
+		result.combinators = append(result.combinators, combinator)
+		combinator = 0
+	}
+	if len(result.compounds) == 0 {
+		return result, ErrEmptySelector
+	}
+	if combinator != 0 {
+		return result, ErrBadSelector
+	}
+	return result, nil
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	result := compoundSelector{}
+	var err error
+	if p.s[p.i] == '*' {
+		result.tag = "*"
+		p.i++
+	} else if isIdentChar(p.s[p.i]) {
+		if result.tag, err = p.parseIdent(); err != nil {
+			return result, err
+		}
+		result.tag = strings.ToLower(result.tag)
+	}
+	for !p.eof() {
+		switch p.s[p.i] {
+		case '#':
+			p.i++
+			if result.id, err = p.parseIdent(); err != nil {
+				return result, err
+			}
+		case '.':
+			p.i++
+			class, err := p.parseIdent()
+			if err != nil {
+				return result, err
+			}
+			result.class = append(result.class, class)
+		case '[':
+			p.i++
+			attr, err := p.parseAttr()
+			if err != nil {
+				return result, err
+			}
+			result.attr = append(result.attr, attr)
+		case ':':
+			return result, ErrBadSelector
+		default:
+			if result.tag == "" && result.id == "" && len(result.class) == 0 && len(result.attr) == 0 {
+				return result, ErrBadSelector
+			}
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	result := attrSelector{}
+	p.skipSpace()
+	key, err := p.parseIdent()
+	if err != nil {
+		return result, err
+	}
+	result.key = strings.ToLower(key)
+	p.skipSpace()
+	if p.eof() {
+		return result, ErrBadSelector
+	}
+	if p.s[p.i] == ']' {
+		p.i++
+		return result, nil
+	}
+	switch {
+	case p.s[p.i] == '=':
+		result.op = "="
+		p.i++
+	case strings.IndexByte("~|^$*", p.s[p.i]) >= 0 && p.i+1 < len(p.s) && p.s[p.i+1] == '=':
+		result.op = p.s[p.i : p.i+2]
+		p.i += 2
+	default:
+		return result, ErrBadSelector
+	}
+	p.skipSpace()
+	if p.eof() {
+		return result, ErrBadSelector
+	}
+	if quote := p.s[p.i]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(p.s[p.i+1:], quote)
+		if end < 0 {
+			return result, ErrBadSelector
+		}
+		result.val = p.s[p.i+1 : p.i+1+end]
+		p.i += end + 2
+	} else if result.val, err = p.parseIdent(); err != nil {
+		return result, err
+	}
+	p.skipSpace()
+	if p.eof() || p.s[p.i] != ']' {
+		return result, ErrBadSelector
+	}
+	p.i++
+	return result, nil
+}
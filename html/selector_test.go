@@ -0,0 +1,85 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	valid := []string{
+		"div",
+		"*",
+		"#main",
+		".related-stories",
+		"div.story.body",
+		"article > p",
+		"h1 + p",
+		"h1 ~ p",
+		`a[href^="http"]`,
+		"[data-role=teaser], aside .ad",
+	}
+	for _, s := range valid {
+		if _, err := ParseSelector(s); err != nil {
+			t.Errorf("%q: unexpected error %v", s, err)
+		}
+	}
+	invalid := []string{
+		"",
+		",",
+		"div >",
+		"> div",
+		"a:hover",
+		"[href",
+		`[href="x]`,
+	}
+	for _, s := range invalid {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<html><body>
+		<div id="main" class="story body">
+			<h1>Title</h1>
+			<p class="lead">Lead</p>
+			<section><p data-role="teaser">Teaser</p></section>
+		</div>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		selector string
+		count    int
+	}{
+		{"p", 2},
+		{"#main p", 2},
+		{"#main > p", 1},
+		{"div.story.body", 1},
+		{"div.story.main", 0},
+		{"h1 + p", 1},
+		{"h1 ~ section", 1},
+		{"[data-role=teaser]", 1},
+		{"[class~=lead], h1", 2},
+		{`[id^="ma"]`, 1},
+	}
+	for _, test := range tests {
+		sel, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Fatalf("%q: %v", test.selector, err)
+		}
+		count := 0
+		iterateNode(root, func(n *html.Node) int {
+			if sel.Match(n) {
+				count += 1
+			}
+			return IterNext
+		})
+		if count != test.count {
+			t.Errorf("%q: expected %d matches, got %d", test.selector, test.count, count)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// findTags returns the tags declared by the meta elements of the document:
+// the comma-separated keywords and news_keywords as well as article:tag
+// properties. Duplicates are removed, ignoring case.
+func (doc *Document) findTags() []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if key := strings.ToLower(tag); tag != "" && !seen[key] {
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+			return IterNext
+		}
+		switch {
+		case getAttr(n, "property") == "article:tag":
+			add(getAttr(n, "content"))
+		case strings.EqualFold(getAttr(n, "name"), "keywords"), strings.EqualFold(getAttr(n, "name"), "news_keywords"):
+			for _, tag := range strings.Split(getAttr(n, "content"), ",") {
+				add(tag)
+			}
+		}
+		return IterNext
+	})
+	return result
+}
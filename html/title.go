@@ -0,0 +1,190 @@
+package html
+
+import (
+	"github.com/slyrz/newscat/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"sort"
+	"strings"
+)
+
+// TitleCandidate is a possible title of the document.
+type TitleCandidate struct {
+	Text   *util.Text
+	Source string  // where the candidate was found, e.g. "og:title" or "h1"
+	Score  float32 // confidence in the candidate, higher is better
+}
+
+// Prior scores of title candidates by source. Candidates found by the Title
+// selector of the options always win.
+var titlePriors = map[string]float32{
+	"selector":      10.0,
+	"og:title":      0.8,
+	"headline":      0.8,
+	"twitter:title": 0.6,
+	"title":         0.6,
+	"h1":            0.7,
+	"h2":            0.3,
+}
+
+// Maximum number of h1 and h2 elements considered as title candidates.
+const maxHeadingCandidates = 3
+
+// Separators between the title and the site name, as in "Title - Site".
+var titleSeparators = []string{" | ", " - ", " – ", " — ", " :: ", " · ", " • ", " » ", " « "}
+
+// Maximum number of words of a title segment considered a site name.
+const maxSiteNameWords = 4
+
+// findTitles returns the title candidates of the document, best first.
+// Candidates are taken from metadata, the title element and the first
+// headings of the body. Site names are stripped from the candidates.
+// Candidates with equal text are merged and candidates similar to other
+// candidates gain score, because different sources agreeing on a title are a
+// good sign.
+func (doc *Document) findTitles() []*TitleCandidate {
+	type raw struct{ text, source string }
+	raws := make([]raw, 0, 8)
+	siteName := ""
+	headings := map[atom.Atom]int{}
+
+	if doc.opts.Title != nil {
+		if n := findFirst(doc.html, doc.opts.Title); n != nil {
+			raws = append(raws, raw{getText(n), "selector"})
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode {
+			return IterNext
+		}
+		switch n.DataAtom {
+		case atom.Meta:
+			key := getAttr(n, "property")
+			if key == "" {
+				key = getAttr(n, "name")
+			}
+			switch key {
+			case "og:title", "twitter:title":
+				raws = append(raws, raw{getAttr(n, "content"), key})
+			case "og:site_name":
+				siteName = getAttr(n, "content")
+			}
+		case atom.Title:
+			// Skip the titles of embedded SVG images.
+			if n.Parent != nil && n.Parent.DataAtom == atom.Head {
+				raws = append(raws, raw{getText(n), "title"})
+			}
+		case atom.H1, atom.H2:
+			if headings[n.DataAtom] < maxHeadingCandidates {
+				headings[n.DataAtom]++
+				raws = append(raws, raw{getText(n), n.Data})
+			}
+		}
+		if getAttr(n, "itemprop") == "headline" {
+			text := getAttr(n, "content")
+			if text == "" {
+				text = getText(n)
+			}
+			raws = append(raws, raw{text, "headline"})
+		}
+		return IterNext
+	})
+
+	result := make([]*TitleCandidate, 0, len(raws))
+	index := make(map[string]*TitleCandidate)
+	for _, r := range raws {
+		text := stripSiteName(strings.Join(strings.Fields(r.text), " "), siteName)
+		if text == "" {
+			continue
+		}
+		key := strings.ToLower(text)
+		if c, ok := index[key]; ok {
+			c.Score += titlePriors[r.source]
+			continue
+		}
+		c := &TitleCandidate{Text: util.NewText(), Source: r.source, Score: titlePriors[r.source]}
+		c.Text.WriteString(text)
+		index[key] = c
+		result = append(result, c)
+	}
+
+	// Reward agreement between different candidates.
+	priors := make([]float32, len(result))
+	for i, c := range result {
+		priors[i] = c.Score
+	}
+	for i, c := range result {
+		for j, d := range result {
+			if i != j {
+				c.Score += 0.5 * priors[j] * c.Text.Similarity(d.Text)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score > result[j].Score
+	})
+	return result
+}
+
+// findRawTitle returns the title of the og:title metadata or, if there's
+// none, the title element. The Title selector of the options takes
+// precedence.
+func (doc *Document) findRawTitle() *util.Text {
+	result := util.NewText()
+	if doc.opts.Title != nil {
+		if n := findFirst(doc.html, doc.opts.Title); n != nil && getText(n) != "" {
+			result.WriteString(getText(n))
+			return result
+		}
+	}
+	title := ""
+	iterateNode(doc.head, func(n *html.Node) int {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta && getAttr(n, "property") == "og:title" {
+			if title = getAttr(n, "content"); title != "" {
+				return IterStop
+			}
+		}
+		return IterNext
+	})
+	if title != "" {
+		result.WriteString(title)
+		return result
+	}
+	iterateNode(doc.head, func(n *html.Node) int {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Title {
+			iterateText(n, result.WriteString)
+			return IterStop
+		}
+		return IterNext
+	})
+	return result
+}
+
+// stripSiteName removes the site name from the title s. If the name of the
+// site is unknown, a short last segment separated from the rest of the title
+// is assumed to be the site name.
+func stripSiteName(s, siteName string) string {
+	if siteName != "" && !strings.EqualFold(s, siteName) {
+		for _, sep := range titleSeparators {
+			n := len(sep) + len(siteName)
+			if len(s) <= n {
+				continue
+			}
+			switch {
+			case strings.EqualFold(s[len(s)-n:], sep+siteName):
+				return strings.TrimSpace(s[:len(s)-n])
+			case strings.EqualFold(s[:n], siteName+sep):
+				return strings.TrimSpace(s[n:])
+			}
+		}
+	}
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(s, sep); i > 0 {
+			head, tail := s[:i], s[i+len(sep):]
+			if n := len(strings.Fields(tail)); n <= maxSiteNameWords && n <= len(strings.Fields(head)) {
+				return stripSiteName(strings.TrimSpace(head), "")
+			}
+		}
+	}
+	return s
+}
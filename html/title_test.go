@@ -0,0 +1,60 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripSiteName(t *testing.T) {
+	tests := []struct{ title, site, want string }{
+		{"Mayor resigns after scandal - Example News", "", "Mayor resigns after scandal"},
+		{"Mayor resigns after scandal | Politics | Example", "", "Mayor resigns after scandal"},
+		{"Example News: Mayor resigns", "Example News", "Example News: Mayor resigns"},
+		{"Example News | Mayor resigns", "Example News", "Mayor resigns"},
+		{"Mayor resigns · EXAMPLE NEWS", "Example News", "Mayor resigns"},
+		{"Brexit - what happens next", "", "Brexit - what happens next"},
+		{"Example News", "Example News", "Example News"},
+	}
+	for _, test := range tests {
+		if got := stripSiteName(test.title, test.site); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestFindTitles(t *testing.T) {
+	page := `<html><head>
+<title>Mayor resigns after scandal - Example News</title>
+<meta property="og:site_name" content="Example News">
+</head><body>
+<header><h1>Example News</h1></header>
+<article><h1>Mayor resigns after scandal</h1><p>Text.</p></article>
+<aside><h2>Most read</h2></aside>
+</body></html>`
+	doc, err := NewDocument(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Titles) != 3 {
+		t.Fatalf("unexpected number of candidates: %d", len(doc.Titles))
+	}
+	if title := doc.Title.String(); title != "Mayor resigns after scandal" {
+		t.Errorf("wrong title %q", title)
+	}
+	for i := 1; i < len(doc.Titles); i++ {
+		if doc.Titles[i-1].Score < doc.Titles[i].Score {
+			t.Errorf("candidates not ordered by score")
+		}
+	}
+	if title := doc.RawTitle.String(); title != "Mayor resigns after scandal - Example News" {
+		t.Errorf("wrong raw title %q", title)
+	}
+
+	page = strings.Replace(page, "<title>", `<meta property="og:title" content="Mayor resigns | Example News"><title>`, 1)
+	if doc, err = NewDocument(strings.NewReader(page)); err != nil {
+		t.Fatal(err)
+	}
+	if title := doc.RawTitle.String(); title != "Mayor resigns | Example News" {
+		t.Errorf("wrong raw title %q with og:title", title)
+	}
+}
@@ -0,0 +1,30 @@
+package html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// findVariants sets the AMP flag and the location of the AMP variant of the
+// document declared by its link elements. The canonical variant is found by
+// findCanonical.
+func (doc *Document) findVariants() {
+	for _, a := range doc.html.Attr {
+		if a.Key == "amp" || a.Key == "⚡" {
+			doc.AMP = true
+		}
+	}
+	iterateNode(doc.html, func(n *html.Node) int {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Link {
+			return IterNext
+		}
+		for _, rel := range strings.Fields(getAttr(n, "rel")) {
+			switch {
+			case strings.EqualFold(rel, "amphtml") && doc.AMPPage == "":
+				doc.AMPPage = doc.resolveLink(getAttr(n, "href"))
+			}
+		}
+		return IterNext
+	})
+}
@@ -0,0 +1,28 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	page := `<html><head><link rel="canonical" href="/news/story">
+		<link rel="amphtml" href="https://amp.example.com/news/story"></head>
+		<body><p>Text</p></body></html>`
+
+	doc, err := NewDocumentOptions(strings.NewReader(page), Options{URL: "https://m.example.com/news/story?ref=feed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.AMP || doc.CanonicalPage != "https://m.example.com/news/story" || doc.AMPPage != "https://amp.example.com/news/story" {
+		t.Errorf("unexpected variants: %v %q %q", doc.AMP, doc.CanonicalPage, doc.AMPPage)
+	}
+
+	doc, err = NewDocument(strings.NewReader(`<html ⚡><head></head><body><p>Text</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.AMP {
+		t.Errorf("AMP page not detected")
+	}
+}
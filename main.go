@@ -1,44 +1,833 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/slyrz/newscat/html"
 	"github.com/slyrz/newscat/model"
 	"github.com/slyrz/newscat/util"
+	"io"
+	"io/ioutil"
+	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 var highlight = util.IsTerminal(os.Stdout)
 
+var (
+	workers     = flag.Int("workers", 1, "number of documents extracted in parallel")
+	feed        = flag.Bool("feed", false, "treat inputs as RSS/Atom feeds and extract their entries")
+	archive     = flag.Bool("archive", false, "treat inputs as WARC/MHTML archives and extract their pages")
+	jsonOut     = flag.Bool("json", false, "print articles as JSON objects, one per line")
+	markdownOut = flag.Bool("markdown", false, "print tables, code blocks, embeds and quotes as Markdown")
+	dedup       = flag.Bool("dedup", false, "flag articles nearly duplicating earlier articles")
+	pages       = flag.Int("pages", 1, "maximum number of pages merged for paginated articles")
+	comments    = flag.Bool("comments", false, "print user comments after the article")
+	rulesArg    = flag.String("rules", "", "JSON file with site-specific extraction rules")
+	timeout     = flag.Duration("timeout", 0, "maximum duration of extracting an input including all its pages")
+	amp         = flag.Bool("amp", false, "extract the AMP or canonical variant of pages the model isn't confident about")
+	preview     = flag.Bool("preview", false, "print all text chunks, rejected ones dimmed with their scores")
+	metricsAddr = flag.String("metrics", "", "address serving Prometheus metrics at /metrics during the run")
+	metricsFile = flag.String("metrics-file", "", "file the Prometheus metrics are written to after the run")
+	options     = optionFlags(flag.CommandLine)
+	limits      = limitFlags(flag.CommandLine, 0)
+	fetches     = fetchFlags(flag.CommandLine)
+	logs        = logFlags(flag.CommandLine)
+	fetcher     *util.Fetcher
+	logger      *slog.Logger
+	counts      *metrics
+	include     selectorsFlag
+	exclude     selectorsFlag
+	rules       html.Rules
+)
+
+func init() {
+	flag.Var(&include, "include", "CSS selector of elements whose text is always extracted")
+	flag.Var(&exclude, "exclude", "CSS selector of elements whose text is never extracted")
+}
+
+// selectorsFlag collects the CSS selectors passed by repeated flags.
+type selectorsFlag []*html.Selector
+
+func (f *selectorsFlag) String() string {
+	result := make([]string, len(*f))
+	for i, sel := range *f {
+		result[i] = sel.String()
+	}
+	return strings.Join(result, ", ")
+}
+
+func (f *selectorsFlag) Set(value string) error {
+	sel, err := html.ParseSelector(value)
+	if err == nil {
+		*f = append(*f, sel)
+	}
+	return err
+}
+
+// linksFlag selects how links inside of the article text are preserved.
+type linksFlag model.LinkMode
+
+var linkModes = map[string]model.LinkMode{
+	"none":     model.LinksNone,
+	"markdown": model.LinksMarkdown,
+	"offsets":  model.LinksOffsets,
+}
+
+func (f *linksFlag) String() string {
+	for name, mode := range linkModes {
+		if mode == model.LinkMode(*f) {
+			return name
+		}
+	}
+	return ""
+}
+
+func (f *linksFlag) Set(value string) error {
+	mode, ok := linkModes[value]
+	if !ok {
+		return fmt.Errorf("unknown link mode %q", value)
+	}
+	*f = linksFlag(mode)
+	return nil
+}
+
+// loadRules reads the site-specific rules from the file at path. It returns
+// no rules if path is empty.
+func loadRules(path string) html.Rules {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	result, err := html.LoadRules(f)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	return result
+}
+
+// readWeights reads the model weights from the file at path. Files not
+// holding a JSON object are read as liblinear or libsvm models, whose feature
+// columns are named by the lines of the file at columnsPath, if any.
+func readWeights(path, columnsPath string) *model.Weights {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var result *model.Weights
+	if b, _ := peekNonSpace(r); b == '{' {
+		result, err = model.LoadWeights(r)
+	} else {
+		result, err = model.LoadLinearModel(r, readColumns(columnsPath))
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	return result
+}
+
+// peekNonSpace skips leading whitespace of r and returns the next byte
+// without consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], nil
+		}
+		r.ReadByte()
+	}
+}
+
+// readColumns reads the feature column names from the file at path, one per
+// line. It returns nil if path is empty.
+func readColumns(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return strings.Fields(string(data))
+}
+
+// inputContext returns the context limiting the processing of a single
+// input to the duration passed by -timeout, if any.
+func inputContext() (context.Context, context.CancelFunc) {
+	if *timeout > 0 {
+		return context.WithTimeout(context.Background(), *timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// optionFlags defines flags for the extractor options on flags. The returned
+// function returns the options once the flags are parsed.
+func optionFlags(flags *flag.FlagSet) func() model.Options {
+	def := model.DefaultOptions
+	minChunkWords := flags.Int("min-paragraph-words", def.MinChunkWords, "minimum number of words per paragraph")
+	minArticleWords := flags.Int("min-words", def.MinArticleWords, "minimum number of words per article")
+	threshold := flags.Float64("threshold", float64(def.Threshold), "minimum score of extracted blocks")
+	headings := flags.Bool("headings", def.KeepHeadings, "extract headings")
+	lists := flags.Bool("lists", def.KeepLists, "extract text inside of lists")
+	boost := flags.Bool("boost", def.Boost, "score text using the random forest")
+	keywords := flags.Int("keywords", def.Keywords, "maximum number of keywords per article")
+	summary := flags.Int("summary", def.Summary, "print a summary of N sentences instead of the article")
+	minConfidence := flags.Float64("min-confidence", float64(def.MinConfidence), "minimum confidence of the model before falling back")
+	fallback := flags.Bool("fallback", def.Fallback, "use rule-based extraction if the model isn't confident")
+	paths := flags.Bool("paths", def.Paths, "report the XPaths of the extracted elements in JSON output")
+	preset := flags.String("preset", def.Preset, "threshold preset: news, blog, forum or auto to select it per page")
+	chunkWorkers := flags.Int("chunk-workers", def.ChunkWorkers, "goroutines scoring the chunks of long pages, 0 means one per CPU")
+	minPages := flags.Int("boilerplate", 0, "drop text repeated on N pages of a site, learned across the inputs")
+	var boilerplate *model.Boilerplate
+	weightsArg := flags.String("weights", "", "JSON file with model weights saved after feedback, or a liblinear or libsvm model")
+	columnsArg := flags.String("columns", "", "file naming the feature columns of liblinear or libsvm models, one per line")
+	quantize := flags.Bool("quantize", false, "score text with the model weights quantized to 8 bits")
+	var weights *model.Weights
+	var once sync.Once
+	links := linksFlag(def.Links)
+	flags.Var(&links, "links", "preserve links as \"markdown\" in the text or as \"offsets\" in JSON output")
+	return func() model.Options {
+		once.Do(func() {
+			if _, ok := model.Presets[*preset]; *preset != "" && *preset != model.PresetAuto && !ok {
+				log.Fatalf("unknown preset %q", *preset)
+			}
+			if *weightsArg != "" {
+				weights = readWeights(*weightsArg, *columnsArg)
+			}
+			if *quantize {
+				if weights == nil {
+					weights = model.NewWeights()
+				}
+				weights = weights.Quantize()
+			}
+			if *minPages > 0 {
+				boilerplate = model.NewBoilerplate(*minPages)
+			}
+		})
+		return model.Options{
+			MinChunkWords:   *minChunkWords,
+			MinArticleWords: *minArticleWords,
+			Threshold:       float32(*threshold),
+			KeepHeadings:    *headings,
+			KeepLists:       *lists,
+			Boost:           *boost,
+			Keywords:        *keywords,
+			Summary:         *summary,
+			MinConfidence:   float32(*minConfidence),
+			Fallback:        *fallback,
+			Links:           model.LinkMode(links),
+			Paths:           *paths,
+			Weights:         weights,
+			Preset:          *preset,
+			ChunkWorkers:    *chunkWorkers,
+			Boilerplate:     boilerplate,
+		}
+	}
+}
+
+// limitFlags defines flags for the document size limits on flags. Zero
+// values mean unlimited. The returned function returns Options with only the
+// limits set.
+func limitFlags(flags *flag.FlagSet, defaultBytes int64) func() html.Options {
+	maxBytes := flags.Int64("max-bytes", defaultBytes, "maximum size of an HTML page in bytes")
+	maxNodes := flags.Int("max-nodes", 0, "maximum number of HTML nodes per page")
+	maxChunks := flags.Int("max-chunks", 0, "maximum number of text chunks per page")
+	return func() html.Options {
+		return html.Options{
+			MaxBytes:  *maxBytes,
+			MaxNodes:  *maxNodes,
+			MaxChunks: *maxChunks,
+		}
+	}
+}
+
+// fetchFlags defines flags for fetching pages politely on flags. The
+// returned function returns the fetcher once the flags are parsed.
+func fetchFlags(flags *flag.FlagSet) func() *util.Fetcher {
+	userAgent := flags.String("user-agent", "newscat", "User-Agent header of HTTP requests")
+	robots := flags.Bool("robots", true, "obey robots.txt")
+	delay := flags.Duration("delay", time.Second, "minimum time between requests to the same host, plus up to 50% jitter")
+	retries := flags.Int("retries", 3, "maximum number of retries of requests answered by 429 or 5xx")
+	backoff := flags.Duration("backoff", time.Second, "wait before the first retry, doubled by every retry")
+	cache := flags.String("cache", "", "directory caching fetched pages")
+	return func() *util.Fetcher {
+		return &util.Fetcher{
+			UserAgent: *userAgent,
+			Robots:    *robots,
+			Delay:     *delay,
+			Jitter:    *delay / 2,
+			Retries:   *retries,
+			Backoff:   *backoff,
+			Cache:     *cache,
+		}
+	}
+}
+
+// logFlags defines the flags selecting the level of the log written to
+// standard error on flags. The returned function returns the logger.
+func logFlags(flags *flag.FlagSet) func() *slog.Logger {
+	verbose := flags.Bool("verbose", false, "log the fetching, parsing and extraction of every page")
+	quiet := flags.Bool("quiet", false, "log errors only")
+	return func() *slog.Logger {
+		level := slog.LevelInfo
+		switch {
+		case *verbose:
+			level = slog.LevelDebug
+		case *quiet:
+			level = slog.LevelError
+		}
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+}
+
 func printArticle(article *util.Article) {
 	pre, pos := "", ""
-	for _, text := range article.Text {
+	if *archive && article.URL != "" {
+		fmt.Printf("%s\n\n", article.URL)
+	}
+	texts := article.Text
+	if len(article.Summary) > 0 {
+		texts = make([]interface{}, 0, len(article.Summary)+1)
+		if article.Title != "" {
+			texts = append(texts, util.Heading(article.Title))
+		}
+		for _, sentence := range article.Summary {
+			texts = append(texts, util.Paragraph(sentence))
+		}
+	}
+	for _, text := range texts {
 		if highlight {
 			switch text.(type) {
 			case util.Heading:
 				pre, pos = "\x1b[1m", "\x1b[0m"
-			case util.Paragraph:
+			default:
 				pre, pos = "", ""
 			}
 		}
+		if *markdownOut {
+			switch t := text.(type) {
+			case util.Table:
+				text = t.Markdown()
+			case util.Code:
+				text = t.Markdown()
+			case util.Embed:
+				text = t.Markdown()
+			case util.Quote:
+				text = t.Markdown()
+			}
+		}
 		fmt.Printf("%s%s%s\n\n", pre, text, pos)
 	}
+	printComments(article.Comments, "")
 }
 
-func main() {
-	ext := model.NewExtractor()
-	for _, input := range util.GetInput(os.Args[1:]) {
-		if document, err := html.NewDocument(input.Data); err == nil {
-			if article, err := ext.Extract(document); err == nil {
-				// Extraction might miss the article heading. So if the text
-				// doesn't start with a heading, use the article title as
-				// opening heading.
-				if !article.StartsWithHeading() && article.Title != "" {
-					article.Prepend(util.Heading(article.Title))
-				}
-				printArticle(article)
+// printJSON prints the article as JSON object on a single line.
+func printJSON(article *util.Article) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.Encode(article)
+}
+
+// printComments prints the comments and, indented, their replies.
+func printComments(comments []*util.Comment, indent string) {
+	pre, pos := "", ""
+	if highlight {
+		pre, pos = "\x1b[1m", "\x1b[0m"
+	}
+	for _, comment := range comments {
+		if comment.Author != "" || comment.Time != "" {
+			fmt.Printf("%s%s%s%s %s\n", indent, pre, comment.Author, pos, comment.Time)
+		}
+		if comment.Text != "" {
+			fmt.Printf("%s%s\n", indent, comment.Text)
+		}
+		fmt.Println()
+		printComments(comment.Replies, indent+"    ")
+	}
+}
+
+// documentOptions returns the options of documents retrieved from location,
+// which selects the site-specific rules.
+func documentOptions(contentType, location string) html.Options {
+	opts := limits()
+	opts.ContentType = contentType
+	opts.Include = include
+	opts.Exclude = exclude
+	opts.URL = location
+	opts.Logger = logger
+	opts.Comments = *comments
+	opts.Paths = options().Paths
+	if u, err := url.Parse(location); err == nil {
+		if rule := rules.Lookup(u.Host); rule != nil {
+			rule.Apply(&opts)
+		}
+	}
+	return opts
+}
+
+// extractDocument returns the article found in the HTML data r and the
+// parsed document. The data was retrieved from location, which selects the
+// site-specific rules. The document is returned even if it doesn't contain
+// an article.
+func extractDocument(ctx context.Context, pool *model.ExtractorPool, r io.Reader, contentType, location string) (*util.Article, *html.Document, error) {
+	document, err := html.NewDocumentContext(ctx, r, documentOptions(contentType, location))
+	if err != nil {
+		return nil, nil, newInputError(location, stageParse, err)
+	}
+	article, err := pool.ExtractContext(ctx, document)
+	if err != nil {
+		return nil, document, newInputError(location, stageExtract, err)
+	}
+	article.Comments = document.Comments
+	return article, document, nil
+}
+
+// extractPage returns the article found in the file or URL arg and the
+// parsed document, like extractDocument.
+func extractPage(ctx context.Context, pool *model.ExtractorPool, arg string) (*util.Article, *html.Document, error) {
+	input, err := fetcher.OpenInput(ctx, arg)
+	if err != nil {
+		return nil, nil, newInputError(arg, stageFetch, err)
+	}
+	defer input.Data.Close()
+	return extractDocument(ctx, pool, input.Data, input.ContentType, arg)
+}
+
+// nextPage returns the location of the next page of the document retrieved
+// from location or an empty string if there's none.
+func nextPage(location string, document *html.Document) string {
+	if document.NextPage == "" {
+		return ""
+	}
+	base, errBase := url.Parse(location)
+	link, errLink := url.Parse(document.NextPage)
+	if errBase != nil || errLink != nil {
+		return ""
+	}
+	return base.ResolveReference(link).String()
+}
+
+// variantPage returns the location of the variant of the document retrieved
+// from location: the canonical page of AMP and mobile pages, otherwise the
+// AMP page. It returns an empty string if there's none.
+func variantPage(location string, document *html.Document) string {
+	result := document.AMPPage
+	if desktop := util.DesktopURL(location); document.AMP || desktop != location {
+		if result = document.CanonicalPage; result == "" {
+			result = desktop
+		}
+	}
+	if result == location {
+		return ""
+	}
+	return result
+}
+
+// lowConfidence returns true if the article is worth extracting from another
+// variant of its page.
+func lowConfidence(article *util.Article) bool {
+	return article.Fallback || article.Confidence < options().MinConfidence
+}
+
+// betterArticle returns true if the model extracted a better than b.
+// Articles the model found beat articles the rule-based scorer found.
+func betterArticle(a, b *util.Article) bool {
+	if a.Fallback != b.Fallback {
+		return !a.Fallback
+	}
+	return a.Confidence > b.Confidence
+}
+
+// addTitle prepends the article title as heading, because extraction might
+// miss the article heading.
+func addTitle(article *util.Article) {
+	if !article.StartsWithHeading() && article.Title != "" && options().KeepHeadings {
+		article.Prepend(util.Heading(article.Title))
+	}
+}
+
+// extractInput returns the article found in the file or URL arg. If the
+// article is paginated, up to -pages pages are merged into one article.
+// It returns an error if the input can't be read or doesn't contain an
+// article. Failing later pages end the article without error.
+func extractInput(pool *model.ExtractorPool, arg string) (*util.Article, error) {
+	ctx, cancel := inputContext()
+	defer cancel()
+	article, document, err := extractPage(ctx, pool, arg)
+	location := arg
+	if *amp && document != nil && (err != nil || lowConfidence(article)) {
+		if variant := variantPage(arg, document); variant != "" {
+			logger.Debug("extracting variant", "url", arg, "variant", variant)
+			more, doc, errVariant := extractPage(ctx, pool, variant)
+			if errVariant == nil && (err != nil || betterArticle(more, article)) {
+				logger.Debug("chose variant", "url", arg, "variant", variant)
+				article, document, err, location = more, doc, nil, variant
+				article.Variant = variant
 			}
 		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	next := nextPage(location, document)
+	visited := map[string]bool{arg: true, location: true}
+	for page := 1; page < *pages && next != "" && !visited[next]; page++ {
+		visited[next] = true
+		more, doc, err := extractPage(ctx, pool, next)
+		if err != nil {
+			break
+		}
+		article.Merge(more)
+		next = nextPage(next, doc)
+	}
+	article.URL = arg
+	addTitle(article)
+	return article, nil
+}
+
+// extractRecord returns the article found in the archived page record. It
+// returns an error if the page doesn't contain an article.
+func extractRecord(pool *model.ExtractorPool, record *util.ArchiveRecord) (*util.Article, error) {
+	ctx, cancel := inputContext()
+	defer cancel()
+	article, _, err := extractDocument(ctx, pool, record.Data, record.ContentType, record.URL)
+	if err != nil {
+		return nil, err
+	}
+	article.URL = record.URL
+	addTitle(article)
+	return article, nil
+}
+
+// expandFeeds returns the entry links of the RSS/Atom feeds passed as args
+// and the errors of the feeds that failed. Relative links are resolved
+// against the feed URL.
+func expandFeeds(args []string) ([]string, []error) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	result := make([]string, 0)
+	failed := make([]error, 0)
+	for _, arg := range args {
+		ctx, cancel := inputContext()
+		input, err := fetcher.OpenInput(ctx, arg)
+		if err != nil {
+			cancel()
+			failed = append(failed, newInputError(arg, stageFetch, err))
+			continue
+		}
+		entries, err := util.ParseFeed(input.Data)
 		input.Data.Close()
+		cancel()
+		if err != nil {
+			failed = append(failed, newInputError(arg, stageParse, err))
+			continue
+		}
+		base, _ := url.Parse(arg)
+		for _, entry := range entries {
+			link, err := url.Parse(entry.Link)
+			if err != nil {
+				continue
+			}
+			if base != nil {
+				link = base.ResolveReference(link)
+			}
+			result = append(result, link.String())
+		}
+	}
+	return result, failed
+}
+
+// learnBoilerplate learns the boilerplate of the sites of the file and URL
+// inputs args ahead of their extraction, so the first pages of a site
+// benefit as well. The inputs are read by n workers, like extract reads
+// them, and only the texts learned are kept, so the inputs are read again
+// once they're extracted. Standard input can't be read twice and is skipped.
+// Errors are reported once the inputs are extracted.
+func learnBoilerplate(boilerplate *model.Boilerplate, args []string, n int) {
+	if n < 1 {
+		n = 1
+	}
+	inputs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for arg := range inputs {
+				learnInput(boilerplate, arg)
+			}
+		}()
+	}
+	for _, arg := range args {
+		if arg != "" {
+			inputs <- arg
+		}
+	}
+	close(inputs)
+	wg.Wait()
+}
+
+// learnInput learns the boilerplate of the file or URL arg.
+func learnInput(boilerplate *model.Boilerplate, arg string) {
+	ctx, cancel := inputContext()
+	defer cancel()
+	input, err := fetcher.OpenInput(ctx, arg)
+	if err != nil {
+		return
+	}
+	defer input.Data.Close()
+	if document, err := html.NewDocumentContext(ctx, input.Data, documentOptions(input.ContentType, arg)); err == nil {
+		boilerplate.Learn(document)
+	}
+}
+
+// A task returns the article of a single input, or the error preventing its
+// extraction.
+type task func(pool *model.ExtractorPool) (*util.Article, error)
+
+// failedTask returns a task failing with err, so inputs failing before
+// their tasks are created are reported in order as well.
+func failedTask(err error) task {
+	return func(pool *model.ExtractorPool) (*util.Article, error) {
+		return nil, err
+	}
+}
+
+// inputTasks sends a task for every file and URL passed as args to tasks.
+func inputTasks(args []string, tasks chan<- task) {
+	for _, arg := range args {
+		arg := arg
+		tasks <- func(pool *model.ExtractorPool) (*util.Article, error) {
+			return extractInput(pool, arg)
+		}
+	}
+}
+
+// archiveTasks sends a task for every HTML page stored in the WARC and MHTML
+// archives passed as args to tasks. The archives are read sequentially, so
+// every page is buffered in memory until its task runs.
+func archiveTasks(args []string, tasks chan<- task) {
+	maxBytes := limits().MaxBytes
+	for _, arg := range args {
+		input, err := fetcher.OpenInput(context.Background(), arg)
+		if err != nil {
+			tasks <- failedTask(newInputError(arg, stageFetch, err))
+			continue
+		}
+		archive, err := util.OpenArchive(input.Data)
+		for err == nil {
+			var record *util.ArchiveRecord
+			if record, err = archive.Next(); err != nil {
+				break
+			}
+			// Read one byte more than allowed, so the document notices
+			// it's truncated.
+			data := record.Data
+			if maxBytes > 0 {
+				data = io.LimitReader(data, maxBytes+1)
+			}
+			var buf []byte
+			if buf, err = ioutil.ReadAll(data); err != nil {
+				break
+			}
+			record.Data = bytes.NewReader(buf)
+			tasks <- func(pool *model.ExtractorPool) (*util.Article, error) {
+				return extractRecord(pool, record)
+			}
+		}
+		if err != io.EOF {
+			tasks <- failedTask(newInputError(arg, stageParse, err))
+		}
+		input.Data.Close()
+	}
+}
+
+// extract prints the articles returned by the tasks received from tasks.
+// The tasks are run by n workers in parallel, but the articles are printed
+// in the order of the tasks. At most n articles wait for being printed, so
+// tasks are received no faster than the articles are printed. Errors are
+// reported in place of the articles. It returns the exit code of the run.
+func extract(tasks <-chan task, n int, opts model.Options) int {
+	if n < 1 {
+		n = 1
+	}
+
+	// Every task gets a buffered channel, so workers never block on
+	// delivering their results.
+	type outcome struct {
+		article *util.Article
+		err     error
+	}
+	type job struct {
+		run    task
+		result chan outcome
+	}
+	jobs := make(chan job)
+	results := make(chan chan outcome, n)
+	pool := model.NewExtractorPoolOptions(opts)
+	for w := 0; w < n; w++ {
+		go func() {
+			for j := range jobs {
+				article, err := j.run(pool)
+				j.result <- outcome{article, err}
+			}
+		}()
+	}
+	go func() {
+		for t := range tasks {
+			j := job{run: t, result: make(chan outcome, 1)}
+			results <- j.result
+			jobs <- j
+		}
+		close(jobs)
+		close(results)
+	}()
+
+	var originals duplicates
+	total, failed := 0, 0
+	for result := range results {
+		total++
+		r := <-result
+		if r.err != nil {
+			failed++
+			counts.failedInput(r.err)
+			reportError(r.err)
+			continue
+		}
+		article := r.article
+		if *dedup {
+			originals.check(article)
+		}
+		switch {
+		case *jsonOut:
+			printJSON(article)
+		case article.DuplicateOf != "":
+			fmt.Fprintf(os.Stderr, "%s: duplicate of %s\n", article.URL, article.DuplicateOf)
+		default:
+			printArticle(article)
+		}
+	}
+	return exitCode(total, failed)
+}
+
+// Maximum number of bits the fingerprints of near-duplicates differ in.
+const maxDuplicateDistance = 3
+
+// original is the fingerprint and location of an article that was seen
+// first.
+type original struct {
+	fingerprint uint64
+	url         string
+}
+
+// duplicates keeps the originals seen so far.
+type duplicates []original
+
+// check sets the DuplicateOf field of article if it nearly duplicates an
+// earlier article. Otherwise the article is remembered.
+func (d *duplicates) check(article *util.Article) {
+	fingerprint, ok := article.SimHash()
+	if !ok {
+		return
+	}
+	for _, original := range *d {
+		if util.HammingDistance(fingerprint, original.fingerprint) <= maxDuplicateDistance {
+			article.DuplicateOf = original.url
+			return
+		}
+	}
+	*d = append(*d, original{fingerprint, article.URL})
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tune" {
+		tune(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		export(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diff(os.Args[2:])
+		return
+	}
+	flag.Parse()
+	rules = loadRules(*rulesArg)
+	logger = logs()
+	fetcher = fetches()
+	fetcher.Logger = logger
+	fetcher.MaxBytes = limits().MaxBytes
+	if *metricsAddr != "" || *metricsFile != "" {
+		counts = newMetrics()
+	}
+	if *metricsAddr != "" {
+		serveMetrics(counts, *metricsAddr)
+	}
+	args := flag.Args()
+	var failed []error
+	if *feed {
+		args, failed = expandFeeds(args)
+	} else if len(args) == 0 {
+		args = []string{""}
+	}
+	if boilerplate := options().Boilerplate; boilerplate != nil && !*archive {
+		learnBoilerplate(boilerplate, args, *workers)
+	}
+	if *preview {
+		for _, err := range failed {
+			reportError(err)
+		}
+		opts := options()
+		opts.Logger = logger
+		os.Exit(previewInputs(args, opts, len(failed)))
+	}
+	tasks := make(chan task)
+	go func() {
+		for _, err := range failed {
+			tasks <- failedTask(err)
+		}
+		if *archive {
+			archiveTasks(args, tasks)
+		} else {
+			inputTasks(args, tasks)
+		}
+		close(tasks)
+	}()
+	opts := options()
+	opts.Logger = logger
+	if counts != nil {
+		opts.Observer = counts
+	}
+	code := extract(tasks, *workers, opts)
+	if *metricsFile != "" {
+		if err := writeMetrics(counts, *metricsFile); err != nil {
+			logger.Error("writing metrics failed", "err", err)
+		}
 	}
+	os.Exit(code)
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// useTestGlobals sets the globals of batch runs for the test and restores
+// them afterwards.
+func useTestGlobals(t *testing.T) {
+	savedFetcher, savedLogger, savedPages := fetcher, logger, *pages
+	fetcher, logger = new(util.Fetcher), util.Discard
+	t.Cleanup(func() {
+		fetcher, logger, *pages = savedFetcher, savedLogger, savedPages
+	})
+}
+
+// paginatedSite serves an article split into n pages at /1 to /n. Every page
+// links to the next page.
+func paginatedSite(n int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d", &page); err != nil || page < 1 || page > n {
+			http.NotFound(w, r)
+			return
+		}
+		var b strings.Builder
+		b.WriteString(`<html><head><title>A long story</title>`)
+		if page < n {
+			fmt.Fprintf(&b, `<link rel="next" href="/%d">`, page+1)
+		}
+		b.WriteString(`</head><body><div class="article"><h1>A long story</h1>`)
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(&b, `<p>Part %d.%d: The council met on Tuesday to discuss the budget. Members argued
+				about the <a href="/roads">road repairs</a> for hours, but no decision was reached.</p>`, page, i)
+		}
+		b.WriteString(`</div></body></html>`)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(b.String()))
+	}))
+}
+
+func TestExtractPages(t *testing.T) {
+	useTestGlobals(t)
+	site := paginatedSite(4)
+	defer site.Close()
+
+	for _, limit := range []int{1, 3, 10} {
+		*pages = limit
+		article, err := extractInput(model.NewExtractorPool(), site.URL+"/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := article.Content()
+		for page := 1; page <= 4; page++ {
+			want := page <= limit
+			if got := strings.Contains(content, fmt.Sprintf("Part %d.0:", page)); got != want {
+				t.Errorf("limit %d: page %d merged: %v, want %v", limit, page, got, want)
+			}
+		}
+		if n := strings.Count(content, "A long story"); n != 1 {
+			t.Errorf("limit %d: heading found %d times", limit, n)
+		}
+		for _, link := range article.Links {
+			text := fmt.Sprint(article.Text[link.Index])
+			if text[link.Start:link.End] != "road repairs" {
+				t.Errorf("limit %d: link of paragraph %d covers %q", limit, link.Index, text[link.Start:link.End])
+			}
+		}
+	}
+}
+
+func TestLearnBoilerplate(t *testing.T) {
+	useTestGlobals(t)
+	var requests int32
+	site := paginatedSite(3)
+	defer site.Close()
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		site.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	args := []string{counting.URL + "/1", "", counting.URL + "/2", counting.URL + "/missing"}
+	boilerplate := model.NewBoilerplate(2)
+	learnBoilerplate(boilerplate, args, 3)
+	if requests != 3 {
+		t.Errorf("learning sent %d requests, want 3", requests)
+	}
+
+	// The heading repeats on every page, the numbered parts don't.
+	resp, err := http.Get(site.URL + "/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	document, err := html.NewDocumentOptions(resp.Body, html.Options{URL: counting.URL + "/3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repeated := make([]string, 0)
+	for i, ok := range boilerplate.Repeated(document) {
+		if ok {
+			repeated = append(repeated, document.Chunks[i].Text.String())
+		}
+	}
+	joined := strings.Join(repeated, "|")
+	if !strings.HasPrefix(joined, "A long story|") || strings.Contains(joined, "Part") {
+		t.Errorf("got repeated texts %q", repeated)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/util"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the buckets of the extraction latency histogram in seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics counts the extractions of a batch run or server and writes them in
+// the Prometheus text exposition format. It's the model.Observer of the
+// extractors. The methods of a nil *metrics do nothing, so the counting
+// needn't be guarded where metrics are optional.
+type metrics struct {
+	mu            sync.Mutex
+	extracted     int            // documents an article was extracted from
+	rejected      int            // documents no article was extracted from
+	errors        map[string]int // inputs failed by stage before their extraction
+	fallbacks     int            // articles extracted by the rule-based scorer
+	confidenceSum float64
+	latencyCounts []int // extractions per bucket of latencyBuckets, not cumulative
+	latencySum    float64
+	latencyCount  int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		errors:        make(map[string]int),
+		latencyCounts: make([]int, len(latencyBuckets)),
+	}
+}
+
+// Extracted implements model.Observer.
+func (m *metrics) Extracted(doc *html.Document, article *util.Article, err error, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.rejected++
+	} else {
+		m.extracted++
+		m.confidenceSum += float64(article.Confidence)
+		if article.Fallback {
+			m.fallbacks++
+		}
+	}
+	if i := sort.SearchFloat64s(latencyBuckets, seconds); i < len(latencyBuckets) {
+		m.latencyCounts[i]++
+	}
+	m.latencySum += seconds
+	m.latencyCount++
+}
+
+// failed counts an input that failed at stage.
+func (m *metrics) failed(stage string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[stage]++
+}
+
+// failedInput counts the input failing with err at the stage it reports.
+// Inputs failing extraction were counted as rejected by Extracted already.
+func (m *metrics) failedInput(err error) {
+	var record *inputError
+	if !errors.As(err, &record) {
+		m.failed("unknown")
+	} else if record.Stage != stageExtract {
+		m.failed(record.Stage)
+	}
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (m *metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	printf := func(format string, args ...interface{}) error {
+		k, err := fmt.Fprintf(w, format, args...)
+		n += int64(k)
+		return err
+	}
+	printf("# HELP newscat_documents_total Documents extracted by result.\n")
+	printf("# TYPE newscat_documents_total counter\n")
+	printf("newscat_documents_total{result=\"article\"} %d\n", m.extracted)
+	printf("newscat_documents_total{result=\"rejected\"} %d\n", m.rejected)
+
+	printf("# HELP newscat_extraction_duration_seconds Time spent extracting a parsed document.\n")
+	printf("# TYPE newscat_extraction_duration_seconds histogram\n")
+	cumulative := 0
+	for i, bound := range latencyBuckets {
+		cumulative += m.latencyCounts[i]
+		printf("newscat_extraction_duration_seconds_bucket{le=\"%g\"} %d\n", bound, cumulative)
+	}
+	printf("newscat_extraction_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyCount)
+	printf("newscat_extraction_duration_seconds_sum %g\n", m.latencySum)
+	printf("newscat_extraction_duration_seconds_count %d\n", m.latencyCount)
+
+	printf("# HELP newscat_input_errors_total Inputs failed before their extraction by processing stage.\n")
+	printf("# TYPE newscat_input_errors_total counter\n")
+	for _, stage := range []string{stageFetch, stageParse} {
+		printf("newscat_input_errors_total{stage=%q} %d\n", stage, m.errors[stage])
+	}
+	if count, ok := m.errors["unknown"]; ok {
+		printf("newscat_input_errors_total{stage=\"unknown\"} %d\n", count)
+	}
+
+	printf("# HELP newscat_article_confidence Confidence of the model in the extracted articles.\n")
+	printf("# TYPE newscat_article_confidence summary\n")
+	printf("newscat_article_confidence_sum %g\n", m.confidenceSum)
+	printf("newscat_article_confidence_count %d\n", m.extracted)
+
+	printf("# HELP newscat_fallbacks_total Articles extracted by the rule-based scorer.\n")
+	printf("# TYPE newscat_fallbacks_total counter\n")
+	err := printf("newscat_fallbacks_total %d\n", m.fallbacks)
+	return n, err
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// serveMetrics serves the metrics at /metrics of addr in the background.
+func serveMetrics(m *metrics, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	go func() {
+		log.Fatal(http.ListenAndServe(addr, mux))
+	}()
+}
+
+// writeMetrics writes the metrics to the file at path, which is replaced
+// atomically for collectors reading it, like the textfile collector of the
+// Prometheus node exporter.
+func writeMetrics(m *metrics, path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".metrics-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = m.WriteTo(tmp)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
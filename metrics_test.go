@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const metricsGolden = `# HELP newscat_documents_total Documents extracted by result.
+# TYPE newscat_documents_total counter
+newscat_documents_total{result="article"} 2
+newscat_documents_total{result="rejected"} 1
+# HELP newscat_extraction_duration_seconds Time spent extracting a parsed document.
+# TYPE newscat_extraction_duration_seconds histogram
+newscat_extraction_duration_seconds_bucket{le="0.005"} 1
+newscat_extraction_duration_seconds_bucket{le="0.01"} 1
+newscat_extraction_duration_seconds_bucket{le="0.025"} 1
+newscat_extraction_duration_seconds_bucket{le="0.05"} 1
+newscat_extraction_duration_seconds_bucket{le="0.1"} 2
+newscat_extraction_duration_seconds_bucket{le="0.25"} 2
+newscat_extraction_duration_seconds_bucket{le="0.5"} 2
+newscat_extraction_duration_seconds_bucket{le="1"} 2
+newscat_extraction_duration_seconds_bucket{le="2.5"} 2
+newscat_extraction_duration_seconds_bucket{le="5"} 2
+newscat_extraction_duration_seconds_bucket{le="10"} 2
+newscat_extraction_duration_seconds_bucket{le="+Inf"} 3
+newscat_extraction_duration_seconds_sum 20.101
+newscat_extraction_duration_seconds_count 3
+# HELP newscat_input_errors_total Inputs failed before their extraction by processing stage.
+# TYPE newscat_input_errors_total counter
+newscat_input_errors_total{stage="fetch"} 2
+newscat_input_errors_total{stage="parse"} 1
+newscat_input_errors_total{stage="unknown"} 1
+# HELP newscat_article_confidence Confidence of the model in the extracted articles.
+# TYPE newscat_article_confidence summary
+newscat_article_confidence_sum 1.25
+newscat_article_confidence_count 2
+# HELP newscat_fallbacks_total Articles extracted by the rule-based scorer.
+# TYPE newscat_fallbacks_total counter
+newscat_fallbacks_total 1
+`
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := newMetrics()
+	m.Extracted(nil, &util.Article{Confidence: 0.5}, nil, time.Millisecond)
+	m.Extracted(nil, &util.Article{Confidence: 0.75, Fallback: true}, nil, 100*time.Millisecond)
+	m.Extracted(nil, nil, model.ErrEmptyResult, 20*time.Second)
+	m.failedInput(newInputError("a", stageFetch, errors.New("timeout")))
+	m.failedInput(newInputError("b", stageFetch, errors.New("timeout")))
+	m.failedInput(newInputError("c", stageParse, errors.New("too many nodes")))
+	m.failedInput(newInputError("d", stageExtract, model.ErrEmptyResult)) // counted as rejected
+	m.failedInput(errors.New("other"))
+
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	if err != nil || n != int64(b.Len()) {
+		t.Fatalf("wrote %d of %d bytes: %v", n, b.Len(), err)
+	}
+	if b.String() != metricsGolden {
+		t.Errorf("got metrics\n%s\nwant\n%s", b.String(), metricsGolden)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	s := newTestServer(1)
+	opts := model.DefaultOptions
+	opts.Observer = s.counts
+	s.pool = model.NewExtractorPoolOptions(opts)
+	serveRequest(t, s, httptest.NewRequest("POST", "/", strings.NewReader(testPage(5))))
+	code, _ := serveRequest(t, s, httptest.NewRequest("POST", "/", strings.NewReader("<html><body></body></html>")))
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("no article: got %d", code)
+	}
+	if s.counts.extracted != 1 || s.counts.rejected != 1 || len(s.counts.errors) != 0 {
+		t.Errorf("got %d extracted, %d rejected, errors %v", s.counts.extracted, s.counts.rejected, s.counts.errors)
+	}
+}
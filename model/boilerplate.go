@@ -0,0 +1,126 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Maximum number of texts remembered per site. Once exceeded, the texts
+// seen on a single page only are forgotten.
+const maxBoilerplateTexts = 100000
+
+// Boilerplate learns the texts repeating verbatim across the pages of a site,
+// like navigation, footers and subscription prompts. Extractors using it
+// drop these texts from their articles even if the model scores them well,
+// because a single page doesn't tell them apart from article text. Pages
+// are learned once per location and sites are told apart by the host of the
+// location. Boilerplate is safe for concurrent use.
+type Boilerplate struct {
+	MinPages int // number of pages a text must appear on to be boilerplate, at least 2
+
+	// Unexported fields.
+	mu    sync.RWMutex
+	sites map[string]*boilerplateSite
+}
+
+// boilerplateSite holds the texts of the pages learned from a single site.
+type boilerplateSite struct {
+	pages map[string]bool // locations of the pages learned
+	texts map[uint64]int  // number of pages containing a text
+}
+
+// NewBoilerplate returns a Boilerplate treating texts appearing on at least
+// minPages pages of a site as boilerplate.
+func NewBoilerplate(minPages int) *Boilerplate {
+	return &Boilerplate{MinPages: minPages, sites: make(map[string]*boilerplateSite)}
+}
+
+// boilerplateHost returns the site of the document, which is the host of its
+// location. Documents without host, like local files, form a site.
+func boilerplateHost(doc *html.Document) string {
+	if u, err := url.Parse(doc.URL()); err == nil {
+		return strings.ToLower(strings.TrimPrefix(u.Host, "www."))
+	}
+	return ""
+}
+
+// boilerplateHash returns the hash of the text of chunk, ignoring case and
+// whitespace.
+func boilerplateHash(chunk *html.Chunk) uint64 {
+	h := fnv.New64a()
+	var buf [utf8.UTFMax]byte
+	first := true
+	chunk.Text.EachToken(func(word string) {
+		if !first {
+			h.Write([]byte{' '})
+		}
+		first = false
+		for _, r := range word {
+			n := utf8.EncodeRune(buf[:], unicode.ToLower(r))
+			h.Write(buf[:n])
+		}
+	})
+	return h.Sum64()
+}
+
+// Learn adds the texts of doc to the texts of its site. Documents learned
+// before are skipped, so documents can be learned ahead of their extraction.
+func (b *Boilerplate) Learn(doc *html.Document) {
+	hashes := make(map[uint64]bool)
+	for _, chunk := range doc.Chunks {
+		if chunk.Text.Words > 0 {
+			hashes[boilerplateHash(chunk)] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sites == nil {
+		b.sites = make(map[string]*boilerplateSite)
+	}
+	host := boilerplateHost(doc)
+	site, ok := b.sites[host]
+	if !ok {
+		site = &boilerplateSite{pages: make(map[string]bool), texts: make(map[uint64]int)}
+		b.sites[host] = site
+	}
+	if location := doc.URL(); location != "" {
+		if site.pages[location] {
+			return
+		}
+		site.pages[location] = true
+	}
+	for hash := range hashes {
+		site.texts[hash]++
+	}
+	if len(site.texts) > maxBoilerplateTexts {
+		for hash, count := range site.texts {
+			if count < 2 {
+				delete(site.texts, hash)
+			}
+		}
+	}
+}
+
+// Repeated returns a slice holding true for every chunk of doc whose text
+// is boilerplate of its site.
+func (b *Boilerplate) Repeated(doc *html.Document) []bool {
+	result := make([]bool, len(doc.Chunks))
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	site, ok := b.sites[boilerplateHost(doc)]
+	if !ok || b.MinPages < 2 {
+		return result
+	}
+	for i, chunk := range doc.Chunks {
+		if chunk.Text.Words > 0 {
+			result[i] = site.texts[boilerplateHash(chunk)] >= b.MinPages
+		}
+	}
+	return result
+}
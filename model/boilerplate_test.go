@@ -0,0 +1,136 @@
+package model
+
+import (
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"strings"
+	"testing"
+)
+
+// boilerplatePage returns page n of a site whose article contains the same
+// subscription prompt on every page, spelled as promo.
+func boilerplatePage(n int, promo string) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>A story</title></head><body><div class="article"><h1>A story</h1>`)
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(&b, `<p>Story %d, part %d: The council met on Tuesday to discuss the budget. Members argued about
+			the road repairs for hours, but no decision was reached on page %d.</p>`, n, i, n)
+	}
+	fmt.Fprintf(&b, `<p class="promo">%s</p>`, promo)
+	for i := 3; i < 6; i++ {
+		fmt.Fprintf(&b, `<p>Story %d, part %d: The vote was postponed until next week, when the mayor returns from
+			her holidays, the council said on page %d.</p>`, n, i, n)
+	}
+	b.WriteString(`</div></body></html>`)
+	return b.String()
+}
+
+const boilerplatePromo = "Subscribe to our newsletter to read the latest stories of the council every morning."
+
+func boilerplateDocument(t *testing.T, location, page string, opts html.Options) *html.Document {
+	t.Helper()
+	opts.URL = location
+	doc, err := html.NewDocumentOptions(strings.NewReader(page), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// repeatedTexts returns the first word of the chunks of doc that are
+// boilerplate.
+func repeatedTexts(b *Boilerplate, doc *html.Document) string {
+	texts := make([]string, 0)
+	for i, repeated := range b.Repeated(doc) {
+		if repeated {
+			texts = append(texts, strings.Fields(doc.Chunks[i].Text.String())[0])
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+func TestBoilerplate(t *testing.T) {
+	// Texts match regardless of case and whitespace, and sites regardless
+	// of the www prefix.
+	pages := []struct {
+		location, promo string
+	}{
+		{"https://example.com/1", boilerplatePromo},
+		{"https://www.example.com/2", strings.ToUpper(boilerplatePromo)},
+		{"https://example.com/3", strings.Replace(boilerplatePromo, " ", "\n\t ", -1)},
+	}
+	b := NewBoilerplate(3)
+	for i, page := range pages {
+		doc := boilerplateDocument(t, page.location, boilerplatePage(i, page.promo), html.Options{})
+		b.Learn(doc)
+		b.Learn(doc) // learned once per location
+	}
+
+	doc := boilerplateDocument(t, "https://example.com/4", boilerplatePage(4, boilerplatePromo), html.Options{})
+	tests := []struct {
+		minPages int
+		want     string
+	}{
+		{0, ""},
+		{1, ""},
+		{2, "A Subscribe"},
+		{3, "A Subscribe"},
+		{4, ""},
+	}
+	for _, test := range tests {
+		b.MinPages = test.minPages
+		if got := repeatedTexts(b, doc); got != test.want {
+			t.Errorf("min pages %d: got repeated %q, want %q", test.minPages, got, test.want)
+		}
+	}
+
+	// Other sites and modified texts aren't boilerplate.
+	b.MinPages = 3
+	other := boilerplateDocument(t, "https://example.org/1", boilerplatePage(1, boilerplatePromo), html.Options{})
+	if got := repeatedTexts(b, other); got != "" {
+		t.Errorf("other site: got repeated %q", got)
+	}
+	changed := boilerplateDocument(t, "https://example.com/5", boilerplatePage(5, boilerplatePromo+" Now."), html.Options{})
+	if got := repeatedTexts(b, changed); got != "A" {
+		t.Errorf("changed text: got repeated %q", got)
+	}
+}
+
+func TestExtractBoilerplate(t *testing.T) {
+	b := NewBoilerplate(2)
+	for i := 0; i < 2; i++ {
+		b.Learn(boilerplateDocument(t, fmt.Sprintf("https://example.com/%d", i), boilerplatePage(i, boilerplatePromo), html.Options{}))
+	}
+	promo, err := html.ParseSelector(".promo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		opts  html.Options
+		promo bool
+	}{
+		{"boilerplate", html.Options{}, false},
+		{"included", html.Options{Include: []*html.Selector{promo}}, true},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions
+		opts.Boilerplate = b
+		doc := boilerplateDocument(t, "https://example.com/2", boilerplatePage(2, boilerplatePromo), test.opts)
+		article, err := NewExtractorOptions(opts).Extract(doc)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		content := article.Content()
+		if !strings.Contains(content, "Story 2, part 5") || strings.Contains(content, "Subscribe") != test.promo {
+			t.Errorf("%s: unexpected article %q", test.name, content)
+		}
+	}
+
+	// Without boilerplate, the model keeps the prompt.
+	doc := boilerplateDocument(t, "https://example.com/2", boilerplatePage(2, boilerplatePromo), html.Options{})
+	article, err := NewExtractor().Extract(doc)
+	if err != nil || !strings.Contains(article.Content(), "Subscribe") {
+		t.Errorf("without boilerplate: got %v, %v", article, err)
+	}
+}
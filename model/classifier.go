@@ -1,15 +1,63 @@
 package model
 
-func (ftr chunkFeature) Score() float32 {
-	score := logit.Intercept
-	for i := range ftr {
-		score += (ftr[i] * logit.Coefficients[i])
+import "math"
+
+// dot returns the dot product of a and b, which must be at least as long
+// as a. Four products are summed at a time into separate accumulators, so
+// the additions don't wait for each other and the loop has no bounds checks.
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// dotInt8 works like dot, but takes int8 coefficients b.
+func dotInt8(a []float32, b []int8) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * float32(b[i])
+		s1 += a[i+1] * float32(b[i+1])
+		s2 += a[i+2] * float32(b[i+2])
+		s3 += a[i+3] * float32(b[i+3])
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * float32(b[i])
 	}
-	return score
+	return (s0 + s1) + (s2 + s3)
 }
 
-func (ftr chunkFeature) Predict() bool {
-	return ftr.Score() > 0.0
+// score returns the linear score of the feature vector ftr. Quantized
+// models use their int8 coefficients.
+func (m *logitModel) score(ftr []float32) float32 {
+	if m.quantized != nil {
+		return m.Intercept + m.scale*dotInt8(ftr, m.quantized)
+	}
+	return m.Intercept + dot(ftr, m.Coefficients)
+}
+
+func (ftr *chunkFeature) Score(m *logitModel) float32 {
+	return m.score(ftr[:])
+}
+
+func (ftr *chunkFeature) Predict(m *logitModel) bool {
+	return ftr.Score(m) > 0.0
+}
+
+// Probability maps the score to the interval [0,1] using the logistic function.
+func (ftr *chunkFeature) Probability(m *logitModel) float32 {
+	return float32(1.0 / (1.0 + math.Exp(-float64(ftr.Score(m)))))
 }
 
 func (ftr boostFeature) Score() float32 {
@@ -30,3 +78,9 @@ func (ftr boostFeature) Score() float32 {
 func (ftr boostFeature) Predict() bool {
 	return ftr.Score() > 0.5
 }
+
+// Probability maps the score of a linear boost model m to the interval [0,1]
+// using the logistic function.
+func (ftr boostFeature) Probability(m *logitModel) float32 {
+	return float32(1.0 / (1.0 + math.Exp(-float64(m.score(ftr[:])))))
+}
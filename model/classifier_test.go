@@ -0,0 +1,83 @@
+package model
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomFeature returns a feature vector of values in [0,1).
+func randomFeature(r *rand.Rand) *chunkFeature {
+	ftr := new(chunkFeature)
+	for i := range ftr {
+		ftr[i] = r.Float32()
+	}
+	return ftr
+}
+
+func TestDot(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n <= 9; n++ {
+		a, b := make([]float32, n), make([]float32, n)
+		want := 0.0
+		for i := range a {
+			a[i], b[i] = r.Float32()-0.5, r.Float32()-0.5
+			want += float64(a[i]) * float64(b[i])
+		}
+		if got := dot(a, b); math.Abs(float64(got)-want) > 1e-6 {
+			t.Errorf("dot of %d components is %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	weights := NewWeights()
+	quantized := weights.Quantize()
+	if weights.Quantized() || !quantized.Quantized() {
+		t.Fatal("Quantize changed the original weights or didn't quantize the copy")
+	}
+
+	// Every coefficient is off by at most half a step, and the features
+	// are at most one.
+	model := quantized.snapshot()
+	bound := float64(model.scale) / 2 * chunkFeatureCap
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ftr := randomFeature(r)
+		want := ftr.Score(weights.snapshot())
+		if got := ftr.Score(model); math.Abs(float64(got-want)) > bound {
+			t.Fatalf("quantized score %v, want %v ± %v", got, want, bound)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := quantized.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadWeights(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Quantized() {
+		t.Error("loaded weights aren't quantized")
+	}
+}
+
+func BenchmarkScore(b *testing.B) {
+	ftr := randomFeature(rand.New(rand.NewSource(1)))
+	for _, bench := range []struct {
+		name    string
+		weights *Weights
+	}{
+		{"float32", NewWeights()},
+		{"int8", NewWeights().Quantize()},
+	} {
+		model := bench.weights.snapshot()
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ftr.Score(model)
+			}
+		})
+	}
+}
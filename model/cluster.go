@@ -61,6 +61,15 @@ func (cl *cluster) Score() float32 {
 	return cl.average
 }
 
+// Words returns the total number of words of all chunks in cluster.
+func (cl *cluster) Words() int {
+	result := 0
+	for _, chunk := range cl.Chunks {
+		result += chunk.Text.Words
+	}
+	return result
+}
+
 // newClusterMap creates and initalizes a new clusterMap.
 func newClusterMap() clusterMap {
 	return make(clusterMap)
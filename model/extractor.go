@@ -1,25 +1,256 @@
 package model
 
 import (
+	"context"
 	"errors"
 	"github.com/slyrz/newscat/html"
 	"github.com/slyrz/newscat/util"
+	gonet "golang.org/x/net/html"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 var (
 	ErrNoChunks    = errors.New("document contains no chunks")
 	ErrEmptyResult = errors.New("nothing found")
+	ErrTooShort    = errors.New("article too short")
 )
 
+// Number of keywords guiding the summary if the options request none.
+const summaryKeywords = 10
+
+// Options control which of the chunks are extracted.
+type Options struct {
+	MinChunkWords   int      // minimum number of words per paragraph
+	MinArticleWords int      // minimum number of words per article
+	Threshold       float32  // minimum block score of relevant chunks
+	KeepHeadings    bool     // extract headings
+	KeepLists       bool     // extract chunks inside of lists
+	Boost           bool     // score chunks using the random forest
+	Keywords        int      // maximum number of keywords per article
+	Summary         int      // number of sentences per summary
+	MinConfidence   float32  // minimum confidence of the model
+	Fallback        bool     // use the rule-based scorer if the model isn't confident
+	Links           LinkMode // how links inside of the text are preserved
+	Paths           bool     // report the XPaths of the extracted elements, needs html.Options.Paths
+	Weights         *Weights // weights of the chunk scores, nil means the trained weights
+	Preset          string   // name of the threshold preset, PresetAuto or "" for none
+	ChunkWorkers    int      // goroutines scoring the chunks of long documents, 0 means GOMAXPROCS
+
+	// Boilerplate learned across the pages of sites. Extracted documents are
+	// learned as well.
+	Boilerplate *Boilerplate
+
+	// Observer notified of every extraction, if not nil.
+	Observer Observer
+
+	// Logger of the extractions and the decisions taken, if not nil.
+	Logger *slog.Logger
+}
+
+// DefaultOptions extract the chunks the way newscat always did: Chunks of
+// blocks scoring above 0.5 are extracted, including headings and lists, and
+// the scores are boosted by the random forest. The extras, like keywords and
+// the rule-based fallback, are disabled.
+var DefaultOptions = Options{
+	MinChunkWords:   0,
+	MinArticleWords: 0,
+	Threshold:       0.5,
+	KeepHeadings:    true,
+	KeepLists:       true,
+	Boost:           true,
+	Keywords:        0,
+	Summary:         0,
+	MinConfidence:   0.2,
+	Fallback:        false,
+}
+
 // Extractor utilizes the trained model to extract relevant html.Chunks from
-// an html.Document.
+// an html.Document. An Extractor must not be used by multiple goroutines at
+// the same time; use an ExtractorPool instead.
 type Extractor struct {
-	Labels []bool
+	Labels  []bool
+	Options Options
+	// Unexported fields.
+	features []chunkFeature // feature vectors of the last document, reused as buffer
 }
 
-// NewExtractor creates and initializes a new Extractor.
+// NewExtractor creates and initializes a new Extractor using the
+// DefaultOptions.
 func NewExtractor() *Extractor {
-	return new(Extractor)
+	return NewExtractorOptions(DefaultOptions)
+}
+
+// NewExtractorOptions creates and initializes a new Extractor using opts.
+func NewExtractorOptions(opts Options) *Extractor {
+	return &Extractor{Options: opts}
+}
+
+// mainContentCenter returns the index of the middle chunk of the container
+// holding the most words, which most likely is the main content of doc.
+func mainContentCenter(doc *html.Document) int {
+	words := make(map[*gonet.Node]int)
+	best := doc.Chunks[0].Container
+	for _, chunk := range doc.Chunks {
+		if words[chunk.Container] += chunk.Text.Words; words[chunk.Container] > words[best] {
+			best = chunk.Container
+		}
+	}
+	indices := make([]int, 0)
+	for i, chunk := range doc.Chunks {
+		if chunk.Container == best {
+			indices = append(indices, i)
+		}
+	}
+	return indices[len(indices)/2]
+}
+
+// newChunkFeatures returns the normalized feature vectors of the chunks of
+// doc. The optional feature groups not selected by features are left zero.
+// The vectors are written to buf if it's large enough. The chunks are split
+// among workers goroutines; only the class and cluster stats and the
+// normalization need all chunks.
+func newChunkFeatures(doc *html.Document, buf []chunkFeature, workers int, features optionalFeatures) []chunkFeature {
+	chunkFeatures := resizeChunkFeatures(buf, len(doc.Chunks))
+
+	// Count the number of words and sentences we encountered for each
+	// class. This helps us to detect elements that contain the doc text.
+	classStats := doc.GetClassStats()
+	clusterStats := doc.GetClusterStats()
+	siblings := newSiblingCounts(doc.Chunks)
+
+	// Detect the minimum and maximum value for each element in the
+	// feature vector while writing the vectors. Every worker detects them
+	// for its own chunks.
+	center := 0
+	if features.position {
+		center = mainContentCenter(doc)
+	}
+	empMins := make([]chunkFeature, workers)
+	empMaxs := make([]chunkFeature, workers)
+	parallelRanges(len(doc.Chunks), workers, func(worker, start, end int) {
+		chunkFeatureWriter := new(chunkFeatureWriter)
+		empMin, empMax := &empMins[worker], &empMaxs[worker]
+		for i, chunk := range doc.Chunks[start:end] {
+			i += start
+			chunkFeatureWriter.Assign(chunkFeatures[i][:])
+			chunkFeatureWriter.WriteElementType(chunk)
+			chunkFeatureWriter.WriteParentType(chunk)
+			chunkFeatureWriter.WriteSiblingTypes(chunk, siblings)
+			chunkFeatureWriter.WriteAncestors(chunk)
+			chunkFeatureWriter.WriteTextStat(chunk)
+			chunkFeatureWriter.WriteTextStatSiblings(chunk)
+			chunkFeatureWriter.WriteClassStat(chunk, classStats)
+			chunkFeatureWriter.WriteClusterStat(chunk, clusterStats)
+			if features.stopwords {
+				chunkFeatureWriter.WriteStopwordStat(chunk)
+			} else {
+				chunkFeatureWriter.Skip(stopwordFeatures)
+			}
+			if features.density {
+				chunkFeatureWriter.WriteDensityStat(chunk)
+			} else {
+				chunkFeatureWriter.Skip(densityFeatures)
+			}
+			if features.position {
+				chunkFeatureWriter.WritePosition(chunk, i, len(doc.Chunks), center)
+			} else {
+				chunkFeatureWriter.Skip(positionFeatures)
+			}
+			for j, val := range chunkFeatures[i] {
+				switch {
+				case val < empMin[j]:
+					empMin[j] = val
+				case val > empMax[j]:
+					empMax[j] = val
+				}
+			}
+		}
+	})
+	empMin := chunkFeature{}
+	empMax := chunkFeature{}
+	for worker := range empMins {
+		for j := range empMin {
+			if empMins[worker][j] < empMin[j] {
+				empMin[j] = empMins[worker][j]
+			}
+			if empMaxs[worker][j] > empMax[j] {
+				empMax[j] = empMaxs[worker][j]
+			}
+		}
+	}
+
+	// Perform MinMax normalization.
+	parallelRanges(len(doc.Chunks), workers, func(worker, start, end int) {
+		for i := start; i < end; i++ {
+			feature := &chunkFeatures[i]
+			for j, val := range chunkFeatures[i] {
+				// If the maximum value is not greater than one, we assume that the feature is
+				// already normalized and leave it untouched.
+				if empMax[j] > 1.0 {
+					feature[j] = (val - empMin[j]) / (empMax[j] - empMin[j])
+				}
+			}
+		}
+	})
+	return chunkFeatures
+}
+
+// newBoostFeatures returns the feature vectors of the chunks of doc scored
+// by the random forest. The chunks are clustered by container in clusters.
+// The vectors are written to buf if it's large enough. The chunks are split
+// among workers goroutines.
+func newBoostFeatures(doc *html.Document, clusters clusterMap, buf []boostFeature, workers int) []boostFeature {
+	boostFeatures := resizeBoostFeatures(buf, len(doc.Chunks))
+	params := getLanguageParams(doc.Language)
+
+	// Clusters calculate their scores on first use. Calculate them now, so
+	// the workers only read them.
+	for _, cluster := range clusters {
+		cluster.Score()
+	}
+	parallelRanges(len(doc.Chunks), workers, func(worker, start, end int) {
+		boostFeatureWriter := &boostFeatureWriter{params: params}
+		for i := start; i < end; i++ {
+			chunk := doc.Chunks[i]
+			boostFeatureWriter.Assign(boostFeatures[i][:])
+			boostFeatureWriter.WriteChunk(chunk)
+			boostFeatureWriter.WriteCluster(chunk, clusters[chunk.Container])
+			boostFeatureWriter.WriteTitleSimilarity(chunk, doc.RawTitle)
+		}
+	})
+	return boostFeatures
+}
+
+// anyLabel returns true if at least one of the labels is true.
+func anyLabel(labels []bool) bool {
+	for _, label := range labels {
+		if label {
+			return true
+		}
+	}
+	return false
+}
+
+// keepEmbed returns true if the chunks around embed were extracted, so the
+// embed most likely belongs to the article.
+func (ext *Extractor) keepEmbed(embed *html.Embed) bool {
+	i := embed.Position
+	return i > 0 && ext.Labels[i-1] && (i == len(ext.Labels) || ext.Labels[i])
+}
+
+// keep returns true if the options allow extracting chunk, given that the
+// score of its block passed the threshold.
+func (ext *Extractor) keep(chunk *html.Chunk, block *cluster) bool {
+	switch {
+	case chunk.IsHeading():
+		return ext.Options.KeepHeadings
+	case !ext.Options.KeepLists && (chunk.Ancestors&html.AncestorList) != 0:
+		return false
+	}
+	return block.Words() >= ext.Options.MinChunkWords
 }
 
 // Extract returns a list of relevant text chunks found in doc.
@@ -36,110 +267,331 @@ func NewExtractor() *Extractor {
 // a second type of feature vector is created based on these scores.
 // This feature vector is fed to our random forest and finally
 // the random forest's predictions are used to generate the result.
+// If boosting is disabled, the logistic regression's probabilities are used
+// instead.
 //
 // By now you might have noticed that I'm exceptionally bad at naming and
 // describing things properly.
 func (ext *Extractor) Extract(doc *html.Document) (*util.Article, error) {
-	*ext = Extractor{}
-	if len(doc.Chunks) == 0 {
-		return nil, ErrNoChunks
+	return ext.ExtractContext(context.Background(), doc)
+}
+
+// ExtractFromNode returns the article found in the parsed HTML n, which is
+// either a complete document or a fragment of the body. The document is
+// created as requested by opts. See html.NewDocumentNode for details; the
+// tree of n is modified.
+func (ext *Extractor) ExtractFromNode(n *gonet.Node, opts html.Options) (*util.Article, error) {
+	return ext.ExtractFromNodeContext(context.Background(), n, opts)
+}
+
+// ExtractFromNodeContext works like ExtractFromNode, but gives up once ctx is
+// done. It returns the error of ctx then.
+func (ext *Extractor) ExtractFromNodeContext(ctx context.Context, n *gonet.Node, opts html.Options) (*util.Article, error) {
+	doc, err := html.NewDocumentNodeContext(ctx, n, opts)
+	if err != nil {
+		return nil, err
 	}
+	return ext.ExtractContext(ctx, doc)
+}
 
-	chunkFeatures := make([]chunkFeature, len(doc.Chunks))
-	boostFeatures := make([]boostFeature, len(doc.Chunks))
+// ExtractContext works like Extract, but gives up once ctx is done. It
+// returns the error of ctx then.
+func (ext *Extractor) ExtractContext(ctx context.Context, doc *html.Document) (*util.Article, error) {
+	return ext.ExtractFunc(ctx, doc, nil)
+}
 
-	// Count the number of words and sentences we encountered for each
-	// class. This helps us to detect elements that contain the doc text.
-	classStats := doc.GetClassStats()
-	clusterStats := doc.GetClusterStats()
+// ChunkScore is the classification of a single chunk.
+type ChunkScore struct {
+	Chunk      *html.Chunk
+	Index      int     // index of the chunk in the Chunks of the document
+	Score      float32 // score of the chunk itself
+	BlockScore float32 // average score of the chunks of its block
+	Label      bool    // true if the chunk is part of the article
+}
 
-	chunkFeatureWriter := new(chunkFeatureWriter)
-	for i, chunk := range doc.Chunks {
-		chunkFeatureWriter.Assign(chunkFeatures[i][:])
-		chunkFeatureWriter.WriteElementType(chunk)
-		chunkFeatureWriter.WriteParentType(chunk)
-		chunkFeatureWriter.WriteSiblingTypes(chunk)
-		chunkFeatureWriter.WriteAncestors(chunk)
-		chunkFeatureWriter.WriteTextStat(chunk)
-		chunkFeatureWriter.WriteTextStatSiblings(chunk)
-		chunkFeatureWriter.WriteClassStat(chunk, classStats)
-		chunkFeatureWriter.WriteClusterStat(chunk, clusterStats)
+// ExtractFunc works like ExtractContext, but calls fn for every chunk of doc,
+// in document order, once the chunks are classified. The Labels of the
+// extractor are complete by then, so fn may examine the neighbors of the
+// chunk as well. fn is called even if no article is returned afterwards,
+// which allows processing the chunks with custom thresholds.
+func (ext *Extractor) ExtractFunc(ctx context.Context, doc *html.Document, fn func(ChunkScore)) (*util.Article, error) {
+	start := time.Now()
+	article, err := ext.extract(ctx, doc, fn)
+	elapsed := time.Since(start)
+	ext.logExtraction(doc, article, err, elapsed)
+	if observer := ext.Options.Observer; observer != nil {
+		observer.Extracted(doc, article, err, elapsed)
 	}
+	return article, err
+}
 
-	// Detect the minimum and maximum value for each element in the
-	// feature vector.
-	empMin := chunkFeature{}
-	empMax := chunkFeature{}
-	for i := range chunkFeatures {
-		for j, val := range chunkFeatures[i] {
-			switch {
-			case val < empMin[j]:
-				empMin[j] = val
-			case val > empMax[j]:
-				empMax[j] = val
-			}
+// logExtraction logs the extraction of doc, which returned article or err.
+func (ext *Extractor) logExtraction(doc *html.Document, article *util.Article, err error, elapsed time.Duration) {
+	logger := util.Logger(ext.Options.Logger)
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if err != nil {
+		logger.Debug("extraction failed", "url", doc.URL(), "elapsed", elapsed, "chunks", len(doc.Chunks), "err", err)
+		return
+	}
+	labeled := 0
+	for _, label := range ext.Labels {
+		if label {
+			labeled++
 		}
 	}
+	logger.Debug("extracted article",
+		"url", doc.URL(),
+		"elapsed", elapsed,
+		"chunks", len(doc.Chunks),
+		"extracted", labeled,
+		"words", article.Stats.Words,
+		"title", article.Title,
+		"confidence", article.Confidence,
+		"fallback", article.Fallback,
+	)
+}
 
-	// Perform MinMax normalization.
-	for i := range chunkFeatures {
-		feature := &chunkFeatures[i]
-		for j, val := range chunkFeatures[i] {
-			// If the maximum value is not greater than one, we assume that the feature is
-			// already normalized and leave it untouched.
-			if empMax[j] > 1.0 {
-				feature[j] = (val - empMin[j]) / (empMax[j] - empMin[j])
-			}
-		}
+// extract implements ExtractFunc.
+func (ext *Extractor) extract(ctx context.Context, doc *html.Document, fn func(ChunkScore)) (*util.Article, error) {
+	ext.Labels = nil
+	if len(doc.Chunks) == 0 {
+		return nil, ErrNoChunks
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The preset adjusts the options for this document only.
+	opts, preset := ext.presetOptions(doc)
+	defer func(saved Options) { ext.Options = saved }(ext.Options)
+	ext.Options = opts
+	logger := util.Logger(opts.Logger)
+	if preset != nil {
+		logger.Debug("selected preset", "url", doc.URL(), "preset", preset.Name)
+	}
+
+	// Features the weights don't use aren't computed. The feature vectors
+	// of the last document are overwritten, which saves allocating them for
+	// every document.
+	weights := ext.weights().snapshot()
+	workers := ext.chunkWorkers(len(doc.Chunks))
+	chunkFeatures := newChunkFeatures(doc, ext.features, workers, weights.features())
+	ext.features = chunkFeatures
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Now cluster chunks by containers to calculate average score per
-	// container.
+	// container. The chunks are scored in parallel, but clustered in
+	// document order.
+	scratch := scratchPool.Get().(*extractScratch)
+	defer scratchPool.Put(scratch)
+	scores := resizeScores(scratch.scores, len(doc.Chunks))
+	scratch.scores = scores
+	parallelRanges(len(doc.Chunks), workers, func(worker, start, end int) {
+		for i := start; i < end; i++ {
+			scores[i] = chunkFeatures[i].Score(weights)
+		}
+	})
 	clusterContainer := newClusterMap()
 	for i, chunk := range doc.Chunks {
-		clusterContainer.Add(chunk.Container, chunk, chunkFeatures[i].Score())
+		clusterContainer.Add(chunk.Container, chunk, scores[i])
 	}
 
-	boostFeatureWriter := new(boostFeatureWriter)
-	for i, chunk := range doc.Chunks {
-		boostFeatureWriter.Assign(boostFeatures[i][:])
-		boostFeatureWriter.WriteChunk(chunk)
-		boostFeatureWriter.WriteCluster(chunk, clusterContainer[chunk.Container])
-		boostFeatureWriter.WriteTitleSimilarity(chunk, doc.Title)
+	var boostFeatures []boostFeature
+	if ext.Options.Boost {
+		boostFeatures = newBoostFeatures(doc, clusterContainer, scratch.boost, workers)
+		scratch.boost = boostFeatures
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Cluster chunks by block.
+	// Cluster chunks by block. Linear boost models imported by
+	// LoadLinearModel replace the random forest.
+	boost := ext.weights().boost
+	parallelRanges(len(doc.Chunks), workers, func(worker, start, end int) {
+		for i := start; i < end; i++ {
+			switch {
+			case ext.Options.Boost && boost != nil:
+				scores[i] = boostFeatures[i].Probability(boost)
+			case ext.Options.Boost:
+				scores[i] = boostFeatures[i].Score()
+			default:
+				scores[i] = chunkFeatures[i].Probability(weights)
+			}
+		}
+	})
 	clusterBlock := newClusterMap()
 	for i, chunk := range doc.Chunks {
-		clusterBlock.Add(chunk.Block, chunk, boostFeatures[i].Score(), float32(chunk.Text.Len()))
+		clusterBlock.Add(chunk.Block, chunk, scores[i], float32(chunk.Text.Len()))
 	}
 
 	// Label all chunks whose blocks have a score above prediction level.
-	// This makes sure that we don't split large blocks.
+	// This makes sure that we don't split large blocks. Chunks the user
+	// asked for are labeled regardless of their scores.
 	ext.Labels = make([]bool, len(doc.Chunks))
 	for i, chunk := range doc.Chunks {
 		if cluster, ok := clusterBlock[chunk.Block]; ok {
-			ext.Labels[i] = cluster.Score() > 0.5
+			ext.Labels[i] = chunk.Included || (cluster.Score() > ext.Options.Threshold && ext.keep(chunk, cluster))
+		}
+	}
+
+	// If the model isn't confident or found nothing, the page probably is
+	// unlike the pages it was trained on. Let the rule-based scorer try.
+	confidence := ext.confidence(doc, clusterBlock)
+	fallback := false
+	if ext.Options.Fallback && (confidence < ext.Options.MinConfidence || !anyLabel(ext.Labels)) {
+		reason := "model not confident"
+		if confidence >= ext.Options.MinConfidence {
+			reason = "model found nothing"
 		}
+		logger.Debug("falling back to rule-based scorer", "url", doc.URL(), "reason", reason,
+			"confidence", confidence, "min_confidence", ext.Options.MinConfidence)
+		ext.Labels = ext.fallbackLabels(doc)
+		fallback = true
 	}
 
-	result := &util.Article{Title: doc.Title.String()}
+	// Drop text repeating on the pages of the site, unless the user asked
+	// for it.
+	if ext.Options.Boilerplate != nil {
+		ext.Options.Boilerplate.Learn(doc)
+		for i, repeated := range ext.Options.Boilerplate.Repeated(doc) {
+			if repeated && !doc.Chunks[i].Included {
+				ext.Labels[i] = false
+			}
+		}
+	}
+
+	// Pull quotes repeat the article text for emphasis, so they are dropped
+	// unless the user asked for them.
 	for i, chunk := range doc.Chunks {
-		if cluster, ok := clusterBlock[chunk.Block]; ok && ext.Labels[i] {
-			text := util.NewText()
-			for _, chunk := range cluster.Chunks {
-				text.WriteText(chunk.Text)
+		if chunk.Quote != nil && chunk.Quote.Pull && !chunk.Included {
+			ext.Labels[i] = false
+		}
+	}
+
+	// Tables and code blocks don't look like prose and score poorly. Keep
+	// them if the chunks around them were extracted.
+	for i, chunk := range doc.Chunks {
+		if (chunk.IsTable() || chunk.IsCode()) && i > 0 && i+1 < len(doc.Chunks) && ext.Labels[i-1] && ext.Labels[i+1] {
+			ext.Labels[i] = true
+		}
+	}
+
+	if fn != nil {
+		for i, chunk := range doc.Chunks {
+			fn(ChunkScore{
+				Chunk:      chunk,
+				Index:      i,
+				Score:      scores[i],
+				BlockScore: clusterBlock[chunk.Block].Score(),
+				Label:      ext.Labels[i],
+			})
+		}
+	}
+
+	result := &util.Article{Title: doc.Title.String(), Confidence: confidence, Fallback: fallback}
+	result.Canonical, result.Image = doc.CanonicalPage, doc.Image
+	if preset != nil {
+		result.Preset = preset.Name
+	}
+	if titles := ext.rankTitles(doc); len(titles) > 0 {
+		result.Title, result.AltTitles = titles[0], titles[1:]
+	}
+	if doc.Language != nil {
+		result.Language = doc.Language.Code
+	}
+	if !doc.Date.IsZero() {
+		result.Date = doc.Date.Format(time.RFC3339)
+	}
+	stats := new(util.Stats)
+	content := make([]string, 0)
+	paragraphs := make([]string, 0)
+	done := make(map[*cluster]bool)
+	if ext.Options.Paths {
+		result.Paths = make([]string, 0)
+	}
+	var quoted *html.Quote // quotation of the last text added, if any
+	add := func(v interface{}, path string) {
+		quoted = nil
+		result.Append(v)
+		if ext.Options.Paths {
+			result.Paths = append(result.Paths, path)
+		}
+	}
+	embeds := doc.Embeds
+	addEmbeds := func(position int) {
+		for ; len(embeds) > 0 && embeds[0].Position <= position; embeds = embeds[1:] {
+			if ext.keepEmbed(embeds[0]) {
+				add(util.Embed{Provider: embeds[0].Provider, URL: embeds[0].URL}, embeds[0].Path)
 			}
-			if chunk.IsHeading() {
-				result.Append(util.Heading(text.String()))
-			} else {
-				result.Append(util.Paragraph(text.String()))
+		}
+	}
+	for i, chunk := range doc.Chunks {
+		addEmbeds(i)
+		if cluster, ok := clusterBlock[chunk.Block]; ok && ext.Labels[i] && !done[cluster] {
+			text := util.NewTextLanguage(doc.Language)
+			output, links := ext.writeCluster(text, cluster, len(result.Text))
+			stats.WriteText(text, !chunk.IsHeading(), chunk.LinkText)
+			path := ""
+			if ext.Options.Paths {
+				path = doc.NodePath(chunk.Block)
+			}
+			switch {
+			case chunk.IsHeading():
+				add(util.Heading(output), path)
+				content = append(content, text.String())
+			case chunk.IsTable():
+				add(util.Table(chunk.Table), path)
+				content = append(content, text.String())
+			case chunk.IsCode():
+				add(util.Code(chunk.Code), path)
+			case chunk.Quote != nil:
+				// The paragraphs of a quotation form a single quote.
+				if n := len(result.Text); quoted == chunk.Quote {
+					quote := result.Text[n-1].(util.Quote)
+					shift := utf8.RuneCountInString(quote.Text) + 1
+					for _, link := range links {
+						link.Index, link.Start, link.End = n-1, link.Start+shift, link.End+shift
+					}
+					quote.Text += "\n" + output
+					result.Text[n-1] = quote
+				} else {
+					add(util.Quote{Text: output, Attribution: chunk.Quote.Attribution, Source: chunk.Quote.Source}, path)
+				}
+				quoted = chunk.Quote
+				content = append(content, text.String())
+			default:
+				add(util.Paragraph(output), path)
+				content = append(content, text.String())
+				paragraphs = append(paragraphs, text.String())
 			}
-			delete(clusterBlock, chunk.Block)
+			result.Links = append(result.Links, links...)
+			done[cluster] = true
 		}
 	}
+	addEmbeds(len(doc.Chunks))
 	if len(result.Text) == 0 {
 		return nil, ErrEmptyResult
 	}
+	if stats.Words < ext.Options.MinArticleWords {
+		return nil, ErrTooShort
+	}
+	result.Tags = doc.Tags
+	result.Stats = stats
+	result.UpdateFingerprint()
+	if ext.Options.Keywords > 0 {
+		result.Keywords = util.Keywords(strings.Join(content, "\n"), doc.Language, ext.Options.Keywords)
+	}
+	if ext.Options.Summary > 0 {
+		keywords := result.Keywords
+		if keywords == nil {
+			keywords = util.Keywords(strings.Join(content, "\n"), doc.Language, summaryKeywords)
+		}
+		result.Summary = util.Summarize(paragraphs, result.Title, keywords, doc.Language, ext.Options.Summary)
+	}
 	return result, nil
 }
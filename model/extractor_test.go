@@ -0,0 +1,286 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/util"
+	gonet "golang.org/x/net/html"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkPage returns a news page with navigation, an article of n
+// paragraphs and a footer.
+func benchmarkPage(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>A benchmark story</title></head><body>`)
+	b.WriteString(`<div class="nav"><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li></ul></div>`)
+	b.WriteString(`<div class="article"><h1>A benchmark story</h1>`)
+	for i := 0; i < n; i++ {
+		b.WriteString(`<p class="text">The council met on Tuesday to discuss the budget. Members argued about
+			the <a href="/roads">road repairs</a> for hours, but no decision was reached. The vote
+			was postponed until next week, when the mayor returns.</p>`)
+	}
+	b.WriteString(`</div><div class="footer"><p>Copyright 2024 <a href="/about">About us</a></p></div></body></html>`)
+	return b.String()
+}
+
+func TestExtractParallel(t *testing.T) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Chunks) < minParallelChunks {
+		t.Fatalf("got %d chunks, want at least %d", len(doc.Chunks), minParallelChunks)
+	}
+	for _, boost := range []bool{true, false} {
+		opts := DefaultOptions
+		opts.Boost, opts.ChunkWorkers = boost, 1
+		want, err := NewExtractorOptions(opts).Extract(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.ChunkWorkers = 4
+		got, err := NewExtractorOptions(opts).Extract(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("boost %v: parallel extraction differs from sequential extraction", boost)
+		}
+	}
+}
+
+func TestExtractFromNode(t *testing.T) {
+	parse := func() *gonet.Node {
+		root, err := gonet.Parse(strings.NewReader(optionsPage()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	list, err := html.ParseSelector("ul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewExtractorPool()
+	article, err := pool.ExtractFromNode(parse(), html.Options{Exclude: []*html.Selector{list}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := article.Content(); !strings.Contains(content, "The council met") || strings.Contains(content, "school budget") {
+		t.Errorf("got article %q", content)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.ExtractFromNodeContext(ctx, parse(), html.Options{}); err != context.Canceled {
+		t.Errorf("cancelled: got error %v", err)
+	}
+}
+
+func TestExtractFunc(t *testing.T) {
+	doc, err := html.NewDocument(strings.NewReader(optionsPage()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := NewExtractor()
+	scores := make([]ChunkScore, 0)
+	article, err := ext.ExtractFunc(context.Background(), doc, func(score ChunkScore) {
+		scores = append(scores, score)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != len(doc.Chunks) {
+		t.Fatalf("got %d scores for %d chunks", len(scores), len(doc.Chunks))
+	}
+	labeled := make([]string, 0)
+	for i, score := range scores {
+		if score.Index != i || score.Chunk != doc.Chunks[i] || score.Label != ext.Labels[i] {
+			t.Errorf("score %d: got index %d, chunk %p, label %v", i, score.Index, score.Chunk, score.Label)
+		}
+		if score.Label != (score.BlockScore > ext.Options.Threshold) {
+			t.Errorf("score %d: got label %v with block score %v", i, score.Label, score.BlockScore)
+		}
+		if score.Label {
+			labeled = append(labeled, score.Chunk.Text.String())
+		}
+	}
+	if got, want := strings.Join(labeled, "\n"), article.Content(); got != want {
+		t.Errorf("labeled chunks %q differ from the article %q", got, want)
+	}
+
+	// The chunks are reported even if no article is found.
+	ext.Options.Threshold = 1
+	calls := 0
+	if _, err := ext.ExtractFunc(context.Background(), doc, func(ChunkScore) { calls++ }); err != ErrEmptyResult {
+		t.Errorf("got error %v, want ErrEmptyResult", err)
+	}
+	if calls != len(doc.Chunks) {
+		t.Errorf("got %d calls without article, want %d", calls, len(doc.Chunks))
+	}
+}
+
+func TestExtractObserver(t *testing.T) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	opts := DefaultOptions
+	opts.Observer = ObserverFunc(func(observed *html.Document, article *util.Article, err error, elapsed time.Duration) {
+		calls++
+		if observed != doc || article == nil || err != nil || elapsed <= 0 {
+			t.Errorf("observed %p, %v, %v, %v", observed, article, err, elapsed)
+		}
+	})
+	if _, err := NewExtractorPoolOptions(opts).Extract(doc); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("observer called %d times, want 1", calls)
+	}
+}
+
+func TestExtractLogger(t *testing.T) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opts := DefaultOptions
+	opts.MinConfidence, opts.Fallback = 2, true
+	opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := NewExtractorOptions(opts).Extract(doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`msg="falling back to rule-based scorer"`, `msg="extracted article"`, `fallback=true`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log lacks %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+// optionsPage returns a news page whose article has a heading and a list,
+// which some of the options drop.
+func optionsPage() string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>A test story</title></head><body>`)
+	b.WriteString(`<div class="nav"><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li></ul></div>`)
+	b.WriteString(`<div class="article"><h1>A test story</h1>`)
+	for i := 0; i < 6; i++ {
+		b.WriteString(`<p class="text">The council met on Tuesday to discuss the budget. Members argued about
+			the road repairs for hours, but no decision was reached. The vote was postponed until
+			next week, when the mayor returns.</p>`)
+		if i == 2 {
+			b.WriteString(`<ul><li>The council approved the new school budget after a long debate, which lasted
+				until late on Tuesday evening, as members of all parties wanted to speak.</li></ul>`)
+		}
+	}
+	b.WriteString(`</div><div class="footer"><p>Copyright 2024 <a href="/about">About us</a></p></div></body></html>`)
+	return b.String()
+}
+
+func TestExtractOptions(t *testing.T) {
+	contains := func(article *util.Article, text string) bool {
+		return strings.Contains(article.Content(), text)
+	}
+	tests := []struct {
+		name   string
+		modify func(opts *Options)
+		err    error
+		check  func(article *util.Article) bool
+	}{
+		{"default", func(opts *Options) {}, nil, func(a *util.Article) bool {
+			return contains(a, "A test story") && contains(a, "school budget") && !contains(a, "Copyright")
+		}},
+		{"no headings", func(opts *Options) { opts.KeepHeadings = false }, nil, func(a *util.Article) bool {
+			return !contains(a, "A test story") && contains(a, "The council met")
+		}},
+		{"no lists", func(opts *Options) { opts.KeepLists = false }, nil, func(a *util.Article) bool {
+			return !contains(a, "school budget") && contains(a, "The council met")
+		}},
+		{"no boost", func(opts *Options) { opts.Boost = false }, nil, func(a *util.Article) bool {
+			return contains(a, "The council met") && !contains(a, "Copyright")
+		}},
+		{"min chunk words", func(opts *Options) { opts.MinChunkWords = 1000 }, nil, func(a *util.Article) bool {
+			return contains(a, "A test story") && !contains(a, "The council met")
+		}},
+		{"min article words", func(opts *Options) { opts.MinArticleWords = 1000 }, ErrTooShort, nil},
+		{"threshold", func(opts *Options) { opts.Threshold = 1 }, ErrEmptyResult, nil},
+	}
+	for _, test := range tests {
+		doc, err := html.NewDocument(strings.NewReader(optionsPage()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := DefaultOptions
+		test.modify(&opts)
+		article, err := NewExtractorOptions(opts).Extract(doc)
+		switch {
+		case err != test.err:
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		case test.check != nil && !test.check(article):
+			t.Errorf("%s: got article %q", test.name, article.Content())
+		}
+	}
+}
+
+func BenchmarkExtract(b *testing.B) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(50)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ext := NewExtractor()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ext.Extract(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractPool(b *testing.B) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(50)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	pool := NewExtractorPool()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := pool.Extract(doc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkExtractLong(b *testing.B) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(2000)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, workers := range []int{1, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := DefaultOptions
+			opts.ChunkWorkers = workers
+			ext := NewExtractorOptions(opts)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ext.Extract(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	gonet "golang.org/x/net/html"
+	"strings"
+)
+
+// Chunks consisting of more link text than this are never labeled by the
+// fallback scorer.
+const maxFallbackLinkText = 0.5
+
+// fallbackLabels labels the chunks of doc using a rule-based scorer similar
+// to Readability. It's used if the model isn't confident about a document,
+// which happens mostly for pages unlike the pages the model was trained on.
+//
+// Every chunk scores its number of words outside of links plus one point per
+// comma. The score is added to the container of the chunk and, halved, to
+// the parent of the container. All chunks below the best scoring node are
+// labeled, unless they consist mostly of link text.
+func (ext *Extractor) fallbackLabels(doc *html.Document) []bool {
+	scores := make(map[*gonet.Node]float32)
+	for _, chunk := range doc.Chunks {
+		score := float32(chunk.Text.Words)*(1.0-chunk.LinkText) + float32(strings.Count(chunk.Text.String(), ","))
+		scores[chunk.Container] += score
+		if parent := chunk.Container.Parent; parent != nil {
+			scores[parent] += score / 2
+		}
+	}
+
+	// Iterating the chunks rather than the map keeps ties deterministic.
+	var best *gonet.Node
+	for _, chunk := range doc.Chunks {
+		for _, n := range []*gonet.Node{chunk.Container, chunk.Container.Parent} {
+			if n != nil && (best == nil || scores[n] > scores[best]) {
+				best = n
+			}
+		}
+	}
+
+	labels := make([]bool, len(doc.Chunks))
+	for i, chunk := range doc.Chunks {
+		switch {
+		case chunk.Included:
+			labels[i] = true
+		case chunk.LinkText > maxFallbackLinkText:
+		case chunk.IsHeading() && !ext.Options.KeepHeadings:
+		case !ext.Options.KeepLists && (chunk.Ancestors&html.AncestorList) != 0:
+		default:
+			for n := chunk.Container; n != nil; n = n.Parent {
+				if n == best {
+					labels[i] = true
+					break
+				}
+			}
+		}
+	}
+	return labels
+}
+
+// confidence returns how certain the model is about the labels of the blocks
+// of doc in clusterBlock, a value between zero and one. It's the average
+// distance of the block scores from the threshold, relative to the largest
+// possible distance and weighted by the text length of the blocks. The blocks
+// are summed up in document order, so the result doesn't vary between
+// extractions.
+func (ext *Extractor) confidence(doc *html.Document, clusterBlock clusterMap) float32 {
+	threshold := ext.Options.Threshold
+	max := threshold
+	if 1.0-threshold > max {
+		max = 1.0 - threshold
+	}
+	sum, weight := float32(0.0), float32(0.0)
+	done := make(map[*gonet.Node]bool)
+	for _, chunk := range doc.Chunks {
+		cluster, ok := clusterBlock[chunk.Block]
+		if !ok || done[chunk.Block] {
+			continue
+		}
+		done[chunk.Block] = true
+		dist := cluster.Score() - threshold
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist > max {
+			dist = max
+		}
+		w := float32(0.0)
+		for _, chunk := range cluster.Chunks {
+			w += float32(chunk.Text.Len())
+		}
+		sum += w * dist / max
+		weight += w
+	}
+	if weight == 0 || max <= 0 {
+		return 0.0
+	}
+	return sum / weight
+}
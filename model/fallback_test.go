@@ -0,0 +1,86 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"strings"
+	"testing"
+)
+
+func TestFallbackLabels(t *testing.T) {
+	const page = `<html><head><title>Story</title></head><body>
+		<div class="nav"><ul><li><a href="/">Home</a></li><li><a href="/world">World, Europe</a></li></ul></div>
+		<div class="story"><h1>Mayor resigns</h1>
+			<p>The mayor resigned on Tuesday, after weeks of pressure, citing health reasons.</p>
+			<p>Her deputy, who led the council before, takes over until the election in May.</p>
+			<p>Residents, shop owners and council members welcomed the decision.</p>
+		</div>
+		<div class="teaser"><p>Read more: <a href="/other">An other story, with commas, many</a></p></div>
+		<div class="footer"><p>Copyright 2024</p></div>
+		</body></html>`
+	footer, err := html.ParseSelector(".footer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := html.NewDocumentOptions(strings.NewReader(page), html.Options{Include: []*html.Selector{footer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labeled := func(opts Options) string {
+		ext := NewExtractorOptions(opts)
+		texts := make([]string, 0)
+		for i, label := range ext.fallbackLabels(doc) {
+			if label {
+				texts = append(texts, strings.Fields(doc.Chunks[i].Text.String())[0])
+			}
+		}
+		return strings.Join(texts, " ")
+	}
+	if got, want := labeled(DefaultOptions), "Mayor The Her Residents, Copyright"; got != want {
+		t.Errorf("got labels %q, want %q", got, want)
+	}
+	opts := DefaultOptions
+	opts.KeepHeadings = false
+	if got, want := labeled(opts), "The Her Residents, Copyright"; got != want {
+		t.Errorf("without headings: got labels %q, want %q", got, want)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	const para = `<div><p>The mayor resigned on Tuesday, citing health reasons.</p></div>`
+	doc, err := html.NewDocument(strings.NewReader("<html><body>" + strings.Repeat(para, 20) + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := NewExtractor()
+	clusters := func(score func(i int) float32) clusterMap {
+		result := newClusterMap()
+		for i, chunk := range doc.Chunks {
+			result.Add(chunk.Block, chunk, score(i), float32(chunk.Text.Len()))
+		}
+		return result
+	}
+	tests := []struct {
+		name  string
+		score func(i int) float32
+		want  float32
+	}{
+		{"certain", func(i int) float32 { return float32(i % 2) }, 1},
+		{"undecided", func(i int) float32 { return 0.5 }, 0},
+		{"half", func(i int) float32 { return 0.5 + 0.5*float32(i%2) }, 0.5},
+	}
+	for _, test := range tests {
+		if got := ext.confidence(doc, clusters(test.score)); got != test.want {
+			t.Errorf("%s: got confidence %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// The blocks are summed up in document order, which doesn't depend on
+	// the order of the map.
+	scores := clusters(func(i int) float32 { return float32(i) / 19 })
+	want := ext.confidence(doc, scores)
+	for i := 0; i < 20; i++ {
+		if got := ext.confidence(doc, scores); got != want {
+			t.Fatalf("got confidence %v, then %v", want, got)
+		}
+	}
+}
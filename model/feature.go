@@ -3,10 +3,13 @@ package model
 import (
 	"github.com/slyrz/newscat/html"
 	"github.com/slyrz/newscat/util"
+	gonet "golang.org/x/net/html"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
-	chunkFeatureCap = 36
+	chunkFeatureCap = 52
 	boostFeatureCap = 10
 )
 
@@ -16,6 +19,97 @@ type feature []float32
 type chunkFeature [chunkFeatureCap]float32
 type boostFeature [boostFeatureCap]float32
 
+// chunkFeatureGroups names the components of the chunk feature vectors,
+// grouped by the writer producing them, in the order they are written.
+var chunkFeatureGroups = []struct {
+	name    string
+	columns []string
+}{
+	{"element", []string{"element_p", "element_a", "element_div", "element_heading"}},
+	{"parent", []string{"parent_p", "parent_span", "parent_div", "parent_li"}},
+	{"siblings", []string{"siblings", "siblings_a", "siblings_p", "siblings_img", "siblings_a_ratio", "siblings_p_ratio", "siblings_img_ratio"}},
+	{"ancestors", []string{"ancestor_article", "ancestor_aside", "ancestor_blockquote", "ancestor_list", "ancestor_nav", "ancestor_header", "ancestor_footer", "ancestor_main", "ancestor_section", "ancestor_figure", "ancestor_table"}},
+	{"text", []string{"words", "sentences", "link_text"}},
+	{"text_siblings", []string{"prev_same_block", "prev_words", "prev_sentences", "next_same_block", "next_words", "next_sentences"}},
+	{"class", []string{"class_found", "class_words", "class_sentences"}},
+	{"cluster", []string{"cluster_words", "cluster_sentences", "cluster_count", "cluster_avg_words", "cluster_avg_sentences"}},
+	{"stopwords", []string{"stopword_ratio"}},
+	{"density", []string{"punct_per_word", "capitalized_ratio", "digit_ratio", "letters_per_element", "word_length"}},
+	{"position", []string{"position", "depth", "center_distance"}},
+}
+
+// Numbers of the features of the optional groups.
+const (
+	stopwordFeatures = 1
+	densityFeatures  = 5
+	positionFeatures = 3
+)
+
+// optionalFeatures selects the optional feature groups of the chunk vectors:
+// stopwords, density and position. The default model wasn't trained with
+// them, so they are only computed for weights using them, like weights
+// trained by tune, and left zero otherwise.
+type optionalFeatures struct {
+	stopwords, density, position bool
+}
+
+// allFeatures selects all optional feature groups, which training needs.
+var allFeatures = optionalFeatures{true, true, true}
+
+// features returns the optional feature groups m has nonzero coefficients
+// for.
+func (m *logitModel) features() optionalFeatures {
+	uses := func(name string) bool {
+		i := 0
+		for _, group := range chunkFeatureGroups {
+			for range group.columns {
+				if group.name == name && i < len(m.Coefficients) && m.Coefficients[i] != 0 {
+					return true
+				}
+				i++
+			}
+		}
+		return false
+	}
+	return optionalFeatures{uses("stopwords"), uses("density"), uses("position")}
+}
+
+// boostFeatureColumns names the components of the boost feature vectors.
+var boostFeatureColumns = []string{
+	"boost_link_text", "boost_words", "boost_sentences", "boost_good_class", "boost_poor_class",
+	"boost_container_score", "boost_chunk_score", "boost_prev_score", "boost_next_score",
+	"boost_title_similarity",
+}
+
+// chunkFeatureColumns returns the names of the components of the chunk
+// feature vectors.
+func chunkFeatureColumns() []string {
+	result := make([]string, 0, chunkFeatureCap)
+	for _, group := range chunkFeatureGroups {
+		result = append(result, group.columns...)
+	}
+	return result
+}
+
+// FeatureColumns returns the names of the components of the chunk feature
+// vectors or, if boost is true, of the boost feature vectors, in the order
+// Corpus.Export writes them.
+func FeatureColumns(boost bool) []string {
+	if boost {
+		return append([]string(nil), boostFeatureColumns...)
+	}
+	return chunkFeatureColumns()
+}
+
+// FeatureGroups returns the names of the groups of chunk features.
+func FeatureGroups() []string {
+	result := make([]string, len(chunkFeatureGroups))
+	for i, group := range chunkFeatureGroups {
+		result[i] = group.name
+	}
+	return result
+}
+
 // featureWriter writes observations to feature vectors.
 type featureWriter struct {
 	feature feature
@@ -108,23 +202,73 @@ func (fw *chunkFeatureWriter) WriteParentType(chunk *html.Chunk) {
 	fw.Skip(4)
 }
 
-func (fw *chunkFeatureWriter) WriteSiblingTypes(chunk *html.Chunk) {
-	count := 0
-	types := map[string]int{"a": 0, "p": 0, "img": 0}
-	for _, siblingType := range chunk.GetSiblingTypes() {
-		count += 1
-		if val, ok := types[siblingType]; ok {
-			types[siblingType] = val + 1
+// siblingCount holds the number of elements among the children of a node
+// and how many of them are <a>, <p> and <img> elements.
+type siblingCount struct {
+	elements, a, p, img int
+}
+
+// add adds n to the count if it's an element. The count of n itself is
+// subtracted if sign is -1.
+func (sc *siblingCount) add(n *gonet.Node, sign int) {
+	if n.Type != gonet.ElementNode {
+		return
+	}
+	sc.elements += sign
+	switch n.Data {
+	case "a":
+		sc.a += sign
+	case "p":
+		sc.p += sign
+	case "img":
+		sc.img += sign
+	}
+}
+
+// siblingCounts maps the parents of the base nodes of chunks to the counts
+// of their children. Counting the children once per parent keeps documents
+// with thousands of sibling paragraphs from counting in quadratic time.
+type siblingCounts map[*gonet.Node]siblingCount
+
+// newSiblingCounts counts the children of the parents of the base nodes of
+// the chunks.
+func newSiblingCounts(chunks []*html.Chunk) siblingCounts {
+	result := make(siblingCounts)
+	for _, chunk := range chunks {
+		parent := chunk.Base.Parent
+		if _, ok := result[parent]; ok || parent == nil {
+			continue
 		}
+		var count siblingCount
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			count.add(c, 1)
+		}
+		result[parent] = count
+	}
+	return result
+}
+
+// of returns the counts of the siblings of n, which is a base node of the
+// chunks counted.
+func (sc siblingCounts) of(n *gonet.Node) siblingCount {
+	if n.Parent == nil {
+		return siblingCount{}
 	}
-	fw.Write(count)
-	fw.Write(types["a"])
-	fw.Write(types["p"])
-	fw.Write(types["img"])
-	if count > 0 {
-		fw.Write(float32(types["a"]) / float32(count))
-		fw.Write(float32(types["p"]) / float32(count))
-		fw.Write(float32(types["img"]) / float32(count))
+	count := sc[n.Parent]
+	count.add(n, -1)
+	return count
+}
+
+func (fw *chunkFeatureWriter) WriteSiblingTypes(chunk *html.Chunk, siblings siblingCounts) {
+	count := siblings.of(chunk.Base)
+	fw.Write(count.elements)
+	fw.Write(count.a)
+	fw.Write(count.p)
+	fw.Write(count.img)
+	if count.elements > 0 {
+		fw.Write(float32(count.a) / float32(count.elements))
+		fw.Write(float32(count.p) / float32(count.elements))
+		fw.Write(float32(count.img) / float32(count.elements))
 	} else {
 		fw.Skip(3)
 	}
@@ -135,6 +279,13 @@ func (fw *chunkFeatureWriter) WriteAncestors(chunk *html.Chunk) {
 	fw.Write((chunk.Ancestors & html.AncestorAside) != 0)
 	fw.Write((chunk.Ancestors & html.AncestorBlockquote) != 0)
 	fw.Write((chunk.Ancestors & html.AncestorList) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorNav) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorHeader) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorFooter) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorMain) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorSection) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorFigure) != 0)
+	fw.Write((chunk.Ancestors & html.AncestorTable) != 0)
 }
 
 func (fw *chunkFeatureWriter) WriteTextStat(chunk *html.Chunk) {
@@ -191,54 +342,78 @@ func (fw *chunkFeatureWriter) WriteClusterStat(chunk *html.Chunk, clusters map[*
 	}
 }
 
+func (fw *chunkFeatureWriter) WriteStopwordStat(chunk *html.Chunk) {
+	fw.Write(chunk.Text.StopwordRatio())
+}
+
+// WritePosition writes where the chunk at index i of the chunks is found:
+// its relative position in the document, its depth in the DOM tree and its
+// relative distance from center, the index of the chunk in the middle of the
+// main content. Navigation and footer text are found at the extremes of the
+// document.
+func (fw *chunkFeatureWriter) WritePosition(chunk *html.Chunk, i int, chunks int, center int) {
+	dist := i - center
+	if dist < 0 {
+		dist = -dist
+	}
+	fw.Write(float32(i) / float32(chunks))
+	fw.Write(chunk.Depth)
+	fw.Write(float32(dist) / float32(chunks))
+}
+
+// WriteDensityStat writes the classic boilerplate detection features:
+// punctuation marks per word, the ratio of capitalized words, the ratio of
+// digits, letters per element and the average word length. Navigation and
+// data-heavy clutter tends to be capitalized, digit-heavy and short.
+func (fw *chunkFeatureWriter) WriteDensityStat(chunk *html.Chunk) {
+	words, capitalized, punct, digits, letters, chars := 0, 0, 0, 0, 0, 0
+	chunk.Text.EachToken(func(word string) {
+		words++
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+			capitalized++
+		}
+		for _, r := range word {
+			chars++
+			switch {
+			case unicode.IsLetter(r):
+				letters++
+			case unicode.IsDigit(r):
+				digits++
+			case unicode.IsPunct(r):
+				punct++
+			}
+		}
+	})
+	if words == 0 {
+		fw.Skip(2)
+	} else {
+		fw.Write(float32(punct) / float32(words))
+		fw.Write(float32(capitalized) / float32(words))
+	}
+	if chars == 0 {
+		fw.Skip(1)
+	} else {
+		fw.Write(float32(digits) / float32(chars))
+	}
+	fw.Write(chunk.Density)
+	if words == 0 {
+		fw.Skip(1)
+	} else {
+		fw.Write(float32(letters) / float32(words))
+	}
+}
+
 type boostFeatureWriter struct {
 	featureWriter
+	params *languageParams
 }
 
-var (
-	goodQualClass = util.NewRegexFromWords(
-		"article",
-		"catchline",
-		"chapter",
-		"content",
-		"head",
-		"intro",
-		"introduction",
-		"leadin",
-		"main",
-		"post",
-		"story",
-		"summary",
-		"title",
-	)
-	poorQualClass = util.NewRegexFromWords(
-		"author",
-		"blog",
-		"byline",
-		"caption",
-		"col",
-		"comment",
-		"description",
-		"email",
-		"excerpt",
-		"image",
-		"info",
-		"menu",
-		"metadata",
-		"nav",
-		"photo",
-		"small",
-		"teaser",
-		"widget",
-	)
-)
-
 func (fw *boostFeatureWriter) WriteChunk(chunk *html.Chunk) {
 	goodQual := false
 	poorQual := false
 	for _, class := range chunk.Classes {
-		goodQual = goodQual || goodQualClass.In(class)
-		poorQual = poorQual || poorQualClass.In(class)
+		goodQual = goodQual || fw.params.goodQualClass.In(class)
+		poorQual = poorQual || fw.params.poorQualClass.In(class)
 	}
 	fw.Write(chunk.LinkText)
 	fw.Write(chunk.Text.Words)
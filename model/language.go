@@ -0,0 +1,100 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/util"
+)
+
+// languageParams holds the model parameters that depend on the document
+// language. These are the words indicating good and poor quality classes.
+// Every language uses the English words, because plenty of non-English sites
+// name their classes in English, and adds its own words.
+type languageParams struct {
+	goodQualClass *util.Regex
+	poorQualClass *util.Regex
+}
+
+var (
+	goodQualWords = []string{
+		"article",
+		"catchline",
+		"chapter",
+		"content",
+		"head",
+		"intro",
+		"introduction",
+		"leadin",
+		"main",
+		"post",
+		"story",
+		"summary",
+		"title",
+	}
+	poorQualWords = []string{
+		"author",
+		"blog",
+		"byline",
+		"caption",
+		"col",
+		"comment",
+		"description",
+		"email",
+		"excerpt",
+		"image",
+		"info",
+		"menu",
+		"metadata",
+		"nav",
+		"photo",
+		"small",
+		"teaser",
+		"widget",
+	}
+)
+
+func newLanguageParams(goodQual []string, poorQual []string) *languageParams {
+	return &languageParams{
+		goodQualClass: util.NewRegexFromWords(append(goodQual, goodQualWords...)...),
+		poorQualClass: util.NewRegexFromWords(append(poorQual, poorQualWords...)...),
+	}
+}
+
+var (
+	defaultParams        = newLanguageParams(nil, nil)
+	languageParamsByCode = map[string]*languageParams{
+		"de": newLanguageParams(
+			[]string{"artikel", "beitrag", "inhalt", "text"},
+			[]string{"anzeige", "autor", "bild", "kommentar", "werbung"},
+		),
+		"fr": newLanguageParams(
+			[]string{"chapo", "contenu", "texte"},
+			[]string{"auteur", "commentaire", "legende", "publicite"},
+		),
+		"es": newLanguageParams(
+			[]string{"articulo", "contenido", "cuerpo", "noticia"},
+			[]string{"autor", "comentario", "publicidad"},
+		),
+		"it": newLanguageParams(
+			[]string{"articolo", "contenuto", "testo"},
+			[]string{"autore", "commento", "didascalia", "pubblicita"},
+		),
+		"pt": newLanguageParams(
+			[]string{"conteudo", "materia", "texto"},
+			[]string{"autor", "comentario", "legenda", "publicidade"},
+		),
+		"nl": newLanguageParams(
+			[]string{"artikel", "inhoud", "tekst"},
+			[]string{"advertentie", "auteur", "reactie"},
+		),
+	}
+)
+
+// getLanguageParams returns the model parameters for lang. Unknown languages
+// get the default parameters.
+func getLanguageParams(lang *util.Language) *languageParams {
+	if lang != nil {
+		if params, ok := languageParamsByCode[lang.Code]; ok {
+			return params
+		}
+	}
+	return defaultParams
+}
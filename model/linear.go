@@ -0,0 +1,275 @@
+package model
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrBadModel      = errors.New("not a linear model of two classes")
+	ErrUnknownColumn = errors.New("unknown feature column")
+	ErrMixedColumns  = errors.New("columns of chunk and boost features mixed")
+)
+
+// LoadLinearModel reads a linear model trained by liblinear or by libsvm with
+// a linear kernel, for example on the data written by Corpus.Export. Feature
+// i of the model is the feature named by columns[i-1]; columns defaults to
+// FeatureColumns(false). If the columns name boost features, the model
+// replaces the random forest. Labels greater than zero mark content.
+//
+// Support vector machines aren't calibrated like the logistic regression, so
+// their scores are mapped to probabilities by the logistic function as well.
+func LoadLinearModel(r io.Reader, columns []string) (*Weights, error) {
+	if columns == nil {
+		columns = chunkFeatureColumns()
+	}
+	index, boost, err := linearColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrBadModel
+	}
+	var weights map[int]float64
+	var intercept float64
+	switch fields := strings.Fields(scanner.Text()); {
+	case len(fields) == 2 && fields[0] == "solver_type":
+		weights, intercept, err = parseLiblinear(scanner, fields[1])
+	case len(fields) == 2 && fields[0] == "svm_type":
+		weights, intercept, err = parseLibsvm(scanner, fields[1])
+	default:
+		err = ErrBadModel
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n := chunkFeatureCap
+	if boost {
+		n = boostFeatureCap
+	}
+	model := &logitModel{Intercept: float32(intercept), Coefficients: make([]float32, n)}
+	for i, w := range weights {
+		if i < 1 || i > len(columns) {
+			if w != 0.0 {
+				return nil, ErrUnknownColumn
+			}
+			continue
+		}
+		model.Coefficients[index[i-1]] = float32(w)
+	}
+	if boost {
+		result := NewWeights()
+		result.boost = model
+		return result, nil
+	}
+	return &Weights{model: *model}, nil
+}
+
+// linearColumns returns the positions of the named columns in the chunk or,
+// if boost is true, in the boost feature vectors.
+func linearColumns(columns []string) (index []int, boost bool, err error) {
+	chunk := make(map[string]int)
+	for i, name := range chunkFeatureColumns() {
+		chunk[name] = i
+	}
+	other := make(map[string]int)
+	for i, name := range boostFeatureColumns {
+		other[name] = i
+	}
+	index = make([]int, len(columns))
+	chunks, boosts := 0, 0
+	for i, name := range columns {
+		if j, ok := chunk[name]; ok {
+			index[i] = j
+			chunks++
+		} else if j, ok := other[name]; ok {
+			index[i] = j
+			boosts++
+		} else {
+			return nil, false, ErrUnknownColumn
+		}
+	}
+	if chunks > 0 && boosts > 0 {
+		return nil, false, ErrMixedColumns
+	}
+	return index, boosts > 0, nil
+}
+
+// contentSign returns 1 if the first of the labels marks content and -1 if
+// the second one does. Both tools make positive decision values mean the
+// first label.
+func contentSign(labels []string) (float64, error) {
+	if len(labels) != 2 {
+		return 0, ErrBadModel
+	}
+	a, errA := strconv.ParseFloat(labels[0], 64)
+	b, errB := strconv.ParseFloat(labels[1], 64)
+	switch {
+	case errA != nil || errB != nil:
+		return 0, ErrBadModel
+	case a > 0 && b <= 0:
+		return 1, nil
+	case b > 0 && a <= 0:
+		return -1, nil
+	}
+	return 0, ErrBadModel
+}
+
+// parseLiblinear parses the rest of a liblinear model file after its
+// solver_type line. It returns the weights keyed by feature and the
+// intercept.
+func parseLiblinear(scanner *bufio.Scanner, solver string) (map[int]float64, float64, error) {
+	var labels []string
+	features, bias := -1, -1.0
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "w" {
+			break
+		}
+		var err error
+		switch fields[0] {
+		case "nr_class":
+			if len(fields) != 2 || fields[1] != "2" {
+				return nil, 0, ErrBadModel
+			}
+		case "label":
+			labels = fields[1:]
+		case "nr_feature":
+			if len(fields) == 2 {
+				features, err = strconv.Atoi(fields[1])
+			}
+		case "bias":
+			if len(fields) == 2 {
+				bias, err = strconv.ParseFloat(fields[1], 64)
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	sign, err := contentSign(labels)
+	if err != nil || features < 0 {
+		return nil, 0, ErrBadModel
+	}
+	// The Crammer and Singer solver keeps a weight per class, the others
+	// keep a single weight for two classes.
+	columns := 1
+	if solver == "MCSVM_CS" {
+		columns = 2
+	}
+	lines := features
+	if bias >= 0 {
+		lines++
+	}
+	weights := make(map[int]float64)
+	intercept := 0.0
+	for i := 1; i <= lines; i++ {
+		if !scanner.Scan() {
+			return nil, 0, ErrBadModel
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != columns {
+			return nil, 0, ErrBadModel
+		}
+		w, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		if columns == 2 {
+			v, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return nil, 0, err
+			}
+			w -= v
+		}
+		switch {
+		case i > features:
+			intercept = sign * w * bias
+		case w != 0.0:
+			weights[i] = sign * w
+		}
+	}
+	return weights, intercept, scanner.Err()
+}
+
+// parseLibsvm parses the rest of a libsvm model file after its svm_type line.
+// The weights are the sum of the support vectors weighted by their
+// coefficients.
+func parseLibsvm(scanner *bufio.Scanner, svmType string) (map[int]float64, float64, error) {
+	if svmType != "c_svc" && svmType != "nu_svc" {
+		return nil, 0, ErrBadModel
+	}
+	var labels []string
+	linear := false
+	rho := 0.0
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "SV" {
+			break
+		}
+		var err error
+		switch fields[0] {
+		case "kernel_type":
+			linear = len(fields) == 2 && fields[1] == "linear"
+		case "nr_class":
+			if len(fields) != 2 || fields[1] != "2" {
+				return nil, 0, ErrBadModel
+			}
+		case "label":
+			labels = fields[1:]
+		case "rho":
+			if len(fields) == 2 {
+				rho, err = strconv.ParseFloat(fields[1], 64)
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	sign, err := contentSign(labels)
+	if err != nil || !linear {
+		return nil, 0, ErrBadModel
+	}
+	weights := make(map[int]float64)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		coef, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, field := range fields[1:] {
+			i := strings.IndexByte(field, ':')
+			if i < 0 {
+				return nil, 0, ErrBadModel
+			}
+			j, err := strconv.Atoi(field[:i])
+			if err != nil {
+				return nil, 0, err
+			}
+			v, err := strconv.ParseFloat(field[i+1:], 64)
+			if err != nil {
+				return nil, 0, err
+			}
+			weights[j] += sign * coef * v
+		}
+	}
+	return weights, -sign * rho, scanner.Err()
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadLinearModel(t *testing.T) {
+	const liblinear = "solver_type L2R_LR\nnr_class 2\nlabel %s\nnr_feature 2\nbias 1\nw\n0.5\n-2\n0.25\n"
+	const libsvm = "svm_type c_svc\nkernel_type linear\nnr_class 2\ntotal_sv 2\nrho 0.3\nlabel %s\nnr_sv 1 1\nSV\n1 1:0.5 2:1\n-0.5 1:1\n"
+	label := func(model, labels string) string {
+		return strings.Replace(model, "%s", labels, 1)
+	}
+	columns := []string{"words", "link_text"}
+	tests := []struct {
+		name      string
+		model     string
+		columns   []string
+		err       error
+		intercept float32
+		weights   map[string]float32 // expected coefficients by column
+	}{
+		{"liblinear", label(liblinear, "1 -1"), columns, nil, 0.25, map[string]float32{"words": 0.5, "link_text": -2}},
+		{"liblinear content second", label(liblinear, "-1 1"), columns, nil, -0.25, map[string]float32{"words": -0.5, "link_text": 2}},
+		{"liblinear crammer singer", "solver_type MCSVM_CS\nnr_class 2\nlabel 1 -1\nnr_feature 2\nbias -1\nw\n1 0.5\n-1 1\n", columns, nil, 0,
+			map[string]float32{"words": 0.5, "link_text": -2}},
+		{"libsvm", label(libsvm, "1 -1"), columns, nil, -0.3, map[string]float32{"words": 0, "link_text": 1}},
+		{"libsvm content second", label(libsvm, "-1 1"), columns, nil, 0.3, map[string]float32{"words": 0, "link_text": -1}},
+		{"missing columns", label(liblinear, "1 -1"), columns[:1], ErrUnknownColumn, 0, nil},
+		{"unknown column", label(liblinear, "1 -1"), []string{"words", "colour"}, ErrUnknownColumn, 0, nil},
+		{"mixed columns", label(liblinear, "1 -1"), []string{"words", "boost_words"}, ErrMixedColumns, 0, nil},
+		{"truncated", "solver_type L2R_LR\nnr_class 2\nlabel 1 -1\nnr_feature 2\nbias -1\nw\n0.5\n", columns, ErrBadModel, 0, nil},
+		{"three classes", "solver_type L2R_LR\nnr_class 3\nlabel 1 2 3\n", columns, ErrBadModel, 0, nil},
+		{"same labels", label(liblinear, "1 1"), columns, ErrBadModel, 0, nil},
+		{"rbf kernel", strings.Replace(label(libsvm, "1 -1"), "linear", "rbf", 1), columns, ErrBadModel, 0, nil},
+		{"no model", "hello\n", columns, ErrBadModel, 0, nil},
+	}
+	names := chunkFeatureColumns()
+	for _, test := range tests {
+		weights, err := LoadLinearModel(strings.NewReader(test.model), test.columns)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if weights.boost != nil {
+			t.Errorf("%s: got boost model", test.name)
+		}
+		if d := weights.model.Intercept - test.intercept; d > 1e-6 || d < -1e-6 {
+			t.Errorf("%s: got intercept %v, want %v", test.name, weights.model.Intercept, test.intercept)
+		}
+		for i, name := range names {
+			if c := weights.model.Coefficients[i]; c != test.weights[name] {
+				t.Errorf("%s: got coefficient %v for %s, want %v", test.name, c, name, test.weights[name])
+			}
+		}
+	}
+}
+
+func TestLoadLinearBoostModel(t *testing.T) {
+	model := "solver_type L2R_LR\nnr_class 2\nlabel -1 1\nnr_feature 2\nbias -1\nw\n-1.5\n0\n"
+	weights, err := LoadLinearModel(strings.NewReader(model), []string{"boost_words", "boost_link_text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weights.boost == nil {
+		t.Fatal("got no boost model")
+	}
+	if len(weights.boost.Coefficients) != boostFeatureCap || weights.boost.Coefficients[1] != 1.5 || weights.boost.Coefficients[0] != 0 {
+		t.Errorf("got boost coefficients %v", weights.boost.Coefficients)
+	}
+	// The chunks are scored by the trained model still.
+	if weights.model.Coefficients[0] != logit.Coefficients[0] {
+		t.Errorf("chunk coefficients changed")
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/util"
+	"strings"
+	"unicode/utf8"
+)
+
+// LinkMode selects how the links inside of the article text are preserved.
+type LinkMode int
+
+const (
+	LinksNone     LinkMode = iota // links are reduced to their text
+	LinksMarkdown                 // links are written as [text](url)
+	LinksOffsets                  // links are listed in the Links of the article
+)
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+var markdownURLEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+// writeCluster writes the text of the chunks of cl to text and returns the
+// text as it should be printed. Links found in the chunks are reported as
+// links of the text at position index of the article, if the options ask for
+// offsets.
+func (ext *Extractor) writeCluster(text *util.Text, cl *cluster, index int) (string, []*util.Link) {
+	parts := make([]string, 0, len(cl.Chunks))
+	links := make([]*util.Link, 0)
+	length := 0
+	for _, chunk := range cl.Chunks {
+		s := chunk.Text.String()
+		text.WriteText(chunk.Text)
+		// Texts of chunks are joined by a single space.
+		start := length
+		if start > 0 {
+			start++
+		}
+		length = start + utf8.RuneCountInString(s)
+		if chunk.Link != "" {
+			switch ext.Options.Links {
+			case LinksMarkdown:
+				s = markdownLink(s, chunk.Link)
+			case LinksOffsets:
+				links = append(links, &util.Link{Index: index, Start: start, End: length, URL: chunk.Link})
+			}
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "), links
+}
+
+// markdownLink returns the link to target with text s in Markdown syntax.
+func markdownLink(s, target string) string {
+	return "[" + markdownEscaper.Replace(s) + "](" + markdownURLEscaper.Replace(target) + ")"
+}
@@ -1,18 +1,25 @@
 package model
 
 var (
-	logit = struct {
-		Intercept    float32
-		Coefficients []float32
-	}{
-		-3.48470,
-		[]float32{
+	logit = logitModel{
+		Intercept: -3.48470,
+		Coefficients: []float32{
 			-1.49895, -0.28132, -3.31730, 1.61287, 1.06209, -1.14583, -1.26443,
 			-2.13654, 1.95069, -1.10264, 2.64148, 0.95751, 0.47757, 0.50960,
-			-1.50042, 0.20151, -3.09770, -0.29993, -1.99981, 4.72123, -0.55059,
-			-5.46229, 1.01904, 0.80692, -0.28433, 1.19377, 2.75097, 0.41616,
+			-1.50042, 0.20151, -3.09770, -0.29993, -1.99981,
+			// Nav, header, footer, main, section, figure and table ancestors,
+			// not part of the trained model yet.
+			0.00000, 0.00000, 0.00000, 0.00000, 0.00000, 0.00000, 0.00000,
+			4.72123, -0.55059, -5.46229, 1.01904, 0.80692, -0.28433, 1.19377,
+			2.75097, 0.41616,
 			-1.75872, 2.37967, 0.33332, 1.51382, 1.02834, -1.18468, 0.43061,
 			0.33378,
+			// Stopword ratio, density and position features, which the model
+			// wasn't trained with. They are only computed for weights using
+			// them, see optionalFeatures.
+			0.00000,
+			0.00000, 0.00000, 0.00000, 0.00000, 0.00000,
+			0.00000, 0.00000, 0.00000,
 		},
 	}
 )
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/util"
+	"time"
+)
+
+// An Observer is notified of the extractions of the extractors whose Options
+// name it, which allows collecting metrics or logging them. Extractors of an
+// ExtractorPool share their Observer, so it must be safe for concurrent use.
+type Observer interface {
+	// Extracted is called once the extraction of doc returned article or
+	// err, which took elapsed.
+	Extracted(doc *html.Document, article *util.Article, err error, elapsed time.Duration)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(doc *html.Document, article *util.Article, err error, elapsed time.Duration)
+
+// Extracted calls fn.
+func (fn ObserverFunc) Extracted(doc *html.Document, article *util.Article, err error, elapsed time.Duration) {
+	fn(doc, article, err, elapsed)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Documents with fewer chunks are processed by a single goroutine, because
+// starting goroutines would cost more than it saves. Every goroutine gets at
+// least minWorkerChunks chunks.
+const (
+	minParallelChunks = 1024
+	minWorkerChunks   = 256
+)
+
+// chunkWorkers returns the number of goroutines computing the features and
+// scores of n chunks.
+func (ext *Extractor) chunkWorkers(n int) int {
+	if n < minParallelChunks {
+		return 1
+	}
+	workers := ext.Options.ChunkWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if max := n / minWorkerChunks; workers > max {
+		workers = max
+	}
+	return workers
+}
+
+// parallelRanges splits the indices [0,n) into contiguous ranges, one per
+// worker, and calls fn for every range in a goroutine of its own. The index
+// of the range is passed as well. It returns once all calls returned. A
+// single worker calls fn directly.
+func parallelRanges(n int, workers int, fn func(worker int, start int, end int)) {
+	if workers <= 1 || n == 0 {
+		fn(0, 0, n)
+		return
+	}
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for worker, start := 0, 0; start < n; worker, start = worker+1, start+size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(worker, start, end int) {
+			defer wg.Done()
+			fn(worker, start, end)
+		}(worker, start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/util"
+	gonet "golang.org/x/net/html"
+	"sync"
+)
+
+// ExtractorPool extracts documents concurrently. An Extractor keeps the labels
+// of the last document it processed and therefore must not be shared between
+// goroutines. The pool hands every call its own Extractor and reuses it later.
+type ExtractorPool struct {
+	pool sync.Pool
+}
+
+// NewExtractorPool creates and initializes a new ExtractorPool using the
+// DefaultOptions.
+func NewExtractorPool() *ExtractorPool {
+	return NewExtractorPoolOptions(DefaultOptions)
+}
+
+// NewExtractorPoolOptions creates and initializes a new ExtractorPool whose
+// Extractors use opts.
+func NewExtractorPoolOptions(opts Options) *ExtractorPool {
+	result := new(ExtractorPool)
+	result.pool.New = func() interface{} {
+		return NewExtractorOptions(opts)
+	}
+	return result
+}
+
+// Extract returns a list of relevant text chunks found in doc. It's safe for
+// concurrent use.
+func (p *ExtractorPool) Extract(doc *html.Document) (*util.Article, error) {
+	return p.ExtractContext(context.Background(), doc)
+}
+
+// ExtractFromNode returns the article found in the parsed HTML n like
+// Extractor.ExtractFromNode. It's safe for concurrent use, as long as the
+// trees passed are distinct.
+func (p *ExtractorPool) ExtractFromNode(n *gonet.Node, opts html.Options) (*util.Article, error) {
+	return p.ExtractFromNodeContext(context.Background(), n, opts)
+}
+
+// ExtractFromNodeContext works like ExtractFromNode, but gives up once ctx is
+// done.
+func (p *ExtractorPool) ExtractFromNodeContext(ctx context.Context, n *gonet.Node, opts html.Options) (*util.Article, error) {
+	ext := p.pool.Get().(*Extractor)
+	defer p.pool.Put(ext)
+	return ext.ExtractFromNodeContext(ctx, n, opts)
+}
+
+// ExtractContext works like Extract, but gives up once ctx is done.
+func (p *ExtractorPool) ExtractContext(ctx context.Context, doc *html.Document) (*util.Article, error) {
+	ext := p.pool.Get().(*Extractor)
+	defer p.pool.Put(ext)
+	return ext.ExtractContext(ctx, doc)
+}
+
+// ExtractFunc works like ExtractContext, but calls fn for every chunk of doc
+// like Extractor.ExtractFunc.
+func (p *ExtractorPool) ExtractFunc(ctx context.Context, doc *html.Document, fn func(ChunkScore)) (*util.Article, error) {
+	ext := p.pool.Get().(*Extractor)
+	defer p.pool.Put(ext)
+	return ext.ExtractFunc(ctx, doc, fn)
+}
+
+// Feedback corrects the label of chunk, one of the chunks of doc, like
+// Extractor.Feedback. The Extractors of the pool share the weights of its
+// options, so all of them score later documents with the updated weights.
+func (p *ExtractorPool) Feedback(doc *html.Document, chunk *html.Chunk, label bool) error {
+	ext := p.pool.Get().(*Extractor)
+	defer p.pool.Put(ext)
+	return ext.Feedback(doc, chunk, label)
+}
+
+// extractScratch holds the buffers an extraction needs only until it
+// returns. They are reused by later extractions through the scratchPool.
+type extractScratch struct {
+	boost  []boostFeature
+	scores []float32
+}
+
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return new(extractScratch)
+	},
+}
+
+// resizeChunkFeatures returns n zeroed feature vectors, reusing buf if its
+// capacity suffices.
+func resizeChunkFeatures(buf []chunkFeature, n int) []chunkFeature {
+	if cap(buf) < n {
+		return make([]chunkFeature, n)
+	}
+	buf = buf[:n]
+	for i := range buf {
+		buf[i] = chunkFeature{}
+	}
+	return buf
+}
+
+// resizeBoostFeatures works like resizeChunkFeatures for boost features.
+func resizeBoostFeatures(buf []boostFeature, n int) []boostFeature {
+	if cap(buf) < n {
+		return make([]boostFeature, n)
+	}
+	buf = buf[:n]
+	for i := range buf {
+		buf[i] = boostFeature{}
+	}
+	return buf
+}
+
+// resizeScores returns n scores, reusing buf if its capacity suffices. The
+// scores aren't zeroed, since every score is assigned.
+func resizeScores(buf []float32, n int) []float32 {
+	if cap(buf) < n {
+		return make([]float32, n)
+	}
+	return buf[:n]
+}
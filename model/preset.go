@@ -0,0 +1,109 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"regexp"
+)
+
+// A Preset adjusts the thresholds of the extraction to a kind of page. There
+// is a single model, trained on news articles. Blog posts and forum threads
+// score lower with it, because they contain shorter paragraphs and more
+// links, so their presets lower the threshold and, if the fallback is
+// enabled, fall back to the rule-based scorer sooner.
+type Preset struct {
+	Name          string
+	Threshold     float32 // minimum block score of relevant chunks
+	MinConfidence float32 // minimum confidence of the model
+	MinChunkWords int     // minimum number of words per paragraph
+}
+
+var (
+	PresetNews  = &Preset{Name: "news", Threshold: 0.5, MinConfidence: 0.2}
+	PresetBlog  = &Preset{Name: "blog", Threshold: 0.4, MinConfidence: 0.3}
+	PresetForum = &Preset{Name: "forum", Threshold: 0.3, MinConfidence: 0.4, MinChunkWords: 3}
+)
+
+// Presets maps the names of the presets to the presets.
+var Presets = map[string]*Preset{
+	PresetNews.Name:  PresetNews,
+	PresetBlog.Name:  PresetBlog,
+	PresetForum.Name: PresetForum,
+}
+
+// PresetAuto is the name selecting the preset of every document by
+// SelectPreset.
+const PresetAuto = "auto"
+
+// Types declared by the pages of the presets, see html.Document.Types.
+var presetTypes = map[string]*Preset{
+	"qapage":                 PresetForum,
+	"question":               PresetForum,
+	"discussionforumposting": PresetForum,
+	"socialmediaposting":     PresetForum,
+	"blogposting":            PresetBlog,
+	"blog":                   PresetBlog,
+	"newsarticle":            PresetNews,
+	"reportagenewsarticle":   PresetNews,
+	"analysisnewsarticle":    PresetNews,
+	"article":                PresetNews,
+}
+
+// Locations of the pages of the presets.
+var (
+	presetForumURL = regexp.MustCompile(`(?i)^[a-z]+://(forums?\.|[^/]*/(forums?|threads?|questions|topics?|t)/)`)
+	presetBlogURL  = regexp.MustCompile(`(?i)^[a-z]+://(blogs?\.|[^/]*/blogs?/)`)
+)
+
+// SelectPreset returns the preset suiting doc best. Types declared by the
+// document are trusted most: forum threads and blog posts often declare
+// themselves as articles too, so their types win over the news types. Then
+// the location of the document is considered. News is the default.
+func SelectPreset(doc *html.Document) *Preset {
+	found := make(map[*Preset]bool)
+	for _, t := range doc.Types {
+		if preset, ok := presetTypes[t]; ok {
+			found[preset] = true
+		}
+	}
+	switch {
+	case found[PresetForum]:
+		return PresetForum
+	case found[PresetBlog]:
+		return PresetBlog
+	case found[PresetNews]:
+		return PresetNews
+	case presetForumURL.MatchString(doc.URL()):
+		return PresetForum
+	case presetBlogURL.MatchString(doc.URL()):
+		return PresetBlog
+	}
+	return PresetNews
+}
+
+// presetOptions returns the options of the extractor adjusted to the
+// preset selected for doc. Options differing from the DefaultOptions were
+// chosen deliberately and are kept.
+func (ext *Extractor) presetOptions(doc *html.Document) (Options, *Preset) {
+	opts := ext.Options
+	var preset *Preset
+	switch opts.Preset {
+	case "":
+		return opts, nil
+	case PresetAuto:
+		preset = SelectPreset(doc)
+	default:
+		if preset = Presets[opts.Preset]; preset == nil {
+			return opts, nil
+		}
+	}
+	if opts.Threshold == DefaultOptions.Threshold {
+		opts.Threshold = preset.Threshold
+	}
+	if opts.MinConfidence == DefaultOptions.MinConfidence {
+		opts.MinConfidence = preset.MinConfidence
+	}
+	if opts.MinChunkWords == DefaultOptions.MinChunkWords {
+		opts.MinChunkWords = preset.MinChunkWords
+	}
+	return opts, preset
+}
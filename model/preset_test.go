@@ -0,0 +1,63 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"strings"
+	"testing"
+)
+
+func TestSelectPreset(t *testing.T) {
+	tests := []struct {
+		name, head, url string
+		want            *Preset
+	}{
+		{"none", ``, "", PresetNews},
+		{"og:type", `<meta property="og:type" content="article">`, "https://example.com/blog/post", PresetNews},
+		{"blog type", `<script type="application/ld+json">{"@type": "BlogPosting"}</script>`, "", PresetBlog},
+		{"forum type", `<meta property="og:type" content="article">
+			<script type="application/ld+json">{"@type": ["DiscussionForumPosting", "Article"]}</script>`, "", PresetForum},
+		{"blog url", ``, "https://blog.example.com/2024/05/post", PresetBlog},
+		{"blog path", ``, "https://example.com/blogs/post", PresetBlog},
+		{"forum url", ``, "https://forum.example.com/t/question/42", PresetForum},
+		{"forum path", ``, "https://example.com/questions/42/how", PresetForum},
+		{"news url", ``, "https://example.com/2024/05/story", PresetNews},
+	}
+	for _, test := range tests {
+		page := "<html><head>" + test.head + "</head><body><p>Text.</p></body></html>"
+		doc, err := html.NewDocumentOptions(strings.NewReader(page), html.Options{URL: test.url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := SelectPreset(doc); got != test.want {
+			t.Errorf("%s: got preset %s, want %s", test.name, got.Name, test.want.Name)
+		}
+	}
+}
+
+func TestPresetOptions(t *testing.T) {
+	doc, err := html.NewDocumentOptions(strings.NewReader("<html><head></head><body><p>Text.</p></body></html>"),
+		html.Options{URL: "https://forum.example.com/t/question/42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions
+	opts.Preset = PresetAuto
+	got, preset := NewExtractorOptions(opts).presetOptions(doc)
+	if preset != PresetForum || got.Threshold != PresetForum.Threshold || got.MinChunkWords != PresetForum.MinChunkWords {
+		t.Errorf("auto: got preset %v, threshold %v", preset, got.Threshold)
+	}
+
+	// Options chosen deliberately are kept.
+	opts.Preset, opts.Threshold = "blog", 0.7
+	got, preset = NewExtractorOptions(opts).presetOptions(doc)
+	if preset != PresetBlog || got.Threshold != 0.7 || got.MinConfidence != PresetBlog.MinConfidence {
+		t.Errorf("blog: got preset %v, threshold %v, min confidence %v", preset, got.Threshold, got.MinConfidence)
+	}
+
+	for _, name := range []string{"", "recipe"} {
+		opts.Preset = name
+		if got, preset = NewExtractorOptions(opts).presetOptions(doc); preset != nil || got.Threshold != 0.7 {
+			t.Errorf("%q: got preset %v, threshold %v", name, preset, got.Threshold)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/slyrz/newscat/html"
+	"sort"
+)
+
+// rankTitles returns the title candidates of doc, best first. Candidates
+// similar to the extracted headings gain score, because the heading of the
+// article usually is among the extracted chunks, whereas site names and
+// headings of teasers aren't.
+func (ext *Extractor) rankTitles(doc *html.Document) []string {
+	scores := make([]float32, len(doc.Titles))
+	for i, candidate := range doc.Titles {
+		best := float32(0)
+		for j, chunk := range doc.Chunks {
+			if ext.Labels[j] && chunk.IsHeading() {
+				if sim := candidate.Text.Similarity(chunk.Text); sim > best {
+					best = sim
+				}
+			}
+		}
+		scores[i] = candidate.Score + best
+	}
+	order := make([]int, len(doc.Titles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	result := make([]string, len(order))
+	for i, j := range order {
+		result[i] = doc.Titles[j].Text.String()
+	}
+	return result
+}
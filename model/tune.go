@@ -0,0 +1,252 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/slyrz/newscat/html"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+var ErrUnknownGroup = errors.New("unknown feature group")
+
+// A Corpus holds documents labeled by hand and the feature vectors and labels
+// of their chunks. It's used to train and evaluate the weights of the
+// logistic regression.
+type Corpus struct {
+	docs     []*html.Document
+	features [][]chunkFeature
+	boost    [][]boostFeature
+	labels   [][]bool
+}
+
+// Add adds the chunks of doc to the corpus. Chunks are labeled as content if
+// their base node or one of its ancestors carries the attribute attr. Add
+// returns the number of content chunks.
+func (c *Corpus) Add(doc *html.Document, attr string) int {
+	if len(doc.Chunks) == 0 {
+		return 0
+	}
+	labels := make([]bool, len(doc.Chunks))
+	count := 0
+	for i, chunk := range doc.Chunks {
+		for n := chunk.Base; n != nil && !labels[i]; n = n.Parent {
+			for _, a := range n.Attr {
+				if a.Key == attr {
+					labels[i] = true
+					count++
+					break
+				}
+			}
+		}
+	}
+	// The boost features depend on the chunk scores of the trained model.
+	features := newChunkFeatures(doc, nil, 1, allFeatures)
+	weights := defaultWeights.snapshot()
+	clusters := newClusterMap()
+	for i, chunk := range doc.Chunks {
+		clusters.Add(chunk.Container, chunk, features[i].Score(weights))
+	}
+	c.docs = append(c.docs, doc)
+	c.features = append(c.features, features)
+	c.boost = append(c.boost, newBoostFeatures(doc, clusters, nil, 1))
+	c.labels = append(c.labels, labels)
+	return count
+}
+
+// Export writes the chunks of the corpus in the sparse data format of
+// liblinear and libsvm to w, one chunk per line. Content chunks are labeled
+// +1, other chunks -1. The features are numbered from 1 in the order of
+// FeatureColumns(boost).
+func (c *Corpus) Export(w io.Writer, boost bool) error {
+	bw := bufio.NewWriter(w)
+	for doc := range c.labels {
+		for i, label := range c.labels[doc] {
+			var ftr []float32
+			if boost {
+				ftr = c.boost[doc][i][:]
+			} else {
+				ftr = c.features[doc][i][:]
+			}
+			if label {
+				bw.WriteString("+1")
+			} else {
+				bw.WriteString("-1")
+			}
+			for j, v := range ftr {
+				if v != 0.0 {
+					bw.WriteByte(' ')
+					bw.WriteString(strconv.Itoa(j + 1))
+					bw.WriteByte(':')
+					bw.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+				}
+			}
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// Len returns the number of documents in the corpus.
+func (c *Corpus) Len() int {
+	return len(c.features)
+}
+
+// TrainOptions control the training of the logistic regression.
+type TrainOptions struct {
+	Rate     float32  // learning rate
+	Epochs   int      // number of passes over the training data
+	Disabled []string // names of the feature groups left out
+	Seed     int64    // seed of the order the chunks are visited in
+}
+
+// Evaluation holds the chunk-level quality of the predicted labels.
+type Evaluation struct {
+	Precision float32
+	Recall    float32
+	FScore    float32
+}
+
+// mask returns the components of the feature vectors enabled by opts.
+func (opts *TrainOptions) mask() (*chunkFeature, error) {
+	disabled := make(map[string]bool)
+	for _, name := range opts.Disabled {
+		disabled[name] = true
+	}
+	result := new(chunkFeature)
+	i := 0
+	for _, group := range chunkFeatureGroups {
+		for range group.columns {
+			if !disabled[group.name] {
+				result[i] = 1.0
+			}
+			i++
+		}
+		delete(disabled, group.name)
+	}
+	if len(disabled) > 0 {
+		return nil, ErrUnknownGroup
+	}
+	return result, nil
+}
+
+// Train returns the weights trained on the whole corpus.
+func (c *Corpus) Train(opts TrainOptions) (*Weights, error) {
+	all := make([]int, c.Len())
+	for i := range all {
+		all[i] = i
+	}
+	model, err := c.train(all, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Weights{model: *model}, nil
+}
+
+// CrossValidate trains and evaluates the logistic regression k times. The
+// documents are split into k folds; every fold is evaluated once by weights
+// trained on the remaining folds. The documents are extracted with the
+// DefaultOptions and the trained weights, so the evaluation covers the
+// clustering and the random forest as well. The returned evaluation covers
+// the chunks of all folds.
+func (c *Corpus) CrossValidate(opts TrainOptions, k int) (Evaluation, error) {
+	if k < 2 || k > c.Len() {
+		k = c.Len()
+	}
+	order := rand.New(rand.NewSource(opts.Seed)).Perm(c.Len())
+	tp, fp, fn := 0, 0, 0
+	for fold := 0; fold < k; fold++ {
+		train, test := make([]int, 0), make([]int, 0)
+		for i, doc := range order {
+			if i%k == fold {
+				test = append(test, doc)
+			} else {
+				train = append(train, doc)
+			}
+		}
+		model, err := c.train(train, opts)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		extractOpts := DefaultOptions
+		extractOpts.Weights = &Weights{model: *model}
+		ext := NewExtractorOptions(extractOpts)
+		for _, doc := range test {
+			labels := c.labels[doc]
+			// Documents without article are evaluated as well, since the
+			// chunks are reported regardless.
+			ext.ExtractFunc(context.Background(), c.docs[doc], func(score ChunkScore) {
+				switch actual := labels[score.Index]; {
+				case score.Label && actual:
+					tp++
+				case score.Label:
+					fp++
+				case actual:
+					fn++
+				}
+			})
+		}
+	}
+	result := Evaluation{}
+	if tp+fp > 0 {
+		result.Precision = float32(tp) / float32(tp+fp)
+	}
+	if tp+fn > 0 {
+		result.Recall = float32(tp) / float32(tp+fn)
+	}
+	if result.Precision+result.Recall > 0 {
+		result.FScore = 2 * result.Precision * result.Recall / (result.Precision + result.Recall)
+	}
+	return result, nil
+}
+
+// train fits a logistic regression to the chunks of the documents docs by
+// stochastic gradient descent. Content is rare compared to clutter, so the
+// samples of either label carry half of the total weight.
+func (c *Corpus) train(docs []int, opts TrainOptions) (*logitModel, error) {
+	mask, err := opts.mask()
+	if err != nil {
+		return nil, err
+	}
+	type sample struct{ doc, chunk int }
+	samples := make([]sample, 0)
+	positive := 0
+	for _, doc := range docs {
+		for i, label := range c.labels[doc] {
+			samples = append(samples, sample{doc, i})
+			if label {
+				positive++
+			}
+		}
+	}
+	weight := [2]float32{1.0, 1.0}
+	if positive > 0 && positive < len(samples) {
+		weight[0] = float32(len(samples)) / float32(2*(len(samples)-positive))
+		weight[1] = float32(len(samples)) / float32(2*positive)
+	}
+
+	model := &logitModel{Coefficients: make([]float32, chunkFeatureCap)}
+	random := rand.New(rand.NewSource(opts.Seed))
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		random.Shuffle(len(samples), func(i, j int) {
+			samples[i], samples[j] = samples[j], samples[i]
+		})
+		for _, s := range samples {
+			ftr := &c.features[s.doc][s.chunk]
+			target, w := float32(0.0), weight[0]
+			if c.labels[s.doc][s.chunk] {
+				target, w = 1.0, weight[1]
+			}
+			p := float32(1.0 / (1.0 + math.Exp(-float64(ftr.Score(model)))))
+			step := opts.Rate * w * (target - p)
+			model.Intercept += step
+			for i := range ftr {
+				model.Coefficients[i] += step * ftr[i] * mask[i]
+			}
+		}
+	}
+	return model, nil
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"strings"
+	"testing"
+)
+
+// labeledPage returns the page i of a synthetic corpus. The article
+// paragraphs carry the data-content attribute; navigation, teasers and the
+// footer don't.
+func labeledPage(i int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>Story</title></head><body>`)
+	b.WriteString(`<div class="menu"><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li><li><a href="/sports">Sports</a></li></ul></div>`)
+	fmt.Fprintf(&b, `<div class="story" data-content><h1>Story number %d</h1>`, i)
+	for j := 0; j < 3+i%3; j++ {
+		fmt.Fprintf(&b, `<p>The council met on day %d to discuss the budget. Members argued about the road
+			repairs for hours, but no decision was reached. The vote was postponed until next week.</p>`, j)
+	}
+	b.WriteString(`</div><div class="teasers">`)
+	for j := 0; j < 3; j++ {
+		fmt.Fprintf(&b, `<p><a href="/story/%d">Another story you might like</a></p>`, j)
+	}
+	b.WriteString(`</div><div class="bottom"><p>Copyright 2024 <a href="/about">About us</a></p></div></body></html>`)
+	return b.String()
+}
+
+func TestCrossValidate(t *testing.T) {
+	corpus := new(Corpus)
+	for i := 0; i < 6; i++ {
+		doc, err := html.NewDocument(strings.NewReader(labeledPage(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if corpus.Add(doc, "data-content") == 0 {
+			t.Fatalf("page %d: no chunks labeled", i)
+		}
+	}
+	opts := TrainOptions{Rate: 0.1, Epochs: 10}
+	eval, err := corpus.CrossValidate(opts, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Precision < 0.9 || eval.Recall < 0.9 {
+		t.Errorf("got evaluation %+v", eval)
+	}
+
+	if _, err := corpus.CrossValidate(TrainOptions{Rate: 0.1, Epochs: 10, Disabled: []string{"nothing"}}, 3); err != ErrUnknownGroup {
+		t.Errorf("unknown group: got error %v", err)
+	}
+
+	// The trained weights extract the articles of other pages.
+	weights, err := corpus.Train(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := html.NewDocument(strings.NewReader(labeledPage(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractOpts := DefaultOptions
+	extractOpts.Weights = weights
+	article, err := NewExtractorOptions(extractOpts).Extract(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := article.Content(); !strings.Contains(content, "Story number 7") || strings.Contains(content, "Another story") {
+		t.Errorf("got article %q", content)
+	}
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/slyrz/newscat/html"
+	"io"
+	"math"
+	"sync"
+)
+
+var (
+	ErrBadWeights   = errors.New("number of coefficients doesn't match the features")
+	ErrUnknownChunk = errors.New("chunk not part of the document")
+	ErrNoWeights    = errors.New("feedback needs the weights of the options")
+)
+
+// Learning rate of the updates made by Feedback.
+const feedbackRate = 0.1
+
+// logitModel holds the parameters of the logistic regression.
+type logitModel struct {
+	Intercept    float32   `json:"intercept"`
+	Coefficients []float32 `json:"coefficients"`
+
+	// Coefficients quantized to int8 by quantize, if any. Coefficient i is
+	// approximately scale * quantized[i].
+	scale     float32
+	quantized []int8
+}
+
+// quantize rounds the coefficients to 255 levels spread symmetrically
+// around zero. The model scores feature vectors with the quantized
+// coefficients afterwards.
+func (m *logitModel) quantize() {
+	max := float32(0.0)
+	for _, c := range m.Coefficients {
+		if c < 0 {
+			c = -c
+		}
+		if c > max {
+			max = c
+		}
+	}
+	m.scale = max / math.MaxInt8
+	m.quantized = make([]int8, len(m.Coefficients))
+	if m.scale == 0 {
+		return
+	}
+	for i, c := range m.Coefficients {
+		m.quantized[i] = int8(math.Round(float64(c / m.scale)))
+	}
+}
+
+// clone returns a copy of the model.
+func (m *logitModel) clone() *logitModel {
+	result := &logitModel{Intercept: m.Intercept, scale: m.scale}
+	result.Coefficients = append([]float32(nil), m.Coefficients...)
+	if m.quantized != nil {
+		result.quantized = append([]int8(nil), m.quantized...)
+	}
+	return result
+}
+
+// weightsFile is the JSON object weights are saved as.
+type weightsFile struct {
+	logitModel
+	Boost     *logitModel `json:"boost,omitempty"`
+	Quantized bool        `json:"quantized,omitempty"`
+}
+
+// Weights are the parameters of the logistic regression scoring the chunks.
+// They can be updated while extractors use them, so applications can correct
+// the model by giving feedback, and they can be saved and loaded again.
+type Weights struct {
+	mu    sync.RWMutex
+	model logitModel
+	boost *logitModel // linear model replacing the random forest, if any
+}
+
+// defaultWeights are the weights of the trained model. Extractors use them
+// unless their options name other weights. They are never updated.
+var defaultWeights = NewWeights()
+
+// NewWeights returns a copy of the weights of the trained model.
+func NewWeights() *Weights {
+	coefficients := make([]float32, len(logit.Coefficients))
+	copy(coefficients, logit.Coefficients)
+	return &Weights{model: logitModel{Intercept: logit.Intercept, Coefficients: coefficients}}
+}
+
+// Quantize returns a copy of w whose coefficients are quantized to int8,
+// which makes them a quarter of their size. The scores of the chunks differ
+// from the scores of w by at most half a quantization step times the sum of
+// the features. Feedback on the copy updates its original coefficients and
+// quantizes them again. Without vector instructions, converting the
+// coefficients makes scoring slightly slower than with the float32 weights.
+func (w *Weights) Quantize() *Weights {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	result := &Weights{model: *w.model.clone()}
+	result.model.quantize()
+	if w.boost != nil {
+		result.boost = w.boost.clone()
+		result.boost.quantize()
+	}
+	return result
+}
+
+// Quantized returns true if w scores chunks with int8 coefficients.
+func (w *Weights) Quantized() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.model.quantized != nil
+}
+
+// LoadWeights reads weights saved by Save. It returns ErrBadWeights if the
+// number of coefficients doesn't match the number of features.
+func LoadWeights(r io.Reader) (*Weights, error) {
+	file := new(weightsFile)
+	if err := json.NewDecoder(r).Decode(file); err != nil {
+		return nil, err
+	}
+	if len(file.Coefficients) != chunkFeatureCap {
+		return nil, ErrBadWeights
+	}
+	if file.Boost != nil && len(file.Boost.Coefficients) != boostFeatureCap {
+		return nil, ErrBadWeights
+	}
+	result := &Weights{model: file.logitModel, boost: file.Boost}
+	if file.Quantized {
+		return result.Quantize(), nil
+	}
+	return result, nil
+}
+
+// Save writes the weights as JSON object to w.
+func (w *Weights) Save(wr io.Writer) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return json.NewEncoder(wr).Encode(&weightsFile{w.model, w.boost, w.model.quantized != nil})
+}
+
+// snapshot returns a copy of the current weights, so a document is scored
+// by the same weights throughout, even if they are updated meanwhile.
+func (w *Weights) snapshot() *logitModel {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.model.clone()
+}
+
+// update performs a stochastic gradient descent step of the logistic
+// regression towards label for the feature vector ftr.
+func (w *Weights) update(ftr *chunkFeature, label bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	target := float32(0.0)
+	if label {
+		target = 1.0
+	}
+	score := w.model.score(ftr[:])
+	p := float32(1.0 / (1.0 + math.Exp(-float64(score))))
+	step := feedbackRate * (target - p)
+	w.model.Intercept += step
+	for i := range ftr {
+		w.model.Coefficients[i] += step * ftr[i]
+	}
+	if w.model.quantized != nil {
+		w.model.quantize()
+	}
+}
+
+// weights returns the weights the extractor scores chunks with.
+func (ext *Extractor) weights() *Weights {
+	if ext.Options.Weights != nil {
+		return ext.Options.Weights
+	}
+	return defaultWeights
+}
+
+// Feedback corrects the label of chunk, one of the chunks of doc, by updating
+// the weights of the options towards label. The weights are shared with all
+// extractors using them, which score later documents with the updated
+// weights. If boosting is enabled, the random forest still decides, so
+// updates only take effect through the chunk scores it considers. Feedback
+// returns ErrNoWeights if the options name no weights, since the weights of
+// the trained model are never updated, and ErrUnknownChunk if chunk isn't
+// part of doc.
+func (ext *Extractor) Feedback(doc *html.Document, chunk *html.Chunk, label bool) error {
+	if ext.Options.Weights == nil {
+		return ErrNoWeights
+	}
+	for i, c := range doc.Chunks {
+		if c == chunk {
+			// The features of the document aren't kept after the extraction,
+			// so they are computed again.
+			weights := ext.Options.Weights
+			features := newChunkFeatures(doc, nil, 1, weights.snapshot().features())
+			weights.update(&features[i], label)
+			return nil
+		}
+	}
+	return ErrUnknownChunk
+}
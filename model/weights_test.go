@@ -0,0 +1,141 @@
+package model
+
+import (
+	"context"
+	"github.com/slyrz/newscat/html"
+	"strings"
+	"testing"
+)
+
+// trainedCoefficients are the coefficients of the trained model by feature.
+// Features added later must not shift them to other features.
+var trainedCoefficients = map[string]float32{
+	"element_p": -1.49895, "element_a": -0.28132, "element_div": -3.31730, "element_heading": 1.61287,
+	"parent_p": 1.06209, "parent_span": -1.14583, "parent_div": -1.26443, "parent_li": -2.13654,
+	"siblings": 1.95069, "siblings_a": -1.10264, "siblings_p": 2.64148, "siblings_img": 0.95751,
+	"siblings_a_ratio": 0.47757, "siblings_p_ratio": 0.50960, "siblings_img_ratio": -1.50042,
+	"ancestor_article": 0.20151, "ancestor_aside": -3.09770, "ancestor_blockquote": -0.29993, "ancestor_list": -1.99981,
+	"words": 4.72123, "sentences": -0.55059, "link_text": -5.46229,
+	"prev_same_block": 1.01904, "prev_words": 0.80692, "prev_sentences": -0.28433,
+	"next_same_block": 1.19377, "next_words": 2.75097, "next_sentences": 0.41616,
+	"class_found": -1.75872, "class_words": 2.37967, "class_sentences": 0.33332,
+	"cluster_words": 1.51382, "cluster_sentences": 1.02834, "cluster_count": -1.18468,
+	"cluster_avg_words": 0.43061, "cluster_avg_sentences": 0.33378,
+}
+
+func TestCoefficientColumns(t *testing.T) {
+	columns := FeatureColumns(false)
+	if len(columns) != chunkFeatureCap || len(logit.Coefficients) != chunkFeatureCap {
+		t.Fatalf("got %d columns and %d coefficients, want %d", len(columns), len(logit.Coefficients), chunkFeatureCap)
+	}
+	for i, name := range columns {
+		want, trained := trainedCoefficients[name]
+		if got := logit.Coefficients[i]; got != want {
+			t.Errorf("coefficient %d of %s is %v, want %v (trained %v)", i, name, got, want, trained)
+		}
+	}
+	for name := range trainedCoefficients {
+		found := false
+		for _, column := range columns {
+			found = found || column == name
+		}
+		if !found {
+			t.Errorf("trained feature %s has no column", name)
+		}
+	}
+}
+
+func TestOptionalFeatures(t *testing.T) {
+	sizes := map[string]int{"stopwords": stopwordFeatures, "density": densityFeatures, "position": positionFeatures}
+	for _, group := range chunkFeatureGroups {
+		if size, ok := sizes[group.name]; ok && size != len(group.columns) {
+			t.Errorf("group %s has %d columns, want %d", group.name, len(group.columns), size)
+		}
+	}
+	if got := NewWeights().snapshot().features(); got != (optionalFeatures{}) {
+		t.Errorf("default weights use optional features %+v", got)
+	}
+
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := newChunkFeatures(doc, nil, 1, allFeatures)
+	none := newChunkFeatures(doc, nil, 1, optionalFeatures{})
+	first := chunkFeatureCap - stopwordFeatures - densityFeatures - positionFeatures
+	for i := range all {
+		for j := range all[i] {
+			want := all[i][j]
+			if j >= first {
+				want = 0
+			}
+			if none[i][j] != want {
+				t.Fatalf("component %d of chunk %d is %v, want %v", j, i, none[i][j], want)
+			}
+		}
+	}
+
+	// Weights trained with a group compute it.
+	model := NewWeights().snapshot()
+	model.Coefficients[first+stopwordFeatures] = 1
+	if got := model.features(); got != (optionalFeatures{density: true}) {
+		t.Errorf("weights with a density coefficient use %+v", got)
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	doc, err := html.NewDocument(strings.NewReader(benchmarkPage(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var home *html.Chunk
+	for _, chunk := range doc.Chunks {
+		if chunk.Text.String() == "Home" {
+			home = chunk
+		}
+	}
+	if home == nil {
+		t.Fatal("navigation not found")
+	}
+	// score returns the score of the navigation link, which isn't part of
+	// the article, extracted by pool.
+	score := func(pool *ExtractorPool) float32 {
+		result := float32(0.0)
+		pool.ExtractFunc(context.Background(), doc, func(score ChunkScore) {
+			if score.Chunk == home {
+				result = score.Score
+			}
+		})
+		return result
+	}
+
+	opts := DefaultOptions
+	opts.Boost = false
+	trained := NewExtractorPoolOptions(opts)
+	if err := trained.Feedback(doc, home, true); err != ErrNoWeights {
+		t.Errorf("trained weights: got error %v, want ErrNoWeights", err)
+	}
+	before := score(trained)
+
+	opts.Weights = NewWeights()
+	pool := NewExtractorPoolOptions(opts)
+	for i := 0; i < 10; i++ {
+		if err := pool.Feedback(doc, home, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := score(pool); after <= before {
+		t.Errorf("feedback moved the score from %v to %v", before, after)
+	}
+	if unchanged := score(trained); unchanged != before {
+		t.Errorf("feedback changed the trained weights: score %v, then %v", before, unchanged)
+	}
+
+	other, err := html.NewDocument(strings.NewReader(benchmarkPage(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Feedback(other, home, true); err != ErrUnknownChunk {
+		t.Errorf("foreign chunk: got error %v, want ErrUnknownChunk", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+)
+
+// Terminal escape sequences of the preview.
+const (
+	previewBold     = "\x1b[1m"
+	previewRejected = "\x1b[2;31m" // dim red
+	previewReset    = "\x1b[0m"
+)
+
+// previewChunk prints a chunk of the preview. Extracted chunks are printed
+// as they are, rejected chunks are preceded by their own score and the score
+// of their block, which decides the label, and dimmed on terminals.
+func previewChunk(score model.ChunkScore) {
+	text := score.Chunk.Text.String()
+	if score.Label {
+		fmt.Printf("%s\n\n", text)
+		return
+	}
+	pre, pos := "", ""
+	if highlight {
+		pre, pos = previewRejected, previewReset
+	}
+	fmt.Printf("%s[%.2f block %.2f] %s%s\n\n", pre, score.Score, score.BlockScore, text, pos)
+}
+
+// previewInput prints the preview of the file or URL arg.
+func previewInput(ext *model.Extractor, arg string) error {
+	ctx, cancel := inputContext()
+	defer cancel()
+	input, err := fetcher.OpenInput(ctx, arg)
+	if err != nil {
+		return newInputError(arg, stageFetch, err)
+	}
+	defer input.Data.Close()
+	doc, err := html.NewDocumentContext(ctx, input.Data, documentOptions(input.ContentType, arg))
+	if err != nil {
+		return newInputError(arg, stageParse, err)
+	}
+	if arg != "" {
+		pre, pos := "", ""
+		if highlight {
+			pre, pos = previewBold, previewReset
+		}
+		fmt.Printf("%s%s%s\n\n", pre, arg, pos)
+	}
+	if _, err := ext.ExtractFunc(ctx, doc, previewChunk); err != nil {
+		return newInputError(arg, stageExtract, err)
+	}
+	return nil
+}
+
+// previewInputs prints every text chunk of the inputs args instead of the
+// articles, so it's easy to see which chunks were extracted and why the
+// others weren't. Inputs that failed earlier, like feeds, are counted by
+// failed. It returns the exit code of the run.
+func previewInputs(args []string, opts model.Options, failed int) int {
+	ext := model.NewExtractorOptions(opts)
+	total := len(args) + failed
+	for _, arg := range args {
+		if err := previewInput(ext, arg); err != nil {
+			reportError(err)
+			failed++
+		}
+	}
+	return exitCode(total, failed)
+}
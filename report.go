@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/slyrz/newscat/util"
+	"os"
+)
+
+// Stages of the processing of an input, reported with its errors. Inputs
+// failing to fetch are worth retrying, inputs failing later aren't.
+const (
+	stageFetch   = "fetch"
+	stageParse   = "parse"
+	stageExtract = "extract"
+)
+
+// Exit codes of batch runs.
+const (
+	exitOK      = 0 // all inputs were extracted
+	exitFailure = 1 // no input was extracted
+	exitPartial = 2 // some inputs failed
+)
+
+// inputError is the error of a single input. In JSON output, it's printed
+// as object in place of the article.
+type inputError struct {
+	URL     string `json:"url"`
+	Stage   string `json:"stage"`
+	Message string `json:"error"`
+	Status  int    `json:"status,omitempty"` // HTTP status code, if any
+}
+
+func newInputError(location, stage string, err error) *inputError {
+	result := &inputError{URL: location, Stage: stage, Message: err.Error()}
+	var status *util.StatusError
+	if errors.As(err, &status) {
+		result.Status = status.Code
+	}
+	return result
+}
+
+func (e *inputError) Error() string {
+	location := e.URL
+	if location == "" {
+		location = "-"
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Stage, e.Message)
+}
+
+// reportError prints err as JSON object if -json is set and to standard
+// error otherwise.
+func reportError(err error) {
+	var record *inputError
+	if !errors.As(err, &record) {
+		record = &inputError{Message: err.Error()}
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.Encode(record)
+	} else {
+		fmt.Fprintln(os.Stderr, record)
+	}
+}
+
+// exitCode returns the exit code of a run over total inputs of which failed
+// inputs failed.
+func exitCode(total, failed int) int {
+	switch {
+	case failed == 0:
+		return exitOK
+	case failed == total:
+		return exitFailure
+	}
+	return exitPartial
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what fn prints to standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		os.Stdout = saved
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func TestReportErrors(t *testing.T) {
+	savedJSON, savedCounts := *jsonOut, counts
+	*jsonOut, counts = true, newMetrics()
+	defer func() {
+		*jsonOut, counts = savedJSON, savedCounts
+	}()
+
+	tasks := make(chan task, 3)
+	tasks <- func(pool *model.ExtractorPool) (*util.Article, error) {
+		article := &util.Article{URL: "https://example.com/story", Title: "Story"}
+		article.Append(util.Paragraph("Text."))
+		return article, nil
+	}
+	tasks <- failedTask(newInputError("https://example.com/missing", stageFetch, &util.StatusError{Code: 404, Status: "404 Not Found"}))
+	tasks <- failedTask(newInputError("page.html", stageParse, html.ErrNoBody))
+	close(tasks)
+
+	code := 0
+	output := captureStdout(t, func() {
+		code = extract(tasks, 2, model.DefaultOptions)
+	})
+	if code != exitPartial {
+		t.Errorf("got exit code %d, want %d", code, exitPartial)
+	}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got output %q", output)
+	}
+	var article util.Article
+	if err := json.Unmarshal([]byte(lines[0]), &article); err != nil || article.URL != "https://example.com/story" {
+		t.Errorf("got article %s", lines[0])
+	}
+	want := []string{
+		`{"url":"https://example.com/missing","stage":"fetch","error":"unexpected HTTP status 404 Not Found","status":404}`,
+		`{"url":"page.html","stage":"parse","error":"missing body element"}`,
+	}
+	for i, line := range lines[1:] {
+		if line != want[i] {
+			t.Errorf("got error %s, want %s", line, want[i])
+		}
+	}
+	if counts.errors[stageFetch] != 1 || counts.errors[stageParse] != 1 {
+		t.Errorf("got error counts %v", counts.errors)
+	}
+}
+
+func TestInputError(t *testing.T) {
+	var buf bytes.Buffer
+	for _, err := range []error{
+		newInputError("page.html", stageParse, html.ErrNoBody),
+		newInputError("", stageExtract, model.ErrEmptyResult),
+	} {
+		buf.WriteString(err.Error() + "\n")
+	}
+	if want := "page.html: parse: missing body element\n-: extract: nothing found\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct{ total, failed, want int }{
+		{0, 0, exitOK},
+		{3, 0, exitOK},
+		{3, 1, exitPartial},
+		{3, 3, exitFailure},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.total, test.failed); got != test.want {
+			t.Errorf("%d of %d failed: got %d, want %d", test.failed, test.total, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Errors returned by the server's HTTP handler.
+var (
+	errBusy    = errors.New("too many concurrent requests")
+	errNoInput = errors.New("expected POSTed HTML or url parameter")
+)
+
+// Default size limit of POSTed and fetched pages in bytes. The server never
+// reads unbounded pages, so -max-bytes 0 selects it as well.
+const serveMaxBytes = 16 << 20
+
+// server exposes the extractor through an HTTP interface. Clients either POST
+// the HTML page as request body or pass the page's location in the url query
+// parameter. The extracted article is returned as JSON object, including the
+// user comments if the comments query parameter is true. The include and
+// exclude query parameters take CSS selectors and may be repeated. Pages
+// fetched from the url parameter are parsed using the rules of their host.
+// Metrics of the extractions are served at /metrics.
+type server struct {
+	limit  chan struct{} // semaphore limiting concurrent extractions
+	limits html.Options  // document size limits of POSTed and fetched pages
+	fetch  *util.Fetcher // fetcher of url parameters
+	pool   *model.ExtractorPool
+	rules  html.Rules // site-specific rules keyed by hostname
+	counts *metrics   // metrics of the requests, also observing the pool
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Wait for a free slot, but give up once the request deadline imposed by
+	// the timeout handler has passed.
+	select {
+	case s.limit <- struct{}{}:
+		defer func() { <-s.limit }()
+	case <-r.Context().Done():
+		writeError(w, http.StatusServiceUnavailable, errBusy)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := s.limits
+	opts.Comments, _ = strconv.ParseBool(query.Get("comments"))
+	for _, param := range []struct {
+		key       string
+		selectors *[]*html.Selector
+	}{
+		{"include", &opts.Include},
+		{"exclude", &opts.Exclude},
+	} {
+		for _, value := range query[param.key] {
+			sel, err := html.ParseSelector(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			*param.selectors = append(*param.selectors, sel)
+		}
+	}
+
+	var data io.ReadCloser
+	if target := query.Get("url"); target != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		resp, err := s.fetch.Get(r.Context(), target)
+		if err != nil {
+			s.counts.failed(stageFetch)
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		data, opts.ContentType = resp.Body, resp.Header.Get("Content-Type")
+		opts.URL = resp.Request.URL.String()
+		if rule := s.rules.Lookup(u.Host); rule != nil {
+			rule.Apply(&opts)
+		}
+	} else if r.Method == "POST" {
+		data, opts.ContentType = r.Body, r.Header.Get("Content-Type")
+	} else {
+		writeError(w, http.StatusBadRequest, errNoInput)
+		return
+	}
+	defer data.Close()
+
+	document, err := html.NewDocumentContext(r.Context(), data, opts)
+	if err != nil {
+		s.counts.failed(stageParse)
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	article, err := s.pool.ExtractContext(r.Context(), document)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	article.Comments = document.Comments
+	writeJSON(w, http.StatusOK, article)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// serve runs newscat as HTTP server until the listener fails.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to listen on")
+	limit := flags.Int("max-concurrent", 16, "maximum number of concurrent extractions")
+	limits := limitFlags(flags, serveMaxBytes)
+	timeout := flags.Duration("timeout", 30*time.Second, "maximum duration of a request")
+	rulesArg := flags.String("rules", "", "JSON file with site-specific extraction rules")
+	options := optionFlags(flags)
+	logs := logFlags(flags)
+	flags.Parse(args)
+
+	logger := logs()
+	counts := newMetrics()
+	opts := options()
+	opts.Observer, opts.Logger = counts, logger
+	documentOpts := limits()
+	documentOpts.Logger = logger
+	documentOpts.Paths = opts.Paths
+	if documentOpts.MaxBytes <= 0 {
+		documentOpts.MaxBytes = serveMaxBytes
+	}
+	handler := &server{
+		limit:  make(chan struct{}, *limit),
+		limits: documentOpts,
+		fetch:  &util.Fetcher{Client: &http.Client{Timeout: *timeout}, MaxBytes: documentOpts.MaxBytes, Logger: logger},
+		pool:   model.NewExtractorPoolOptions(opts),
+		rules:  loadRules(*rulesArg),
+		counts: counts,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", counts)
+	mux.Handle("/", http.TimeoutHandler(handler, *timeout, `{"error":"timeout"}`))
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: *timeout,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout + time.Second,
+	}
+	log.Fatal(srv.ListenAndServe())
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"github.com/slyrz/newscat/util"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testPage returns a news page with navigation, an article of n paragraphs
+// and a footer.
+func testPage(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>A test story</title></head><body>`)
+	b.WriteString(`<div class="nav"><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li></ul></div>`)
+	b.WriteString(`<div class="article"><h1>A test story</h1>`)
+	for i := 0; i < n; i++ {
+		b.WriteString(`<p class="text">The council met on Tuesday to discuss the budget. Members argued about
+			the road repairs for hours, but no decision was reached. The vote was postponed until
+			next week, when the mayor returns.</p>`)
+	}
+	b.WriteString(`</div><div class="footer"><p>Copyright 2024 <a href="/about">About us</a></p></div></body></html>`)
+	return b.String()
+}
+
+// newTestServer returns a server allowing limit concurrent extractions.
+func newTestServer(limit int) *server {
+	return &server{
+		limit:  make(chan struct{}, limit),
+		limits: html.Options{MaxBytes: serveMaxBytes},
+		fetch:  new(util.Fetcher),
+		pool:   model.NewExtractorPool(),
+		counts: newMetrics(),
+	}
+}
+
+// serveRequest sends r to s and returns the status code and the decoded
+// JSON object of the response.
+func serveRequest(t *testing.T, s *server, r *http.Request) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("%s %s: content type %q", r.Method, r.URL, ct)
+	}
+	result := make(map[string]interface{})
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("%s %s: %v", r.Method, r.URL, err)
+	}
+	return w.Code, result
+}
+
+func TestServePost(t *testing.T) {
+	s := newTestServer(1)
+	r := httptest.NewRequest("POST", "/", strings.NewReader(testPage(5)))
+	r.Header.Set("Content-Type", "text/html; charset=utf-8")
+	code, article := serveRequest(t, s, r)
+	if code != http.StatusOK || article["title"] != "A test story" {
+		t.Fatalf("got %d %v", code, article)
+	}
+	text, _ := json.Marshal(article["text"])
+	if !strings.Contains(string(text), "The council met on Tuesday") || strings.Contains(string(text), "Copyright") {
+		t.Errorf("unexpected text %s", text)
+	}
+}
+
+func TestServeURL(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/story" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage(5)))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(1)
+	code, article := serveRequest(t, s, httptest.NewRequest("GET", "/?url="+url.QueryEscape(upstream.URL+"/story"), nil))
+	if code != http.StatusOK || article["title"] != "A test story" {
+		t.Fatalf("got %d %v", code, article)
+	}
+
+	code, result := serveRequest(t, s, httptest.NewRequest("GET", "/?url="+url.QueryEscape(upstream.URL+"/missing"), nil))
+	if code != http.StatusBadGateway || result["error"] == nil {
+		t.Errorf("missing page: got %d %v", code, result)
+	}
+	if s.counts.errors[stageFetch] != 1 {
+		t.Errorf("got %d fetch errors, want 1", s.counts.errors[stageFetch])
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *http.Request
+		code int
+	}{
+		{"no input", httptest.NewRequest("GET", "/", nil), http.StatusBadRequest},
+		{"bad selector", httptest.NewRequest("POST", "/?include="+url.QueryEscape("div[["), strings.NewReader(testPage(5))), http.StatusBadRequest},
+		{"no article", httptest.NewRequest("POST", "/", strings.NewReader("<html><body></body></html>")), http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		code, result := serveRequest(t, newTestServer(1), test.r)
+		if code != test.code || result["error"] == nil {
+			t.Errorf("%s: got %d %v, want %d and an error", test.name, code, result, test.code)
+		}
+	}
+}
+
+func TestServeBusy(t *testing.T) {
+	s := newTestServer(1)
+	s.limit <- struct{}{} // an extraction is running
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(testPage(5))).WithContext(ctx)
+	code, result := serveRequest(t, s, r)
+	if code != http.StatusServiceUnavailable || result["error"] != errBusy.Error() {
+		t.Errorf("got %d %v, want %d %q", code, result, http.StatusServiceUnavailable, errBusy)
+	}
+
+	// The slot is free again once the running extraction finishes.
+	<-s.limit
+	code, _ = serveRequest(t, s, httptest.NewRequest("POST", "/", strings.NewReader(testPage(5))))
+	if code != http.StatusOK || len(s.limit) != 0 {
+		t.Errorf("got %d with %d slots taken", code, len(s.limit))
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/slyrz/newscat/html"
+	"github.com/slyrz/newscat/model"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tuneConfig is a configuration of the training evaluated by tune.
+type tuneConfig struct {
+	opts model.TrainOptions
+	eval model.Evaluation
+}
+
+func (c *tuneConfig) String() string {
+	disabled := "none"
+	if len(c.opts.Disabled) > 0 {
+		disabled = strings.Join(c.opts.Disabled, ",")
+	}
+	return fmt.Sprintf("rate=%g epochs=%d disabled=%s\tP=%.3f R=%.3f F=%.3f",
+		c.opts.Rate, c.opts.Epochs, disabled, c.eval.Precision, c.eval.Recall, c.eval.FScore)
+}
+
+// parseList parses the comma-separated numbers of a flag.
+func parseList(name, value string, bits int) []float64 {
+	result := make([]float64, 0)
+	for _, field := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), bits)
+		if err != nil {
+			log.Fatalf("-%s: %v", name, err)
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// corpusFiles returns the HTML files named by args. Directories are replaced
+// by the .html files they contain.
+func corpusFiles(args []string) []string {
+	result := make([]string, 0)
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !info.IsDir() {
+			result = append(result, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.html"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		result = append(result, matches...)
+	}
+	return result
+}
+
+// loadCorpus parses the labeled HTML files and adds them to a corpus.
+func loadCorpus(files []string, attr string, opts html.Options) *model.Corpus {
+	corpus := new(model.Corpus)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		doc, err := html.NewDocumentOptions(f, opts)
+		f.Close()
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			continue
+		}
+		if corpus.Add(doc, attr) == 0 {
+			log.Printf("%s: no chunks labeled by %s", path, attr)
+		}
+	}
+	return corpus
+}
+
+// tune searches the training configuration of the chunk model performing
+// best on a labeled corpus. Content elements of the corpus documents carry
+// the -label attribute. Every configuration is evaluated by k-fold
+// cross-validation: first all combinations of learning rates and epochs,
+// then the best of them with each feature group left out in turn.
+func tune(args []string) {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	folds := flags.Int("folds", 5, "number of cross-validation folds")
+	ratesArg := flags.String("rates", "0.01,0.03,0.1,0.3", "comma-separated learning rates")
+	epochsArg := flags.String("epochs", "5,10,20", "comma-separated numbers of epochs")
+	attr := flags.String("label", "data-content", "attribute marking the content elements")
+	output := flags.String("o", "", "file the weights of the best configuration are saved to")
+	limits := limitFlags(flags, 0)
+	flags.Parse(args)
+
+	corpus := loadCorpus(corpusFiles(flags.Args()), *attr, limits())
+	if corpus.Len() < 2 {
+		log.Fatal("tune needs at least two labeled documents")
+	}
+	rates := parseList("rates", *ratesArg, 32)
+	epochs := parseList("epochs", *epochsArg, 64)
+
+	var best *tuneConfig
+	evaluate := func(opts model.TrainOptions) {
+		eval, err := corpus.CrossValidate(opts, *folds)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config := &tuneConfig{opts, eval}
+		fmt.Println(config)
+		if best == nil || eval.FScore > best.eval.FScore {
+			best = config
+		}
+	}
+	for _, rate := range rates {
+		for _, n := range epochs {
+			evaluate(model.TrainOptions{Rate: float32(rate), Epochs: int(n)})
+		}
+	}
+	base := best.opts
+	for _, group := range model.FeatureGroups() {
+		opts := base
+		opts.Disabled = []string{group}
+		evaluate(opts)
+	}
+	fmt.Printf("\nbest: %s\n", best)
+
+	if *output == "" {
+		return
+	}
+	weights, err := corpus.Train(best.opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := weights.Save(f); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// export writes the features of a labeled corpus in the data format of
+// liblinear and libsvm to standard output, so the model can be trained by
+// these tools and loaded again by -weights.
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	boost := flags.Bool("boost", false, "export the features of the random forest instead")
+	attr := flags.String("label", "data-content", "attribute marking the content elements")
+	columns := flags.String("columns", "", "file the feature column names are written to")
+	limits := limitFlags(flags, 0)
+	flags.Parse(args)
+
+	corpus := loadCorpus(corpusFiles(flags.Args()), *attr, limits())
+	if *columns != "" {
+		names := strings.Join(model.FeatureColumns(*boost), "\n") + "\n"
+		if err := ioutil.WriteFile(*columns, []byte(names), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := corpus.Export(os.Stdout, *boost); err != nil {
+		log.Fatal(err)
+	}
+}
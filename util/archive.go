@@ -0,0 +1,223 @@
+package util
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoArchive  = errors.New("neither WARC nor MHTML archive")
+	ErrBadWARC    = errors.New("malformed WARC record")
+	ErrBadMHTML   = errors.New("malformed MHTML archive")
+	errNoHTMLPart = errors.New("no HTML part")
+)
+
+// ArchiveRecord is an HTML page stored in a web archive.
+type ArchiveRecord struct {
+	URL         string    // the URL the page was retrieved from
+	ContentType string    // the Content-Type of the page
+	Data        io.Reader // the HTML data, valid until the next call of Next
+}
+
+// ArchiveReader provides sequential access to the HTML pages of a web
+// archive. Next returns io.EOF if there are no more pages.
+type ArchiveReader interface {
+	Next() (*ArchiveRecord, error)
+}
+
+// OpenArchive returns an ArchiveReader for the WARC or MHTML archive r.
+// Gzip compressed WARC files are uncompressed on the fly.
+func OpenArchive(r io.Reader) (ArchiveReader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(5)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return NewWARCReader(zr), nil
+	case string(magic) == "WARC/":
+		return NewWARCReader(br), nil
+	}
+	return NewMHTMLReader(br)
+}
+
+// WARCReader reads the HTML pages stored in the response and resource
+// records of a WARC file. All other records are skipped.
+type WARCReader struct {
+	r     *bufio.Reader
+	block io.Reader // the unread remainder of the current record
+}
+
+// NewWARCReader creates a WARCReader reading from r.
+func NewWARCReader(r io.Reader) *WARCReader {
+	return &WARCReader{r: bufio.NewReader(r)}
+}
+
+// Next advances to the next HTML page of the WARC file.
+func (wr *WARCReader) Next() (*ArchiveRecord, error) {
+	for {
+		if wr.block != nil {
+			if _, err := io.Copy(ioutil.Discard, wr.block); err != nil {
+				return nil, err
+			}
+			wr.block = nil
+		}
+		header, err := wr.readHeader()
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return nil, ErrBadWARC
+		}
+		wr.block = io.LimitReader(wr.r, length)
+
+		record := &ArchiveRecord{URL: strings.Trim(header.Get("WARC-Target-URI"), "<>")}
+		switch header.Get("WARC-Type") {
+		case "response":
+			if !strings.HasPrefix(header.Get("Content-Type"), "application/http") {
+				continue
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(wr.block), nil)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				continue
+			}
+			record.ContentType = resp.Header.Get("Content-Type")
+			record.Data = resp.Body
+			if resp.Header.Get("Content-Encoding") == "gzip" {
+				if record.Data, err = gzip.NewReader(resp.Body); err != nil {
+					continue
+				}
+			}
+		case "resource":
+			record.ContentType = header.Get("Content-Type")
+			record.Data = wr.block
+		default:
+			continue
+		}
+		if isHTML(record.ContentType) {
+			return record, nil
+		}
+	}
+}
+
+// readHeader reads the version line and the named fields of the next record.
+func (wr *WARCReader) readHeader() (textproto.MIMEHeader, error) {
+	tr := textproto.NewReader(wr.r)
+	for {
+		line, err := tr.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		// Records are separated by empty lines.
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "WARC/") {
+			return nil, ErrBadWARC
+		}
+		break
+	}
+	header, err := tr.ReadMIMEHeader()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return header, err
+}
+
+// MHTMLReader reads the HTML parts of an MHTML archive, the format browsers
+// use to save complete pages in a single file. The first part usually is the
+// saved page, later parts are the pages of its frames.
+type MHTMLReader struct {
+	parts *multipart.Reader
+	part  *ArchiveRecord // the page of an archive without parts
+}
+
+// NewMHTMLReader creates an MHTMLReader reading from r. It returns
+// ErrNoArchive if r isn't a MIME message.
+func NewMHTMLReader(r io.Reader) (*MHTMLReader, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, ErrNoArchive
+	}
+	mediatype, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, ErrBadMHTML
+	}
+	if !strings.HasPrefix(mediatype, "multipart/") {
+		part, err := newMHTMLPart(textproto.MIMEHeader(msg.Header), msg.Body)
+		if err != nil {
+			return nil, ErrBadMHTML
+		}
+		return &MHTMLReader{part: part}, nil
+	}
+	if params["boundary"] == "" {
+		return nil, ErrBadMHTML
+	}
+	return &MHTMLReader{parts: multipart.NewReader(msg.Body, params["boundary"])}, nil
+}
+
+// Next advances to the next HTML part of the MHTML archive.
+func (mr *MHTMLReader) Next() (*ArchiveRecord, error) {
+	if mr.parts == nil {
+		part := mr.part
+		if mr.part = nil; part == nil {
+			return nil, io.EOF
+		}
+		return part, nil
+	}
+	for {
+		p, err := mr.parts.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part, err := newMHTMLPart(p.Header, p); err == nil {
+			return part, nil
+		}
+	}
+}
+
+// newMHTMLPart returns the record of the MIME part with the given header and
+// body or an error if the part isn't HTML.
+func newMHTMLPart(header textproto.MIMEHeader, body io.Reader) (*ArchiveRecord, error) {
+	record := &ArchiveRecord{
+		URL:         header.Get("Content-Location"),
+		ContentType: header.Get("Content-Type"),
+		Data:        body,
+	}
+	if !isHTML(record.ContentType) {
+		return nil, errNoHTMLPart
+	}
+	// The multipart package decodes quoted-printable parts itself and drops
+	// their Content-Transfer-Encoding, but archives without parts aren't
+	// decoded yet.
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		record.Data = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		record.Data = quotedprintable.NewReader(body)
+	}
+	return record, nil
+}
+
+func isHTML(contentType string) bool {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediatype = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediatype == "text/html" || mediatype == "application/xhtml+xml"
+}
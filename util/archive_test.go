@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func warcRecord(typ, uri, contentType, block string) string {
+	return fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\nWARC-Target-URI: %s\r\n"+
+		"Content-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		typ, uri, contentType, len(block), block)
+}
+
+const testWARC = "WARC/1.0\r\nWARC-Type: warcinfo\r\nContent-Length: 0\r\n\r\n\r\n\r\n"
+
+func readArchive(t *testing.T, ar ArchiveReader) []string {
+	result := make([]string, 0)
+	for {
+		record, err := ar.Next()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(record.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, record.URL+" "+string(data))
+	}
+}
+
+func TestWARCReader(t *testing.T) {
+	warc := testWARC +
+		warcRecord("request", "http://example.com/1", "application/http; msgtype=request",
+			"GET /1 HTTP/1.1\r\nHost: example.com\r\n\r\n") +
+		warcRecord("response", "http://example.com/1", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<p>One</p>") +
+		warcRecord("response", "http://example.com/2", "application/http; msgtype=response",
+			"HTTP/1.1 404 Not Found\r\nContent-Type: text/html\r\n\r\n<p>Missing</p>") +
+		warcRecord("response", "http://example.com/3.png", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\nPNG") +
+		warcRecord("resource", "<http://example.com/4>", "text/html; charset=utf-8", "<p>Four</p>")
+
+	want := []string{"http://example.com/1 <p>One</p>", "http://example.com/4 <p>Four</p>"}
+	compressed := new(bytes.Buffer)
+	zw := gzip.NewWriter(compressed)
+	zw.Write([]byte(warc))
+	zw.Close()
+	for _, data := range []io.Reader{strings.NewReader(warc), compressed} {
+		ar, err := OpenArchive(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readArchive(t, ar); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("unexpected records %q", got)
+		}
+	}
+}
+
+func TestMHTMLReader(t *testing.T) {
+	mhtml := strings.Replace(`From: <Saved by Blink>
+Subject: Example
+MIME-Version: 1.0
+Content-Type: multipart/related; type="text/html"; boundary="----boundary"
+
+------boundary
+Content-Type: text/html
+Content-Transfer-Encoding: quoted-printable
+Content-Location: http://example.com/
+
+<p class=3D"lead">One</p>
+------boundary
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Location: http://example.com/logo.png
+
+iVBORw0KGgo=
+------boundary
+Content-Type: text/html
+Content-Transfer-Encoding: base64
+Content-Location: http://example.com/frame
+
+PHA+VHdv
+PC9wPg==
+------boundary--
+`, "\n", "\r\n", -1)
+
+	ar, err := OpenArchive(strings.NewReader(mhtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`http://example.com/ <p class="lead">One</p>`, "http://example.com/frame <p>Two</p>"}
+	if got := readArchive(t, ar); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unexpected records %q", got)
+	}
+
+	if _, err := OpenArchive(strings.NewReader("<html></html>")); err != ErrNoArchive {
+		t.Errorf("expected ErrNoArchive, got %v", err)
+	}
+}
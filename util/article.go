@@ -1,11 +1,214 @@
 package util
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type Heading string
 type Paragraph string
 
+// MarshalJSON encodes the heading as typed JSON object, so headings and
+// paragraphs remain distinguishable in the Text list of an encoded Article.
+func (h Heading) MarshalJSON() ([]byte, error) {
+	return marshalText("heading", string(h))
+}
+
+// MarshalJSON encodes the paragraph as typed JSON object.
+func (p Paragraph) MarshalJSON() ([]byte, error) {
+	return marshalText("paragraph", string(p))
+}
+
+// Table is a data table, holding the text of its cells row by row.
+type Table [][]string
+
+// Code is a code block or other preformatted text.
+type Code string
+
+// MarshalJSON encodes the table as typed JSON object.
+func (t Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string     `json:"type"`
+		Rows [][]string `json:"rows"`
+	}{"table", [][]string(t)})
+}
+
+// MarshalJSON encodes the code block as typed JSON object.
+func (c Code) MarshalJSON() ([]byte, error) {
+	return marshalText("code", string(c))
+}
+
+// String returns the table as tab-separated values.
+func (t Table) String() string {
+	rows := make([]string, len(t))
+	for i, row := range t {
+		rows[i] = strings.Join(row, "\t")
+	}
+	return strings.Join(rows, "\n")
+}
+
+var markdownCellEscaper = strings.NewReplacer(`|`, `\|`)
+
+// Markdown returns the table in Markdown syntax. The first row is used as
+// header row. Rows shorter than the header are padded with empty cells.
+func (t Table) Markdown() string {
+	if len(t) == 0 {
+		return ""
+	}
+	columns := 0
+	for _, row := range t {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = markdownCellEscaper.Replace(row[i])
+			}
+			sb.WriteString("| " + cell + " ")
+		}
+		sb.WriteString("|\n")
+	}
+	writeRow(t[0])
+	sb.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+	for _, row := range t[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Markdown returns the code block fenced in Markdown syntax. The fence is
+// longer than any run of backticks inside of the code.
+func (c Code) Markdown() string {
+	fence := "```"
+	for strings.Contains(string(c), fence) {
+		fence += "`"
+	}
+	return fence + "\n" + string(c) + "\n" + fence
+}
+
+// Embed is a placeholder of a video or social media post embedded in the
+// article, like a YouTube video or a tweet.
+type Embed struct {
+	Provider string
+	URL      string
+}
+
+// MarshalJSON encodes the embed as typed JSON object.
+func (e Embed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string `json:"type"`
+		Provider string `json:"provider"`
+		URL      string `json:"url"`
+	}{"embed", e.Provider, e.URL})
+}
+
+// String returns the provider and the location of the embed in brackets.
+func (e Embed) String() string {
+	return "[" + e.Provider + ": " + e.URL + "]"
+}
+
+// Markdown returns a Markdown link to the embedded content.
+func (e Embed) Markdown() string {
+	return "[" + e.Provider + "](" + e.URL + ")"
+}
+
+// Quote is a quotation set off from the article text. Its paragraphs are
+// separated by newlines.
+type Quote struct {
+	Text        string
+	Attribution string // person or work the quotation is attributed to, if known
+	Source      string // location of the cited source, if known
+}
+
+// MarshalJSON encodes the quote as typed JSON object.
+func (q Quote) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Attribution string `json:"attribution,omitempty"`
+		Source      string `json:"source,omitempty"`
+	}{"quote", q.Text, q.Attribution, q.Source})
+}
+
+// String returns the text of the quote, followed by its attribution on a
+// separate line.
+func (q Quote) String() string {
+	if q.Attribution == "" {
+		return q.Text
+	}
+	return q.Text + "\n— " + q.Attribution
+}
+
+// Markdown returns the quote as Markdown blockquote. The attribution links
+// to the source.
+func (q Quote) Markdown() string {
+	lines := strings.Split(q.Text, "\n")
+	attribution := q.Attribution
+	switch {
+	case attribution != "" && q.Source != "":
+		attribution = "[" + attribution + "](" + q.Source + ")"
+	case q.Source != "":
+		attribution = "<" + q.Source + ">"
+	}
+	if attribution != "" {
+		lines = append(lines, "— "+attribution)
+	}
+	return "> " + strings.Join(lines, "\n>\n> ")
+}
+
+func marshalText(kind string, text string) ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}{kind, text})
+}
+
+// Comment is a user comment. Replies to the comment are nested.
+type Comment struct {
+	Author  string     `json:"author,omitempty"`
+	Time    string     `json:"time,omitempty"` // datetime attribute or displayed time
+	Text    string     `json:"text"`
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+// Link is a link inside of the article text. Start and End are the offsets
+// of the link text in characters, not bytes, within the heading or paragraph
+// Index of the Text list.
+type Link struct {
+	Index int    `json:"index"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	URL   string `json:"url"`
+}
+
 type Article struct {
-	Title string
-	Text  []interface{}
+	Title       string        `json:"title"`
+	AltTitles   []string      `json:"alt_titles,omitempty"`   // less likely titles, best first
+	URL         string        `json:"url,omitempty"`          // file path or URL of the page
+	Canonical   string        `json:"canonical,omitempty"`    // canonical URL of the page
+	Variant     string        `json:"variant,omitempty"`      // AMP or canonical page extracted instead
+	Image       string        `json:"image,omitempty"`        // URL of the lead image
+	Language    string        `json:"language,omitempty"`     // ISO 639-1 code
+	Date        string        `json:"date,omitempty"`         // publication date in RFC 3339 format
+	Tags        []string      `json:"tags,omitempty"`         // tags declared by the page
+	Keywords    []string      `json:"keywords,omitempty"`     // keywords found in the text, best first
+	Summary     []string      `json:"summary,omitempty"`      // most important sentences of the text
+	Fingerprint string        `json:"fingerprint,omitempty"`  // hex-encoded SimHash of the text
+	DuplicateOf string        `json:"duplicate_of,omitempty"` // URL of the article this one duplicates
+	Stats       *Stats        `json:"stats,omitempty"`
+	Confidence  float32       `json:"confidence"`         // confidence of the model, between 0 and 1
+	Fallback    bool          `json:"fallback,omitempty"` // extracted by the rule-based scorer
+	Preset      string        `json:"preset,omitempty"`   // threshold preset the article was extracted with
+	Text        []interface{} `json:"text"`
+	Links       []*Link       `json:"links,omitempty"` // links inside of the text
+	Paths       []string      `json:"paths,omitempty"` // XPaths of the elements of Text, if requested
+	Comments    []*Comment    `json:"comments,omitempty"`
 }
 
 func (a *Article) Append(v interface{}) {
@@ -14,6 +217,72 @@ func (a *Article) Append(v interface{}) {
 
 func (a *Article) Prepend(v interface{}) {
 	a.Text = append([]interface{}{v}, a.Text...)
+	if a.Paths != nil {
+		a.Paths = append([]string{""}, a.Paths...)
+	}
+	for _, link := range a.Links {
+		link.Index++
+	}
+}
+
+// Merge appends the text of other, usually the next page of a paginated
+// article, to a. Texts already present in a, like headings repeated on every
+// page, are skipped.
+func (a *Article) Merge(other *Article) {
+	seen := make(map[string]bool)
+	for _, v := range a.Text {
+		seen[fmt.Sprint(v)] = true
+	}
+	index := make(map[int]int)
+	for i, v := range other.Text {
+		if !seen[fmt.Sprint(v)] {
+			index[i] = len(a.Text)
+			a.Append(v)
+			if a.Paths != nil {
+				path := ""
+				if i < len(other.Paths) {
+					path = other.Paths[i]
+				}
+				a.Paths = append(a.Paths, path)
+			}
+		}
+	}
+	for _, link := range other.Links {
+		if i, ok := index[link.Index]; ok {
+			merged := *link
+			merged.Index = i
+			a.Links = append(a.Links, &merged)
+		}
+	}
+	if a.Fingerprint != "" {
+		a.UpdateFingerprint()
+	}
+	if a.Stats != nil && other.Stats != nil {
+		a.Stats.Merge(other.Stats)
+	}
+}
+
+// Content returns the headings and paragraphs of the article, separated by
+// newlines.
+func (a *Article) Content() string {
+	texts := make([]string, len(a.Text))
+	for i, v := range a.Text {
+		texts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// UpdateFingerprint sets the fingerprint of the article to the SimHash of
+// its content.
+func (a *Article) UpdateFingerprint() {
+	a.Fingerprint = fmt.Sprintf("%016x", SimHash(a.Content()))
+}
+
+// SimHash returns the decoded fingerprint of the article. The second return
+// value is false if the article has no valid fingerprint.
+func (a *Article) SimHash() (uint64, bool) {
+	fp, err := strconv.ParseUint(a.Fingerprint, 16, 64)
+	return fp, err == nil
 }
 
 func (a *Article) StartsWithHeading() bool {
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	table := Table{{"Name", "Votes"}, {"Smith | Jones", "42"}, {"Miller"}}
+	want := "| Name | Votes |\n| --- | --- |\n| Smith \\| Jones | 42 |\n| Miller |  |"
+	if got := table.Markdown(); got != want {
+		t.Errorf("table is %q, want %q", got, want)
+	}
+	if got := table.String(); got != "Name\tVotes\nSmith | Jones\t42\nMiller" {
+		t.Errorf("unexpected tab-separated table %q", got)
+	}
+	code := Code("x := \"```\"")
+	if got := code.Markdown(); got != "````\nx := \"```\"\n````" {
+		t.Errorf("unexpected code block %q", got)
+	}
+	quote := Quote{Text: "First.\nSecond.", Attribution: "Jane Smith", Source: "https://example.com/"}
+	if got := quote.Markdown(); got != "> First.\n>\n> Second.\n>\n> — [Jane Smith](https://example.com/)" {
+		t.Errorf("unexpected quote %q", got)
+	}
+	if got := quote.String(); got != "First.\nSecond.\n— Jane Smith" {
+		t.Errorf("unexpected plain quote %q", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := &Article{
+		Text:  []interface{}{Heading("Budget talks"), Paragraph("The council met on Tuesday."), Paragraph("See the agenda.")},
+		Links: []*Link{{Index: 2, Start: 4, End: 14, URL: "https://example.com/agenda"}},
+		Paths: []string{"/html/body/h1", "/html/body/p[1]", "/html/body/p[2]"},
+	}
+	second := &Article{
+		Text: []interface{}{Heading("Budget talks"), Paragraph("The vote was postponed."), Paragraph("Read the minutes.")},
+		Links: []*Link{
+			{Index: 0, Start: 0, End: 6, URL: "https://example.com/budget"},
+			{Index: 2, Start: 9, End: 16, URL: "https://example.com/minutes"},
+		},
+		Paths: []string{"/html/body/h1", "/html/body/p[1]", "/html/body/p[2]"},
+	}
+	first.Merge(second)
+
+	// The repeated heading and its link are dropped, the links of the
+	// other paragraphs point to their new indices.
+	want := []interface{}{Heading("Budget talks"), Paragraph("The council met on Tuesday."), Paragraph("See the agenda."),
+		Paragraph("The vote was postponed."), Paragraph("Read the minutes.")}
+	if !reflect.DeepEqual(first.Text, want) {
+		t.Errorf("merged text %v, want %v", first.Text, want)
+	}
+	if len(first.Paths) != len(want) || first.Paths[4] != "/html/body/p[2]" {
+		t.Errorf("unexpected paths %v", first.Paths)
+	}
+	if len(first.Links) != 2 {
+		t.Fatalf("got %d links, want 2", len(first.Links))
+	}
+	if link := first.Links[1]; *link != (Link{Index: 4, Start: 9, End: 16, URL: "https://example.com/minutes"}) {
+		t.Errorf("unexpected merged link %+v", link)
+	}
+	if text := fmt.Sprint(first.Text[first.Links[1].Index]); text[first.Links[1].Start:first.Links[1].End] != "minutes" {
+		t.Errorf("merged link covers %q", text[first.Links[1].Start:first.Links[1].End])
+	}
+	if second.Links[1].Index != 2 {
+		t.Error("Merge modified the links of the merged article")
+	}
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry is the header of a page stored in the cache directory. The
+// file holds the header as JSON object on the first line, followed by the
+// page.
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ContentType  string `json:"content_type,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// cachePath returns the path of the file caching the page at location.
+func (f *Fetcher) cachePath(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(f.Cache, hex.EncodeToString(sum[:]))
+}
+
+// loadCache returns the cached page at location or nil if it isn't cached.
+func (f *Fetcher) loadCache(location string) (*cacheEntry, []byte) {
+	data, err := ioutil.ReadFile(f.cachePath(location))
+	if err != nil {
+		return nil, nil
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, nil
+	}
+	entry := new(cacheEntry)
+	if err := json.Unmarshal(data[:i], entry); err != nil || entry.URL != location {
+		return nil, nil
+	}
+	return entry, data[i+1:]
+}
+
+// storeCache caches the page data at location, which resp answered. The
+// file is replaced atomically, so concurrent runs never read partial pages.
+func (f *Fetcher) storeCache(location string, resp *http.Response, data []byte) error {
+	if err := os.MkdirAll(f.Cache, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(f.Cache, ".page-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	err = json.NewEncoder(w).Encode(&cacheEntry{
+		URL:          location,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.cachePath(location))
+}
+
+// cachedResponse returns a response serving the cached page data.
+func cachedResponse(req *http.Request, entry *cacheEntry, data []byte) *http.Response {
+	header := make(http.Header)
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,263 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Number of the most frequent n-grams kept in a profile.
+	profileLen = 300
+	// Texts with fewer letters are too short for language detection.
+	minLetters = 20
+)
+
+// A profile lists the most frequent character n-grams of a text, ordered by
+// decreasing frequency. It maps every n-gram to its rank.
+type profile map[string]int
+
+// newProfile creates the n-gram profile of text. Words are lowercased and
+// padded with underscores, so n-grams at word boundaries are distinguishable
+// from n-grams inside words.
+func newProfile(text string) profile {
+	// The padded words are joined to a single string, so the n-grams are
+	// substrings of it instead of strings of their own. Words consist of
+	// letters only, so underscores always pad words.
+	var padded strings.Builder
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		padded.WriteByte('_')
+		padded.WriteString(word)
+		padded.WriteByte('_')
+	}
+	s := padded.String()
+
+	counts := make(map[string]int)
+	offsets := make([]int, 0, 32) // byte offsets of the runes of a word
+	for start := 0; start < len(s); {
+		end := start + 1 + strings.IndexByte(s[start+1:], '_') + 1
+		offsets = offsets[:0]
+		for i := range s[start:end] {
+			offsets = append(offsets, start+i)
+		}
+		offsets = append(offsets, end)
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n < len(offsets); i++ {
+				counts[s[offsets[i]:offsets[i+n]]] += 1
+			}
+		}
+		start = end
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileLen {
+		grams = grams[:profileLen]
+	}
+
+	result := make(profile, len(grams))
+	for rank, gram := range grams {
+		result[gram] = rank
+	}
+	return result
+}
+
+// distance calculates the out-of-place measure between the profiles p and q.
+// N-grams missing in q get the maximum penalty.
+func (p profile) distance(q profile) int {
+	result := 0
+	for gram, rank := range p {
+		if other, ok := q[gram]; ok {
+			if rank > other {
+				result += rank - other
+			} else {
+				result += other - rank
+			}
+		} else {
+			result += profileLen
+		}
+	}
+	return result
+}
+
+// Sample texts used to build the profiles of languages written in Latin or
+// Cyrillic script. Other scripts are mostly used by a single language and
+// therefore detected by script.
+var samples = map[string]string{
+	"en": `The government announced on Tuesday that it would increase spending
+		on public health and education, a move that was welcomed by teachers and
+		doctors across the country. The minister said the new budget would help
+		local hospitals hire more staff and reduce waiting times for patients.
+		Critics argued that the plan did not go far enough and that the money
+		would not reach the people who need it most. The opposition leader told
+		reporters that the announcement was little more than a political stunt
+		ahead of the elections. Meanwhile, thousands of workers gathered in the
+		capital to demand higher wages and better working conditions. Police said
+		the protest was peaceful and that there were no reports of violence.`,
+	"de": `Die Bundesregierung hat am Dienstag angekündigt, die Ausgaben für
+		Gesundheit und Bildung deutlich zu erhöhen. Lehrer und Ärzte im ganzen Land
+		begrüßten die Entscheidung. Der Minister sagte, mit dem neuen Haushalt
+		könnten die Krankenhäuser mehr Personal einstellen und die Wartezeiten für
+		Patienten verkürzen. Kritiker bemängelten jedoch, dass der Plan nicht weit
+		genug gehe und das Geld nicht bei den Menschen ankomme, die es am meisten
+		brauchen. Der Oppositionsführer erklärte gegenüber Journalisten, die
+		Ankündigung sei kaum mehr als ein Wahlkampfmanöver. Unterdessen haben sich
+		Tausende Beschäftigte in der Hauptstadt versammelt, um höhere Löhne und
+		bessere Arbeitsbedingungen zu fordern. Die Polizei teilte mit, die
+		Demonstration sei friedlich verlaufen.`,
+	"fr": `Le gouvernement a annoncé mardi qu'il allait augmenter les dépenses
+		consacrées à la santé et à l'éducation, une décision saluée par les
+		enseignants et les médecins dans tout le pays. Le ministre a déclaré que
+		le nouveau budget permettrait aux hôpitaux d'embaucher davantage de
+		personnel et de réduire les délais d'attente pour les patients. Les
+		critiques estiment toutefois que le plan ne va pas assez loin et que
+		l'argent n'atteindra pas ceux qui en ont le plus besoin. Le chef de
+		l'opposition a affirmé aux journalistes que cette annonce n'était qu'une
+		manœuvre électorale. Pendant ce temps, des milliers de salariés se sont
+		rassemblés dans la capitale pour réclamer des hausses de salaires et de
+		meilleures conditions de travail.`,
+	"es": `El gobierno anunció el martes que aumentará el gasto en sanidad y
+		educación, una medida que fue bien recibida por los profesores y los
+		médicos de todo el país. El ministro dijo que el nuevo presupuesto
+		permitirá a los hospitales contratar más personal y reducir las listas de
+		espera de los pacientes. Los críticos sostienen que el plan no es
+		suficiente y que el dinero no llegará a las personas que más lo
+		necesitan. El líder de la oposición afirmó ante los periodistas que el
+		anuncio no era más que una maniobra electoral. Mientras tanto, miles de
+		trabajadores se concentraron en la capital para exigir salarios más altos
+		y mejores condiciones laborales. La policía informó de que la protesta
+		transcurrió de forma pacífica.`,
+	"it": `Il governo ha annunciato martedì che aumenterà la spesa per la sanità
+		e per l'istruzione, una decisione accolta con favore dagli insegnanti e
+		dai medici di tutto il paese. Il ministro ha detto che il nuovo bilancio
+		permetterà agli ospedali di assumere più personale e di ridurre i tempi di
+		attesa per i pazienti. I critici sostengono però che il piano non è
+		sufficiente e che i soldi non arriveranno alle persone che ne hanno più
+		bisogno. Il leader dell'opposizione ha dichiarato ai giornalisti che
+		l'annuncio non è altro che una mossa elettorale. Nel frattempo migliaia di
+		lavoratori si sono radunati nella capitale per chiedere salari più alti e
+		migliori condizioni di lavoro. La polizia ha riferito che la protesta si è
+		svolta in modo pacifico.`,
+	"pt": `O governo anunciou na terça-feira que vai aumentar os gastos com
+		saúde e educação, uma medida que foi bem recebida pelos professores e
+		pelos médicos de todo o país. O ministro disse que o novo orçamento vai
+		permitir que os hospitais contratem mais funcionários e reduzam o tempo de
+		espera dos pacientes. Os críticos afirmam que o plano não é suficiente e
+		que o dinheiro não vai chegar às pessoas que mais precisam. O líder da
+		oposição disse aos jornalistas que o anúncio não passa de uma manobra
+		eleitoral. Enquanto isso, milhares de trabalhadores se reuniram na capital
+		para exigir salários mais altos e melhores condições de trabalho. A
+		polícia informou que o protesto foi pacífico e que não houve feridos.`,
+	"nl": `De regering heeft dinsdag aangekondigd dat zij meer geld gaat
+		uitgeven aan gezondheidszorg en onderwijs, een besluit dat door leraren en
+		artsen in het hele land werd verwelkomd. De minister zei dat de nieuwe
+		begroting ziekenhuizen in staat stelt meer personeel aan te nemen en de
+		wachttijden voor patiënten te verkorten. Critici vinden dat het plan niet
+		ver genoeg gaat en dat het geld niet terechtkomt bij de mensen die het het
+		hardst nodig hebben. De oppositieleider zei tegen journalisten dat de
+		aankondiging niet meer is dan een verkiezingsstunt. Ondertussen kwamen
+		duizenden werknemers in de hoofdstad bijeen om hogere lonen en betere
+		arbeidsomstandigheden te eisen. Volgens de politie verliep het protest
+		vreedzaam.`,
+	"ru": `Правительство объявило во вторник, что увеличит расходы на
+		здравоохранение и образование. Это решение приветствовали учителя и врачи
+		по всей стране. Министр заявил, что новый бюджет позволит больницам нанять
+		больше сотрудников и сократить время ожидания для пациентов. Критики
+		считают, что этого недостаточно и что деньги не дойдут до тех, кто в них
+		больше всего нуждается. Лидер оппозиции сказал журналистам, что это
+		заявление является лишь предвыборным ходом. Тем временем тысячи
+		работников собрались в столице, чтобы потребовать повышения зарплат и
+		улучшения условий труда. Полиция сообщила, что акция протеста прошла
+		мирно.`,
+}
+
+var profiles = make(map[string]profile)
+
+func init() {
+	for code, text := range samples {
+		profiles[code] = newProfile(text)
+	}
+}
+
+// Scripts used by a single major language.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	code   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// DetectLanguage returns the ISO 639-1 code of the language text is written
+// in. The language is determined by script first; texts written in Latin or
+// Cyrillic script are compared to character n-gram profiles of the major news
+// languages and get the language of the closest profile. DetectLanguage
+// returns an empty string for texts which are too short or whose script
+// isn't covered.
+func DetectLanguage(text string) string {
+	letters := 0
+	kana := 0
+	han := 0
+	scripts := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters += 1
+		switch {
+		case r < unicode.MaxLatin1:
+			continue
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana += 1
+		case unicode.Is(unicode.Han, r):
+			han += 1
+		default:
+			for i, entry := range scriptLanguages {
+				if unicode.Is(entry.script, r) {
+					scripts[i] += 1
+					break
+				}
+			}
+		}
+	}
+	if letters < minLetters {
+		return ""
+	}
+
+	// Japanese mixes kana with Han characters, Chinese uses Han only.
+	if 2*(kana+han) > letters {
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	}
+	for i, count := range scripts {
+		if 2*count > letters {
+			return scriptLanguages[i].code
+		}
+	}
+
+	best, bestDistance := "", 0
+	textProfile := newProfile(text)
+	for code, langProfile := range profiles {
+		distance := textProfile.distance(langProfile)
+		if best == "" || distance < bestDistance || (distance == bestDistance && code < best) {
+			best, bestDistance = code, distance
+		}
+	}
+	return best
+}
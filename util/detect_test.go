@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		code string
+	}{
+		{"The mayor said on Monday that the city would not support the new stadium, because the costs were far too high for taxpayers.", "en"},
+		{"Der Bürgermeister sagte am Montag, die Stadt werde das neue Stadion nicht unterstützen, weil die Kosten für die Steuerzahler viel zu hoch seien.", "de"},
+		{"Le maire a déclaré lundi que la ville ne soutiendrait pas le nouveau stade, car les coûts étaient beaucoup trop élevés pour les contribuables.", "fr"},
+		{"El alcalde dijo el lunes que la ciudad no apoyaría el nuevo estadio, porque los costes eran demasiado altos para los contribuyentes.", "es"},
+		{"Il sindaco ha detto lunedì che la città non sosterrà il nuovo stadio, perché i costi sono troppo alti per i contribuenti.", "it"},
+		{"O prefeito disse na segunda-feira que a cidade não vai apoiar o novo estádio, porque os custos são altos demais para os contribuintes.", "pt"},
+		{"De burgemeester zei maandag dat de stad het nieuwe stadion niet zal steunen, omdat de kosten veel te hoog zijn voor de belastingbetalers.", "nl"},
+		{"Мэр заявил в понедельник, что город не поддержит строительство нового стадиона, потому что расходы слишком высоки.", "ru"},
+		{"市长周一表示，由于成本对纳税人来说太高，该市不会支持新体育场的建设。", "zh"},
+		{"市長は月曜日、納税者にとって費用が高すぎるため、市は新しいスタジアムを支援しないと述べた。", "ja"},
+		{"시장은 월요일 납세자들에게 비용이 너무 높기 때문에 시가 새 경기장을 지원하지 않을 것이라고 말했다.", "ko"},
+		{"Hello", ""},
+	}
+	for _, test := range tests {
+		if code := DetectLanguage(test.text); code != test.code {
+			t.Errorf("%q: expected %q, got %q", test.text, test.code, code)
+		}
+	}
+}
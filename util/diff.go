@@ -0,0 +1,141 @@
+package util
+
+// DiffOp is the operation of a span of a word diff.
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // words found in both texts
+	DiffDelete               // words found in the old text only
+	DiffInsert               // words found in the new text only
+)
+
+// DiffSpan is a run of words of a word diff sharing the same operation.
+type DiffSpan struct {
+	Op    DiffOp
+	Words []string
+}
+
+// Maximum number of edits DiffWords searches for. Texts differing in more
+// words are reported as entirely replaced, since the search takes memory
+// quadratic in the number of edits and such texts have nothing in common
+// worth reporting.
+const maxDiffEdits = 2000
+
+// DiffWords returns the shortest edit script turning the words a into the
+// words b as spans of equal, deleted and inserted words. It implements the
+// O(ND) algorithm of Myers.
+func DiffWords(a, b []string) []DiffSpan {
+	result := make([]DiffSpan, 0)
+	add := func(op DiffOp, words ...string) {
+		if len(words) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Op == op {
+			result[n-1].Words = append(result[n-1].Words, words...)
+		} else {
+			result = append(result, DiffSpan{op, append([]string(nil), words...)})
+		}
+	}
+
+	// Common prefixes and suffixes need no search.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	add(DiffEqual, a[:prefix]...)
+	if ops := diffWords(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]); ops != nil {
+		x, y := prefix, prefix
+		for _, op := range ops {
+			switch op {
+			case DiffEqual:
+				add(op, a[x])
+				x, y = x+1, y+1
+			case DiffDelete:
+				add(op, a[x])
+				x++
+			case DiffInsert:
+				add(op, b[y])
+				y++
+			}
+		}
+	} else {
+		add(DiffDelete, a[prefix:len(a)-suffix]...)
+		add(DiffInsert, b[prefix:len(b)-suffix]...)
+	}
+	add(DiffEqual, a[len(a)-suffix:]...)
+	return result
+}
+
+// diffWords returns the operations turning a into b word by word or nil if
+// they differ in more than maxDiffEdits words.
+func diffWords(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	// v holds the furthest x reached on each diagonal k = x - y, offset by
+	// limit + 1. The trace holds v[-d-1:d+2] before each round d.
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	trace := make([][]int, 0)
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	// Walk the trace back from the end, collecting the operations in reverse.
+	ops := make([]DiffOp, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		state := trace[d]
+		at := func(k int) int { return state[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffEqual)
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, DiffInsert)
+			} else {
+				ops = append(ops, DiffDelete)
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
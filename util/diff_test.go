@@ -0,0 +1,92 @@
+package util
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	a := strings.Fields("the council met on Tuesday to discuss the budget")
+	b := strings.Fields("the city council met on Monday to discuss the budget again")
+	want := []DiffSpan{
+		{DiffEqual, []string{"the"}},
+		{DiffInsert, []string{"city"}},
+		{DiffEqual, []string{"council", "met", "on"}},
+		{DiffDelete, []string{"Tuesday"}},
+		{DiffInsert, []string{"Monday"}},
+		{DiffEqual, []string{"to", "discuss", "the", "budget"}},
+		{DiffInsert, []string{"again"}},
+	}
+	if got := DiffWords(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := DiffWords(nil, nil); len(got) != 0 {
+		t.Errorf("expected no spans for empty texts, got %v", got)
+	}
+}
+
+// lcsLen returns the length of the longest common subsequence of a and b.
+func lcsLen(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffWordsRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	words := func() []string {
+		result := make([]string, rnd.Intn(30))
+		for i := range result {
+			result[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return result
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := words(), words()
+		var before, after []string
+		equal := 0
+		for _, span := range DiffWords(a, b) {
+			switch span.Op {
+			case DiffEqual:
+				before, after = append(before, span.Words...), append(after, span.Words...)
+				equal += len(span.Words)
+			case DiffDelete:
+				before = append(before, span.Words...)
+			case DiffInsert:
+				after = append(after, span.Words...)
+			}
+		}
+		if strings.Join(before, " ") != strings.Join(a, " ") || strings.Join(after, " ") != strings.Join(b, " ") {
+			t.Fatalf("diff of %v and %v doesn't reproduce them", a, b)
+		}
+		if want := lcsLen(a, b); equal != want {
+			t.Fatalf("diff of %v and %v keeps %d words, want %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestDiffWordsLimit(t *testing.T) {
+	a, b := make([]string, 3000), make([]string, 3000)
+	for i := range a {
+		a[i], b[i] = "a", "b"
+	}
+	a[0], b[0] = "x", "x"
+	spans := DiffWords(a, b)
+	if len(spans) != 3 || spans[1].Op != DiffDelete || len(spans[1].Words) != 2999 || len(spans[2].Words) != 2999 {
+		t.Errorf("unexpected spans of replaced text: %d", len(spans))
+	}
+}
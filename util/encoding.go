@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// Decoders maps content codings to functions returning readers that decode
+// them. Fetchers accept the codings of the map and decode responses using
+// them. Brotli isn't part of the standard library; to support it, register
+// a decoder under "br", e.g. the reader of github.com/andybalholm/brotli.
+var Decoders = map[string]func(r io.Reader) (io.Reader, error){
+	"gzip":    newGzipReader,
+	"x-gzip":  newGzipReader,
+	"deflate": newDeflateReader,
+}
+
+func newGzipReader(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// newDeflateReader returns a reader decoding the deflate coding, which is
+// zlib data by the standard, but raw deflate data by many servers.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint(header[0])<<8|uint(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// acceptEncoding returns the value of the Accept-Encoding header listing the
+// codings of the Decoders.
+func acceptEncoding() string {
+	codings := make([]string, 0, len(Decoders))
+	for coding := range Decoders {
+		if !strings.HasPrefix(coding, "x-") {
+			codings = append(codings, coding)
+		}
+	}
+	sort.Strings(codings)
+	return strings.Join(codings, ", ")
+}
+
+// lenientReader ends the data at unexpected EOFs, which are caused by
+// responses shorter than their Content-Length and by truncated compressed
+// data. The data read before is kept.
+type lenientReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (r *lenientReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// decodeBody replaces the body of resp by a reader decoding its content
+// codings. Bodies compressed by gzip without declaring it, because servers
+// compressed already compressed pages, are decoded as well.
+func decodeBody(resp *http.Response) error {
+	var r io.Reader = resp.Body
+	codings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		decoder, ok := Decoders[coding]
+		if !ok {
+			return ErrUnsupportedEncoding
+		}
+		var err error
+		if r, err = decoder(r); err != nil {
+			return err
+		}
+	}
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		if gz, err := gzip.NewReader(br); err == nil {
+			r = gz
+		} else {
+			r = br
+		}
+	} else {
+		r = br
+	}
+	resp.Body = &lenientReader{r, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
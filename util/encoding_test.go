@@ -0,0 +1,123 @@
+package util
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const encodingPage = "<html><body><p>Hello, World!</p></body></html>"
+
+func compress(t *testing.T, coding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, encoding string, data []byte) (string, error) {
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {encoding}},
+		Body:   ioutil.NopCloser(bytes.NewReader(data)),
+	}
+	if err := decodeBody(resp); err != nil {
+		return "", err
+	}
+	result, err := ioutil.ReadAll(resp.Body)
+	return string(result), err
+}
+
+func TestDecodeBody(t *testing.T) {
+	page := []byte(encodingPage)
+	for _, test := range []struct {
+		encoding string
+		data     []byte
+	}{
+		{"", page},
+		{"identity", page},
+		{"gzip", compress(t, "gzip", page)},
+		{"x-gzip", compress(t, "gzip", page)},
+		{"deflate", compress(t, "zlib", page)},
+		{"deflate", compress(t, "flate", page)},
+		{"", compress(t, "gzip", page)},
+		{"gzip", compress(t, "gzip", compress(t, "gzip", page))},
+		{"deflate, gzip", compress(t, "gzip", compress(t, "zlib", page))},
+	} {
+		result, err := decode(t, test.encoding, test.data)
+		if err != nil || result != encodingPage {
+			t.Errorf("%q: unexpected result %q, %v", test.encoding, result, err)
+		}
+	}
+}
+
+func TestDecodeBodyTruncated(t *testing.T) {
+	data := compress(t, "gzip", bytes.Repeat([]byte(encodingPage), 100))
+	result, err := decode(t, "gzip", data[:len(data)/2])
+	if err != nil || len(result) == 0 {
+		t.Errorf("truncated data not decoded: %d bytes, %v", len(result), err)
+	}
+}
+
+func TestDecodeBodyUnsupported(t *testing.T) {
+	if _, err := decode(t, "br", []byte{1, 2, 3}); err != ErrUnsupportedEncoding {
+		t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestFetcherDecodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "deflate, gzip" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compress(t, "gzip", []byte(encodingPage)))
+	}))
+	defer server.Close()
+
+	resp, err := new(Fetcher).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if data, err := ioutil.ReadAll(resp.Body); err != nil || string(data) != encodingPage {
+		t.Errorf("unexpected body %q, %v", data, err)
+	}
+}
+
+func TestDecodersBrotli(t *testing.T) {
+	if coding := acceptEncoding(); coding != "deflate, gzip" {
+		t.Errorf("got Accept-Encoding %q without a Brotli decoder", coding)
+	}
+
+	// A registered decoder is asked for and used; raw deflate stands in for
+	// Brotli here.
+	Decoders["br"] = func(r io.Reader) (io.Reader, error) {
+		return flate.NewReader(r), nil
+	}
+	defer delete(Decoders, "br")
+	if coding := acceptEncoding(); coding != "br, deflate, gzip" {
+		t.Errorf("got Accept-Encoding %q with a Brotli decoder", coding)
+	}
+	if data, err := decode(t, "br", compress(t, "flate", []byte(encodingPage))); err != nil || data != encodingPage {
+		t.Errorf("unexpected body %q, %v", data, err)
+	}
+}
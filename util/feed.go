@@ -0,0 +1,106 @@
+package util
+
+import (
+	"encoding/xml"
+	"errors"
+	"golang.org/x/net/html/charset"
+	"io"
+	"strings"
+)
+
+var ErrNoFeed = errors.New("neither RSS nor Atom feed")
+
+// FeedEntry is a single item of an RSS feed or entry of an Atom feed.
+type FeedEntry struct {
+	Title string
+	Link  string
+}
+
+// The XML structures below cover RSS 0.9x/2.0 (items inside the channel),
+// RSS 1.0 (items next to the channel) and Atom (entries inside the feed).
+type rssItem struct {
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	Guid  struct {
+		Value     string `xml:",chardata"`
+		Permalink string `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+type feedXML struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// link returns the item's link. If the item has no link element, the guid is
+// used instead, given that it's a permalink.
+func (item *rssItem) link() string {
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	if item.Guid.Permalink != "false" {
+		return strings.TrimSpace(item.Guid.Value)
+	}
+	return ""
+}
+
+// link returns the entry's alternate link, which points to the HTML page.
+// Atom treats links without rel attribute as alternate links.
+func (entry *atomEntry) link() string {
+	for _, link := range entry.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if link.Type == "" || strings.Contains(link.Type, "html") {
+				return strings.TrimSpace(link.Href)
+			}
+		}
+	}
+	return ""
+}
+
+// ParseFeed returns the entries of the RSS or Atom feed read from r. Entries
+// without links are omitted. Feeds in other charsets than UTF-8, like
+// ISO-8859-1, are transcoded by the encoding of their XML declaration.
+func ParseFeed(r io.Reader) ([]FeedEntry, error) {
+	var feed feedXML
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	if err := dec.Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	result := make([]FeedEntry, 0)
+	switch feed.XMLName.Local {
+	case "rss", "RDF":
+		items := append(feed.Channel.Items, feed.Items...)
+		for i := range items {
+			if link := items[i].link(); link != "" {
+				result = append(result, FeedEntry{strings.TrimSpace(items[i].Title), link})
+			}
+		}
+	case "feed":
+		for i := range feed.Entries {
+			if link := feed.Entries[i].link(); link != "" {
+				result = append(result, FeedEntry{strings.TrimSpace(feed.Entries[i].Title), link})
+			}
+		}
+	default:
+		return nil, ErrNoFeed
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFeedRSS(t *testing.T) {
+	entries, err := ParseFeed(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>News</title>
+	<item><title>First</title><link>http://example.com/1</link></item>
+	<item><title>Second</title><guid>http://example.com/2</guid></item>
+	<item><title>Third</title><guid isPermaLink="false">id-3</guid></item>
+</channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("unexpected number of entries: %d", len(entries))
+	}
+	if entries[0].Link != "http://example.com/1" || entries[1].Link != "http://example.com/2" {
+		t.Errorf("unexpected links: %v", entries)
+	}
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	entries, err := ParseFeed(strings.NewReader(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>News</title>
+	<entry><title>First</title>
+		<link rel="self" href="http://example.com/1.atom"/>
+		<link rel="alternate" type="text/html" href="http://example.com/1"/>
+	</entry>
+	<entry><title>Second</title><link href="http://example.com/2"/></entry>
+</feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("unexpected number of entries: %d", len(entries))
+	}
+	if entries[0].Link != "http://example.com/1" || entries[1].Title != "Second" {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+}
+
+func TestParseFeedCharset(t *testing.T) {
+	for _, name := range []string{"ISO-8859-1", "windows-1252"} {
+		feed := "<?xml version=\"1.0\" encoding=\"" + name + "\"?>\n" +
+			"<rss version=\"2.0\"><channel><item><title>Caf\xe9 \x80</title><link>http://example.com/1</link></item></channel></rss>"
+		entries, err := ParseFeed(strings.NewReader(feed))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		// Both labels select windows-1252, like browsers do.
+		if len(entries) != 1 || entries[0].Title != "Café €" {
+			t.Errorf("%s: unexpected entries: %v", name, entries)
+		}
+	}
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	if _, err := ParseFeed(strings.NewReader(`<html></html>`)); err != ErrNoFeed {
+		t.Errorf("expected ErrNoFeed, got %v", err)
+	}
+}
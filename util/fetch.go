@@ -0,0 +1,355 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// Longest wait for a retry the Retry-After header of a response may ask for.
+const maxRetryAfter = time.Minute
+
+// A Fetcher retrieves pages politely: it obeys the robots.txt files of the
+// hosts, waits between requests to the same host and retries requests the
+// host rejected as too many or failed to answer. The zero Fetcher does none
+// of this and behaves like http.DefaultClient.
+//
+// If Cache names a directory, fetched pages are stored there and
+// revalidated by their ETag and Last-Modified headers when fetched again.
+// Pages are served from the cache if the host can't be reached or answers
+// 429 or 5xx. Pages are read into memory to be cached, so MaxBytes should
+// limit their size; larger pages are returned truncated to MaxBytes plus a
+// byte, telling documents they're truncated, and aren't cached.
+//
+// Responses are decoded by the Decoders, and only their codings are asked
+// for. Brotli isn't among them, so it isn't asked for unless a decoder is
+// registered.
+//
+// Fetchers are safe for concurrent use; requests to the same host are spaced
+// out nevertheless.
+type Fetcher struct {
+	Client    *http.Client  // client sending the requests, http.DefaultClient if nil
+	UserAgent string        // User-Agent header, also matched against robots.txt
+	Robots    bool          // obey robots.txt
+	Delay     time.Duration // minimum time between requests to the same host
+	Jitter    time.Duration // maximum random time added to Delay
+	Retries   int           // maximum number of retries of 429 and 5xx responses
+	Backoff   time.Duration // wait before the first retry, doubled by every retry
+	Cache     string        // directory caching fetched pages, if not empty
+	MaxBytes  int64         // maximum size of cached pages, unlimited if 0
+	Logger    *slog.Logger  // logger of requests, retries and cache hits, if not nil
+
+	// Unexported fields.
+	mu    sync.Mutex
+	hosts map[string]*fetchHost
+	rand  *rand.Rand
+}
+
+// fetchHost is the state of the requests to a single host.
+type fetchHost struct {
+	mu     sync.Mutex
+	next   time.Time // earliest time of the next request
+	robots *Robots   // rules of robots.txt once fetched
+
+	// Held while robots.txt is fetched, so it's fetched once.
+	fetchingRobots sync.Mutex
+}
+
+// host returns the state of the host of u.
+func (f *Fetcher) host(u *url.URL) *fetchHost {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hosts == nil {
+		f.hosts = make(map[string]*fetchHost)
+	}
+	key := u.Scheme + "://" + u.Host
+	h, ok := f.hosts[key]
+	if !ok {
+		h = new(fetchHost)
+		f.hosts[key] = h
+	}
+	return h
+}
+
+// jitter returns a random duration of at most Jitter.
+func (f *Fetcher) jitter() time.Duration {
+	if f.Jitter <= 0 {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rand == nil {
+		f.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return time.Duration(f.rand.Int63n(int64(f.Jitter) + 1))
+}
+
+// wait blocks until the next request to h may be sent or ctx is done. The
+// slot is reserved before waiting, so concurrent requests queue up.
+func (f *Fetcher) wait(ctx context.Context, h *fetchHost) error {
+	jitter := f.jitter()
+	h.mu.Lock()
+	delay := f.Delay
+	if h.robots != nil && h.robots.Delay() > delay {
+		delay = h.robots.Delay()
+	}
+	if delay > 0 {
+		delay += jitter
+	}
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+	return sleep(ctx, start.Sub(now))
+}
+
+// sleep blocks for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do sends a GET request for u to h once its turn has come. The header is
+// added to the request. Bodies of 200 OK responses are decoded.
+func (f *Fetcher) do(ctx context.Context, h *fetchHost, u *url.URL, header http.Header) (*http.Response, error) {
+	if err := f.wait(ctx, h); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	// Setting the header keeps the client from decoding gzip by itself, so
+	// all codings are decoded the same way.
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding())
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		Logger(f.Logger).Debug("request failed", "url", u.String(), "err", err, "elapsed", time.Since(start))
+		return resp, err
+	}
+	Logger(f.Logger).Debug("fetched", "url", u.String(), "status", resp.StatusCode, "elapsed", time.Since(start))
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	if err := decodeBody(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// robots returns the robots.txt rules of h, fetching them on first use.
+// Requests answered 429 or 5xx are retried like pages. Hosts failing to
+// answer nevertheless disallow everything, since their rules are unknown,
+// but only until the next request asks for the rules again. The rules
+// aren't kept if ctx is done while fetching them either; robots returns the
+// error of ctx then.
+func (f *Fetcher) robots(ctx context.Context, h *fetchHost, u *url.URL) (*Robots, error) {
+	h.fetchingRobots.Lock()
+	defer h.fetchingRobots.Unlock()
+	h.mu.Lock()
+	robots := h.robots
+	h.mu.Unlock()
+	if robots != nil {
+		return robots, nil
+	}
+
+	robots = &Robots{}
+	resp, err := f.get(ctx, h, &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}, nil)
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil && retryable(err):
+		return &Robots{rules: []robotsRule{{"/", false}}}, nil
+	case err == nil:
+		defer resp.Body.Close()
+		agent := f.UserAgent
+		if i := strings.IndexByte(agent, '/'); i >= 0 {
+			agent = agent[:i]
+		}
+		robots = ParseRobots(resp.Body, agent)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	h.mu.Lock()
+	h.robots = robots
+	h.mu.Unlock()
+	return robots, nil
+}
+
+// retryDelay returns the wait before retry number n of a request answered
+// by resp.
+func (f *Fetcher) retryDelay(n int, resp *http.Response) time.Duration {
+	result := f.Backoff << uint(n)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		if after := time.Duration(seconds) * time.Second; after > result {
+			result = after
+		}
+	}
+	if result > maxRetryAfter {
+		result = maxRetryAfter
+	}
+	return result
+}
+
+// Get fetches the page at location. It returns ErrDisallowed if robots.txt
+// disallows the page and a StatusError if the final response has a status
+// other than 200 OK.
+func (f *Fetcher) Get(ctx context.Context, location string) (*http.Response, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	h := f.host(u)
+	if f.Robots {
+		robots, err := f.robots(ctx, h, u)
+		if err != nil {
+			return nil, err
+		}
+		if !robots.Allowed(u.RequestURI()) {
+			Logger(f.Logger).Debug("disallowed by robots.txt", "url", location)
+			return nil, ErrDisallowed
+		}
+	}
+	if f.Cache == "" {
+		return f.get(ctx, h, u, nil)
+	}
+
+	entry, data := f.loadCache(location)
+	header := make(http.Header)
+	if entry != nil {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := f.get(ctx, h, u, header)
+	switch {
+	case entry != nil && err == nil && resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		Logger(f.Logger).Debug("not modified, serving cached page", "url", location)
+		return cachedResponse(resp.Request, entry, data), nil
+	case entry != nil && err != nil && retryable(err):
+		Logger(f.Logger).Warn("fetch failed, serving cached page", "url", location, "err", err)
+		return cachedResponse(nil, entry, data), nil
+	case err != nil:
+		return nil, err
+	case resp.StatusCode == http.StatusNotModified:
+		// The page was cached by the client itself.
+		resp.Body.Close()
+		return nil, &StatusError{resp.StatusCode, resp.Status}
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if f.MaxBytes > 0 {
+		body = io.LimitReader(body, f.MaxBytes+1)
+	}
+	data, err = ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	entry = &cacheEntry{ContentType: resp.Header.Get("Content-Type")}
+	if f.MaxBytes > 0 && int64(len(data)) > f.MaxBytes {
+		Logger(f.Logger).Debug("page too large, not cached", "url", location)
+	} else if err := f.storeCache(location, resp, data); err != nil {
+		return nil, err
+	}
+	return cachedResponse(resp.Request, entry, data), nil
+}
+
+// retryable returns true if err might go away by fetching again later.
+// Errors of a done context never do.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return err != ErrDisallowed
+}
+
+// get fetches u from h, retrying 429 and 5xx responses. Responses other
+// than 200 OK and 304 Not Modified are returned as StatusError.
+func (f *Fetcher) get(ctx context.Context, h *fetchHost, u *url.URL, header http.Header) (*http.Response, error) {
+	for n := 0; ; n++ {
+		resp, err := f.do(ctx, h, u, header)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		}
+		resp.Body.Close()
+		err = &StatusError{resp.StatusCode, resp.Status}
+		if !retryable(err) || n >= f.Retries {
+			return nil, err
+		}
+		delay := f.retryDelay(n, resp)
+		Logger(f.Logger).Info("retrying", "url", u.String(), "status", resp.StatusCode, "retry", n+1, "delay", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// OpenInput works like OpenInputContext, but fetches HTTP URLs through f.
+func (f *Fetcher) OpenInput(ctx context.Context, arg string) (Input, error) {
+	switch {
+	case arg == "":
+		return Input{Origin: "", Data: os.Stdin}, nil
+	case strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://"):
+		resp, err := f.Get(ctx, arg)
+		if err != nil {
+			return Input{}, err
+		}
+		return Input{Origin: arg, Data: resp.Body, ContentType: resp.Header.Get("Content-Type")}, nil
+	default:
+		file, err := os.Open(arg)
+		if err != nil {
+			return Input{}, err
+		}
+		return Input{Origin: arg, Data: file}, nil
+	}
+}
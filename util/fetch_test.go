@@ -0,0 +1,232 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetcherRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	f := &Fetcher{Retries: 2, Backoff: time.Millisecond}
+	resp, err := f.Get(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "<html></html>" || requests != 3 {
+		t.Errorf("unexpected response %q after %d requests", data, requests)
+	}
+
+	requests = 0
+	f.Retries = 1
+	if _, err := f.Get(context.Background(), server.URL+"/page"); err == nil {
+		t.Errorf("expected error after exhausted retries")
+	} else if status, ok := err.(*StatusError); !ok || status.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFetcherRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: newscat\nDisallow: /private\n"))
+			return
+		}
+		if r.Header.Get("User-Agent") != "newscat/1.0" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	f := &Fetcher{UserAgent: "newscat/1.0", Robots: true}
+	if _, err := f.Get(context.Background(), server.URL+"/private/page"); err != ErrDisallowed {
+		t.Errorf("expected ErrDisallowed, got %v", err)
+	}
+	resp, err := f.Get(context.Background(), server.URL+"/public/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestFetcherCacheLimits(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<p>cached</p>"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "newscat-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Pages larger than MaxBytes are truncated and not cached.
+	f := &Fetcher{Cache: dir, MaxBytes: 4}
+	for i := 0; i < 2; i++ {
+		resp, err := f.Get(context.Background(), server.URL+"/large")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != "<p>ca" {
+			t.Errorf("large page: got %q", data)
+		}
+	}
+	if entry, _ := f.loadCache(server.URL + "/large"); entry != nil {
+		t.Errorf("large page was cached")
+	}
+
+	// Cached pages aren't served once the request is cancelled.
+	f.MaxBytes = 0
+	if _, err := f.Get(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.Get(ctx, server.URL+"/page"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+func TestFetcherRobotsErrors(t *testing.T) {
+	var robotsRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsRequests, 1)
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		}
+	}))
+	defer server.Close()
+
+	// Rules fetched while the request is cancelled aren't kept.
+	f := &Fetcher{Robots: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.Get(ctx, server.URL+"/private/page"); err != context.Canceled {
+		t.Errorf("cancelled: got error %v", err)
+	}
+	if _, err := f.Get(context.Background(), server.URL+"/private/page"); err != ErrDisallowed {
+		t.Errorf("after cancelled: got error %v, want ErrDisallowed", err)
+	}
+	if _, err := f.Get(context.Background(), server.URL+"/private/other"); err != ErrDisallowed || robotsRequests != 1 {
+		t.Errorf("got error %v after %d robots.txt requests", err, robotsRequests)
+	}
+
+	// Hosts that can't be reached disallow everything.
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	if _, err := f.Get(context.Background(), down.URL+"/page"); err != ErrDisallowed {
+		t.Errorf("unreachable: got error %v, want ErrDisallowed", err)
+	}
+
+	// Hosts answering 429 or 5xx disallow everything until they recover,
+	// and are asked again by the next request.
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		var failing int32 = 1
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" && atomic.LoadInt32(&failing) == 1 {
+				w.WriteHeader(code)
+			}
+		}))
+		f := &Fetcher{Robots: true, Retries: 1, Backoff: time.Millisecond}
+		if _, err := f.Get(context.Background(), flaky.URL+"/page"); err != ErrDisallowed {
+			t.Errorf("status %d: got error %v, want ErrDisallowed", code, err)
+		}
+		atomic.StoreInt32(&failing, 0)
+		if resp, err := f.Get(context.Background(), flaky.URL+"/page"); err != nil {
+			t.Errorf("status %d: got error %v after recovery", code, err)
+		} else {
+			resp.Body.Close()
+		}
+		flaky.Close()
+	}
+}
+
+func TestFetcherDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	f := &Fetcher{Delay: 50 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := f.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("requests not delayed: %v", elapsed)
+	}
+}
+
+func TestFetcherCache(t *testing.T) {
+	var requests, revalidated int32
+	fail := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<p>cached</p>"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "newscat-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &Fetcher{Cache: dir}
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			atomic.StoreInt32(&fail, 1)
+		}
+		resp, err := f.Get(context.Background(), server.URL+"/page")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != "<p>cached</p>" || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("unexpected response %d: %q %q", i, data, resp.Header.Get("Content-Type"))
+		}
+	}
+	if requests != 3 || revalidated != 1 {
+		t.Errorf("unexpected requests: %d, revalidated %d", requests, revalidated)
+	}
+}
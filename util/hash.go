@@ -2,12 +2,25 @@ package util
 
 import "hash/fnv"
 
-var (
-	hash = fnv.New32()
-)
-
+// Hash returns the 32-bit FNV-1 hash of s. It's safe for concurrent use.
 func Hash(s string) uint32 {
-	hash.Reset()
+	hash := fnv.New32()
 	hash.Write([]byte(s))
 	return hash.Sum32()
 }
+
+// Parameters of the 64-bit FNV-1a hash.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// fnvAdd64 adds s to the 64-bit FNV-1a hash h. Unlike the hash of package
+// hash/fnv, it takes strings and therefore hashes them without copying.
+func fnvAdd64(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return h
+}
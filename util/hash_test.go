@@ -1,6 +1,7 @@
 package util
 
 import (
+	"hash/fnv"
 	"testing"
 )
 
@@ -13,3 +14,13 @@ func TestHash(t *testing.T) {
 		t.Errorf("Hash(x) == Hash(y): what are the odds?")
 	}
 }
+
+func TestFNVAdd64(t *testing.T) {
+	for _, s := range []string{"", "a", "the city council"} {
+		hash := fnv.New64a()
+		hash.Write([]byte(s))
+		if got, want := fnvAdd64(fnvOffset64, s), hash.Sum64(); got != want {
+			t.Errorf("fnvAdd64(%q) = %x, want %x", s, got, want)
+		}
+	}
+}
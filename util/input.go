@@ -1,34 +1,51 @@
 package util
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"net/http"
 	"os"
-	"strings"
 )
 
 // Input stores the user-provided data and its origin.
 type Input struct {
-	Origin string        // either file path or URL or empty if data was read from stdin
-	Data   io.ReadCloser // the HTML data (hopefully)
+	Origin      string        // either file path or URL or empty if data was read from stdin
+	Data        io.ReadCloser // the HTML data (hopefully)
+	ContentType string        // the Content-Type header of HTTP responses
+}
+
+// StatusError is returned for HTTP responses with a status other than 200 OK.
+type StatusError struct {
+	Code   int    // HTTP status code
+	Status string // HTTP status line, like "404 Not Found"
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %s", e.Status)
+}
+
+// OpenInput opens the file path or HTTP URL arg. If arg is empty, the data
+// is read from stdin.
+func OpenInput(arg string) (Input, error) {
+	return OpenInputContext(context.Background(), arg)
+}
+
+// OpenInputContext works like OpenInput, but HTTP requests are cancelled
+// once ctx is done.
+func OpenInputContext(ctx context.Context, arg string) (Input, error) {
+	return new(Fetcher).OpenInput(ctx, arg)
 }
 
 func GetInput(args []string) []Input {
 	result := make([]Input, 0)
 	if len(args) > 0 {
 		for _, arg := range args {
-			if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
-				if resp, err := http.Get(arg); err == nil {
-					result = append(result, Input{arg, resp.Body})
-				}
-			} else {
-				if file, err := os.Open(arg); err == nil {
-					result = append(result, Input{arg, file})
-				}
+			if input, err := OpenInput(arg); err == nil {
+				result = append(result, input)
 			}
 		}
 	} else {
-		result = append(result, Input{"", os.Stdin})
+		result = append(result, Input{Origin: "", Data: os.Stdin})
 	}
 	return result
 }
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parameters of the keyword ranking.
+const (
+	keywordWindow     = 4    // co-occurrence window in words
+	keywordDamping    = 0.85 // damping factor of the ranking
+	keywordIterations = 30
+	keywordMaxPhrase  = 3 // maximum number of words per key phrase
+	keywordMinLen     = 3 // minimum number of letters per keyword
+)
+
+// Keywords returns up to n keywords or key phrases of text, best first.
+// They are ranked by TextRank: the words of text form a graph, which
+// connects words occurring near each other, and words connected to important
+// words are important themselves. Top ranked words next to each other are
+// joined to key phrases. Stopwords of lang are ignored; if lang is nil, the
+// English stopwords are.
+func Keywords(text string, lang *Language, n int) []string {
+	if lang == nil {
+		lang = English
+	}
+
+	// Every token is either a candidate word or the empty string, if it's a
+	// stopword or too short. Punctuation ending a word separates phrases.
+	fields := strings.Fields(text)
+	tokens := make([]string, len(fields))
+	breaks := make([]bool, len(fields))
+	for i, field := range fields {
+		word := normalizeWord(field)
+		if utf8.RuneCountInString(word) >= keywordMinLen && !lang.IsStopword(word) {
+			tokens[i] = word
+		}
+		last, _ := utf8.DecodeLastRuneInString(field)
+		breaks[i] = !unicode.IsLetter(last)
+	}
+
+	// Build the co-occurrence graph.
+	ids := make(map[string]int)
+	words := make([]string, 0)
+	for _, token := range tokens {
+		if _, ok := ids[token]; token != "" && !ok {
+			ids[token] = len(words)
+			words = append(words, token)
+		}
+	}
+	if len(words) == 0 || n <= 0 {
+		return nil
+	}
+	edges := make([]map[int]float64, len(words))
+	for i := range edges {
+		edges[i] = make(map[int]float64)
+	}
+	for i, a := range tokens {
+		if a == "" {
+			continue
+		}
+		for j := i + 1; j < i+keywordWindow && j < len(tokens); j++ {
+			if b := tokens[j]; b != "" && b != a {
+				edges[ids[a]][ids[b]]++
+				edges[ids[b]][ids[a]]++
+			}
+		}
+	}
+	weights := make([]float64, len(words))
+	for i, edge := range edges {
+		for _, w := range edge {
+			weights[i] += w
+		}
+	}
+
+	// Rank the words.
+	scores := make([]float64, len(words))
+	for i := range scores {
+		scores[i] = 1.0
+	}
+	next := make([]float64, len(words))
+	for iter := 0; iter < keywordIterations; iter++ {
+		for i, edge := range edges {
+			sum := 0.0
+			for j, w := range edge {
+				sum += w / weights[j] * scores[j]
+			}
+			next[i] = (1 - keywordDamping) + keywordDamping*sum
+		}
+		scores, next = next, scores
+	}
+
+	// The top third of the words, but at least n words, are keywords.
+	order := make([]int, len(words))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	top := len(words) / 3
+	if top < n {
+		top = n
+	}
+	if top > len(words) {
+		top = len(words)
+	}
+	isTop := make(map[string]bool, top)
+	for _, i := range order[:top] {
+		isTop[words[i]] = true
+	}
+
+	// Join adjacent keywords to phrases.
+	phrases := make(map[string]float64)
+	run := make([]string, 0, keywordMaxPhrase)
+	score := 0.0
+	flush := func() {
+		if len(run) > 0 {
+			phrases[strings.Join(run, " ")] = score
+		}
+		run, score = run[:0], 0.0
+	}
+	for i, token := range tokens {
+		if !isTop[token] {
+			flush()
+			continue
+		}
+		if len(run) == keywordMaxPhrase {
+			flush()
+		}
+		run = append(run, token)
+		score += scores[ids[token]]
+		if breaks[i] {
+			flush()
+		}
+	}
+	flush()
+
+	result := make([]string, 0, len(phrases))
+	for phrase := range phrases {
+		result = append(result, phrase)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if phrases[result[i]] != phrases[result[j]] {
+			return phrases[result[i]] > phrases[result[j]]
+		}
+		return result[i] < result[j]
+	})
+
+	// Skip keywords already part of a better key phrase.
+	covered := make(map[string]bool)
+	keywords := make([]string, 0, n)
+	for _, phrase := range result {
+		if len(keywords) == n {
+			break
+		}
+		if covered[phrase] {
+			continue
+		}
+		for _, word := range strings.Fields(phrase) {
+			covered[word] = true
+		}
+		keywords = append(keywords, phrase)
+	}
+	return keywords
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	text := `The city council approved the new budget on Monday. Members of the
+city council argued about the budget for weeks. The mayor welcomed the
+decision of the city council, but critics said the budget ignores schools.
+Schools will receive less money next year, while the budget for roads grows.`
+
+	keywords := Keywords(text, English, 3)
+	if len(keywords) != 3 {
+		t.Fatalf("unexpected number of keywords: %v", keywords)
+	}
+	found := strings.Join(keywords, ",")
+	for _, want := range []string{"city council", "budget"} {
+		if !strings.Contains(found, want) {
+			t.Errorf("keyword %q missing in %v", want, keywords)
+		}
+	}
+	for _, keyword := range keywords {
+		if English.IsStopword(keyword) {
+			t.Errorf("stopword %q returned as keyword", keyword)
+		}
+	}
+	if Keywords("the of and", English, 5) != nil {
+		t.Error("expected no keywords for stopwords")
+	}
+}
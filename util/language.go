@@ -0,0 +1,142 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language holds the language-specific rules used to calculate text
+// statistics: a list of stopwords and a list of abbreviations, which end
+// with a period but don't end sentences.
+type Language struct {
+	Code          string // ISO 639-1 language code
+	stopwords     map[string]bool
+	abbreviations map[string]bool
+}
+
+func newLanguage(code string, stopwords string, abbreviations string) *Language {
+	lang := &Language{
+		Code:          code,
+		stopwords:     make(map[string]bool),
+		abbreviations: make(map[string]bool),
+	}
+	for _, word := range strings.Fields(stopwords) {
+		lang.stopwords[word] = true
+	}
+	for _, word := range strings.Fields(abbreviations) {
+		lang.abbreviations[word] = true
+	}
+	return lang
+}
+
+// normalizeWord lowercases word and strips leading and trailing punctuation.
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}))
+}
+
+// IsStopword returns true if word is a stopword of the language. Case and
+// surrounding punctuation are ignored.
+func (l *Language) IsStopword(word string) bool {
+	return l.stopwords[normalizeWord(word)]
+}
+
+// IsAbbreviation returns true if word is an abbreviation and its trailing
+// period therefore doesn't end a sentence.
+func (l *Language) IsAbbreviation(word string) bool {
+	return l.abbreviations[normalizeWord(word)]
+}
+
+var (
+	English = newLanguage("en", `
+		a about after all also an and any are as at be because been but by can
+		could did do does for from had has have he her his how i if in into is
+		it its just more most my no not of on one only or other our out over
+		said she so some than that the their them then there these they this
+		to up was we were what when which who will with would you your`, `
+		apr aug capt co corp dec dr feb gen gov inc jan jr jul jun lt ltd mar
+		mr mrs ms nov oct prof rep sen sep sept sgt sr st vs`)
+	German = newLanguage("de", `
+		aber als am an auch auf aus bei bin bis da dann das dass dem den der
+		des die doch du durch ein eine einem einen einer eines er es für hat
+		hatte ich ihr im in ist ja kann man mit nach nicht noch nur oder sich
+		sie sind so um und uns von vor war wie wir wird wurde zu zum zur über`, `
+		abs bzw ca dr evtl ggf hr inkl jh mio mrd nr prof str usw vgl`)
+	French = newLanguage("fr", `
+		a au aux avec ce ces dans de des du elle en est et été il ils je la le
+		les leur lui mais me même ne nous on ou par pas plus pour qu que qui sa
+		se ses son sont sur une un vous y à était`, `
+		av bd dr janv mlle mme mr prof st`)
+	Spanish = newLanguage("es", `
+		al como con de del el ella en era es esta este fue ha han la las le
+		lo los más no nos o para pero por que se sin sobre su sus también un
+		una y ya él`, `
+		av avda dr dra etc sr sra srta ud uds`)
+	Italian = newLanguage("it", `
+		a ad al alla anche che chi ci come con da dal della delle di e era è
+		gli ha hanno i il in la le lo ma ne nel non o per più quando se si
+		sono su sua suo tra un una`, `
+		dott ecc ing prof sig`)
+	Portuguese = newLanguage("pt", `
+		a ao aos as com como da das de do dos e ela ele em entre era essa
+		esse foi já mais mas na nas no nos não o os ou para pela pelo por que
+		se sem ser seu sua são também um uma é`, `
+		av dr dra etc prof sr sra`)
+	Dutch = newLanguage("nl", `
+		aan al als bij dat de den der die dit door een en er had heeft het
+		hij hoe in is ja je kan maar met naar niet nog of om ook op te tot
+		uit van voor was wat we werd wordt ze zich zijn`, `
+		bijv blz dhr dr mevr nr prof resp`)
+	Russian = newLanguage("ru", `
+		а без бы был была были было быть в вы где да для до его ее если есть
+		еще же за и из или им их к как когда кто ли мы на не него нет но о он
+		она они от по при с со так также то только у уже что это чтобы я`, `
+		г гг др им проф см тыс ул`)
+
+	// Languages detected by script. There are no stopwords or abbreviations
+	// for these yet.
+	Arabic   = newLanguage("ar", "", "")
+	Chinese  = newLanguage("zh", "", "")
+	Greek    = newLanguage("el", "", "")
+	Hebrew   = newLanguage("he", "", "")
+	Hindi    = newLanguage("hi", "", "")
+	Japanese = newLanguage("ja", "", "")
+	Korean   = newLanguage("ko", "", "")
+	Thai     = newLanguage("th", "", "")
+)
+
+// Languages lists all languages with known text statistics rules.
+var Languages = []*Language{
+	English,
+	German,
+	French,
+	Spanish,
+	Italian,
+	Portuguese,
+	Dutch,
+	Russian,
+	Arabic,
+	Chinese,
+	Greek,
+	Hebrew,
+	Hindi,
+	Japanese,
+	Korean,
+	Thai,
+}
+
+// LookupLanguage returns the language identified by the language tag,
+// e.g. "en" or "de-AT". It returns nil for unknown languages.
+func LookupLanguage(tag string) *Language {
+	code := strings.ToLower(tag)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, lang := range Languages {
+		if lang.Code == code {
+			return lang
+		}
+	}
+	return nil
+}
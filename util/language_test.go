@@ -0,0 +1,60 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestLookupLanguage(t *testing.T) {
+	if LookupLanguage("de-AT") != German {
+		t.Errorf("expected German for de-AT")
+	}
+	if LookupLanguage("EN") != English {
+		t.Errorf("expected English for EN")
+	}
+	if LookupLanguage("tlh") != nil {
+		t.Errorf("expected nil for unknown language")
+	}
+}
+
+func TestTextSentences(t *testing.T) {
+	tests := []struct {
+		lang      *Language
+		text      string
+		sentences int
+	}{
+		{English, "One sentence. Another one! A question?", 3},
+		{English, `He said "stop." Then he left.`, 2},
+		{English, "J. K. Rowling lives in the U.S. now.", 1},
+		{English, "Version 3.5 is out.", 1},
+		{English, "Mr. Smith met Dr. Jones.", 1},
+		{English, "Wait... He left. Nothing happened…", 3},
+		{German, "Das gilt u.a. für Prof. Müller.", 1},
+		{Japanese, "今日は晴れです。明日は雨です。", 2},
+		{Japanese, "A. B. C.", 3},
+
+		// Without a language, every word ending with a terminator counts.
+		{nil, "One sentence. Another one! A question?", 3},
+		{nil, `He said "stop." Then he left.`, 1},
+		{nil, "Mr. Smith met Dr. Jones.", 3},
+		{nil, "Wait... He left.", 2},
+		{nil, "今日は晴れです。明日は雨です。", 0},
+	}
+	for _, test := range tests {
+		text := NewTextLanguage(test.lang)
+		text.WriteString(test.text)
+		if text.Sentences != test.sentences {
+			t.Errorf("%q: expected %d sentences, got %d", test.text, test.sentences, text.Sentences)
+		}
+	}
+}
+
+func TestTextStopwords(t *testing.T) {
+	text := NewTextLanguage(English)
+	text.WriteString("The cat sat on the mat.")
+	if text.Stopwords != 3 {
+		t.Errorf("expected 3 stopwords, got %d", text.Stopwords)
+	}
+	if NewText().StopwordRatio() != 0.0 {
+		t.Errorf("expected zero ratio for empty text")
+	}
+}
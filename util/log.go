@@ -0,0 +1,17 @@
+package util
+
+import (
+	"log/slog"
+)
+
+// Discard is a logger dropping all records. Fetchers, documents and
+// extractors that aren't given a logger use it.
+var Discard = slog.New(slog.DiscardHandler)
+
+// Logger returns l, or Discard if l is nil.
+func Logger(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Discard
+	}
+	return l
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRule is an Allow or Disallow line of a robots.txt file.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// Robots holds the rules of a robots.txt file that apply to a user agent.
+type Robots struct {
+	rules []robotsRule
+	delay time.Duration // Crawl-delay of the group, if any
+}
+
+// robotsGroup is a group of rules and the user agents it applies to.
+type robotsGroup struct {
+	agents []string
+	Robots
+}
+
+// ParseRobots parses the robots.txt file r and returns the rules applying to
+// agent, the product token of the user agent, like "newscat". The group of
+// the longest user agent contained in agent applies, or else the group of
+// the "*" agent.
+func ParseRobots(r io.Reader, agent string) *Robots {
+	agent = strings.ToLower(agent)
+	groups := make([]*robotsGroup, 0)
+	var group *robotsGroup
+	rules := false // true if the current group has rules already
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			// Consecutive user agent lines share their group.
+			if group == nil || rules {
+				group = new(robotsGroup)
+				groups = append(groups, group)
+				rules = false
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if group == nil {
+				continue
+			}
+			rules = true
+			// An empty Disallow allows everything, like no rule at all.
+			if value != "" {
+				group.rules = append(group.rules, robotsRule{value, key == "allow"})
+			}
+		case "crawl-delay":
+			if group == nil {
+				continue
+			}
+			rules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.delay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	var best *robotsGroup
+	bestLen := -1
+	for _, g := range groups {
+		for _, a := range g.agents {
+			n := -1
+			switch {
+			case a == "*":
+				n = 0
+			case a != "" && strings.Contains(agent, a):
+				n = len(a)
+			}
+			if n > bestLen {
+				best, bestLen = g, n
+			}
+		}
+	}
+	if best == nil {
+		return &Robots{}
+	}
+	return &best.Robots
+}
+
+// Allowed returns true if the rules allow fetching path, which includes the
+// query of the URL. The longest matching rule decides; Allow rules win over
+// Disallow rules of the same length.
+func (r *Robots) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > length || (n == length && rule.allow) {
+			allow, length = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// Delay returns the Crawl-delay of the rules or zero if there is none.
+func (r *Robots) Delay() time.Duration {
+	return r.delay
+}
+
+// robotsMatch returns true if path starts with pattern. Within patterns, "*"
+// matches any sequence of characters and a trailing "$" the end of the path.
+func robotsMatch(pattern, path string) bool {
+	if pattern == "" {
+		return true
+	}
+	if pattern == "$" {
+		return path == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(path); i++ {
+			if robotsMatch(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	default:
+		return path != "" && path[0] == pattern[0] && robotsMatch(pattern[1:], path[1:])
+	}
+}
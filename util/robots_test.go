@@ -0,0 +1,54 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	const data = `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+
+User-agent: newscat
+User-agent: other
+Disallow: /search
+Disallow: /*.pdf$
+Allow: /search/about
+Crawl-delay: 2.5
+
+User-agent: googlebot
+Disallow: /
+`
+	robots := ParseRobots(strings.NewReader(data), "Newscat")
+	for path, allowed := range map[string]bool{
+		"/":                          true,
+		"/private/":                  true,
+		"/search":                    false,
+		"/search?q=news":             false,
+		"/search/about":              true,
+		"/files/report.pdf":          false,
+		"/files/report.pdf?download": true,
+	} {
+		if robots.Allowed(path) != allowed {
+			t.Errorf("Allowed(%q) != %v", path, allowed)
+		}
+	}
+	if robots.Delay() != 2500*time.Millisecond {
+		t.Errorf("unexpected delay: %v", robots.Delay())
+	}
+
+	robots = ParseRobots(strings.NewReader(data), "somebot")
+	if robots.Allowed("/private/index.html") || !robots.Allowed("/private/public.html") {
+		t.Errorf("rules of wildcard agent not applied")
+	}
+}
+
+func TestParseRobotsEmpty(t *testing.T) {
+	robots := ParseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "newscat")
+	if !robots.Allowed("/index.html") {
+		t.Errorf("empty disallow rule disallows")
+	}
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// Number of words per shingle hashed by SimHash.
+const shingleSize = 3
+
+// SimHash returns the 64-bit SimHash fingerprint of text. Every overlapping
+// sequence of three words, a shingle, is hashed. Bit i of the fingerprint is
+// set if bit i is set in most of the shingle hashes, so similar texts get
+// fingerprints differing in few bits only. Case and punctuation are ignored.
+func SimHash(text string) uint64 {
+	words := make([]string, 0)
+	for _, field := range strings.Fields(text) {
+		if word := normalizeWord(field); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return 0
+	}
+	n := shingleSize
+	if len(words) < n {
+		n = len(words)
+	}
+	var weights [64]int
+	for i := 0; i+n <= len(words); i++ {
+		// Hash the words of the shingle separated by spaces without joining
+		// them first.
+		sum := uint64(fnvOffset64)
+		for j, word := range words[i : i+n] {
+			if j > 0 {
+				sum = fnvAdd64(sum, " ")
+			}
+			sum = fnvAdd64(sum, word)
+		}
+		for b := range weights {
+			if sum&(1<<uint(b)) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+	result := uint64(0)
+	for b, w := range weights {
+		if w > 0 {
+			result |= 1 << uint(b)
+		}
+	}
+	return result
+}
+
+// HammingDistance returns the number of bits fingerprints a and b differ in.
+// Fingerprints of near-duplicates usually differ in 3 bits or less.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimHash(t *testing.T) {
+	story := `The city council approved the new budget on Monday evening after a
+long debate. The budget includes more money for schools and roads, while the
+funding of the public library was cut. Critics said the decision ignores the
+needs of families. The mayor welcomed the compromise and thanked the members
+of the council for their work during the last weeks.`
+	copied := strings.Replace(story, "Monday", "Tuesday", 1)
+	other := `Heavy rain flooded several streets in the old town on Tuesday. The
+fire brigade pumped water out of dozens of basements and the railway station
+was closed for hours. Meteorologists expect more rain during the weekend.`
+
+	if SimHash(story) != SimHash(story) {
+		t.Error("SimHash(x) != SimHash(x)")
+	}
+	if d := HammingDistance(SimHash(story), SimHash(copied)); d > 3 {
+		t.Errorf("near-duplicates differ in %d bits", d)
+	}
+	if d := HammingDistance(SimHash(story), SimHash(other)); d <= 3 {
+		t.Errorf("different texts differ in %d bits only", d)
+	}
+	if SimHash("") != 0 {
+		t.Error("expected zero fingerprint for empty text")
+	}
+}
+
+func BenchmarkSimHash(b *testing.B) {
+	s := strings.Repeat("The council met on Tuesday to discuss the budget. ", 50)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SimHash(s)
+	}
+}
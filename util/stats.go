@@ -0,0 +1,75 @@
+package util
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// Average reading speeds. Languages not separating words by whitespace are
+// read at a rate of characters rather than words per minute.
+const (
+	wordsPerMinute      = 230
+	charactersPerMinute = 500
+)
+
+// Stats are statistics of the text of an article.
+type Stats struct {
+	Words            int     `json:"words"`
+	Sentences        int     `json:"sentences"`
+	Characters       int     `json:"characters"`
+	Paragraphs       int     `json:"paragraphs"`
+	AvgSentenceWords float32 `json:"avg_sentence_words"`
+	LinkDensity      float32 `json:"link_density"` // fraction of characters inside links
+	ReadingTime      int     `json:"reading_time"` // estimated reading time in seconds
+
+	// Unexported fields.
+	linkCharacters float32
+	ideographic    bool // text counted in characters instead of words
+}
+
+// WriteText adds the text t of a heading or paragraph to the statistics.
+// linkDensity is the fraction of characters of t inside links.
+func (s *Stats) WriteText(t *Text, paragraph bool, linkDensity float32) {
+	characters := utf8.RuneCountInString(t.String())
+	s.Words += t.Words
+	s.Sentences += t.Sentences
+	s.Characters += characters
+	if paragraph {
+		s.Paragraphs++
+	}
+	s.linkCharacters += linkDensity * float32(characters)
+	if lang := t.Language(); lang != nil {
+		switch lang.Code {
+		case "ja", "ko", "zh":
+			s.ideographic = true
+		}
+	}
+	s.update()
+}
+
+// Merge adds the statistics of the text of other to s.
+func (s *Stats) Merge(other *Stats) {
+	s.Words += other.Words
+	s.Sentences += other.Sentences
+	s.Characters += other.Characters
+	s.Paragraphs += other.Paragraphs
+	s.linkCharacters += other.linkCharacters
+	s.ideographic = s.ideographic || other.ideographic
+	s.update()
+}
+
+// update recalculates the derived statistics.
+func (s *Stats) update() {
+	s.AvgSentenceWords, s.LinkDensity = 0, 0
+	if s.Sentences > 0 {
+		s.AvgSentenceWords = float32(s.Words) / float32(s.Sentences)
+	}
+	if s.Characters > 0 {
+		s.LinkDensity = s.linkCharacters / float32(s.Characters)
+	}
+	minutes := float64(s.Words) / wordsPerMinute
+	if s.ideographic {
+		minutes = float64(s.Characters) / charactersPerMinute
+	}
+	s.ReadingTime = int(math.Ceil(minutes * 60))
+}
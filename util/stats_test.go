@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	heading := NewTextLanguage(English)
+	heading.WriteString("Council approves budget")
+	paragraph := NewTextLanguage(English)
+	paragraph.WriteString("The council approved the budget yesterday. Critics weren't amused.")
+
+	stats := new(Stats)
+	stats.WriteText(heading, false, 1.0)
+	stats.WriteText(paragraph, true, 0.0)
+	if stats.Paragraphs != 1 || stats.Words != heading.Words+paragraph.Words || stats.Sentences != 2 {
+		t.Errorf("unexpected counts %+v", stats)
+	}
+	if want := float32(len("Council approves budget")) / float32(stats.Characters); stats.LinkDensity != want {
+		t.Errorf("link density %f, want %f", stats.LinkDensity, want)
+	}
+	if stats.ReadingTime != 4 {
+		t.Errorf("unexpected reading time %d", stats.ReadingTime)
+	}
+
+	stats.Merge(stats)
+	if stats.Paragraphs != 2 || stats.AvgSentenceWords != float32(stats.Words)/4 {
+		t.Errorf("unexpected merged stats %+v", stats)
+	}
+}
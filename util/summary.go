@@ -0,0 +1,113 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// Weights of the sentence features used by Summarize.
+const (
+	summaryPosition = 1.0 // earlier sentences are more important
+	summaryLead     = 0.2 // the first sentence of each paragraph
+	summaryTitle    = 1.0 // similarity to the title
+	summaryKeywords = 1.5 // fraction of keywords contained
+)
+
+// Sentences longer or shorter than this are never part of a summary.
+const (
+	summaryMinWords = 5
+	summaryMaxWords = 60
+)
+
+// Sentences splits text into sentences using the sentence rules of lang.
+// Unlike the sentence count of Text, sentences are split at whitespace only.
+func Sentences(text string, lang *Language) []string {
+	result := make([]string, 0)
+	words := make([]string, 0)
+	for _, word := range strings.Fields(text) {
+		words = append(words, word)
+		if sentenceEnds(word, lang) > 0 {
+			result = append(result, strings.Join(words, " "))
+			words = words[:0]
+		}
+	}
+	if len(words) > 0 {
+		result = append(result, strings.Join(words, " "))
+	}
+	return result
+}
+
+// Summarize returns the n sentences of paragraphs that summarize them best,
+// in the order they appear in the paragraphs. Sentences are ranked by their
+// position, their similarity to the title and the keywords they contain.
+func Summarize(paragraphs []string, title string, keywords []string, lang *Language, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	type sentence struct {
+		text  string
+		score float64
+		index int
+	}
+
+	titleText := NewText()
+	titleText.WriteString(title)
+	keywordSet := make(map[string]bool)
+	for _, keyword := range keywords {
+		for _, word := range strings.Fields(keyword) {
+			keywordSet[word] = true
+		}
+	}
+
+	split := make([][]string, len(paragraphs))
+	total := 0
+	for i, paragraph := range paragraphs {
+		split[i] = Sentences(paragraph, lang)
+		total += len(split[i])
+	}
+	candidates := make([]sentence, 0, total)
+	index := 0
+	for _, sentences := range split {
+		for i, s := range sentences {
+			index++
+			fields := strings.Fields(s)
+			if len(fields) < summaryMinWords || len(fields) > summaryMaxWords {
+				continue
+			}
+			text := NewTextLanguage(lang)
+			text.WriteString(s)
+			score := summaryPosition * (1.0 - float64(index-1)/float64(total))
+			if titleText.Words > 0 && text.Words > 0 {
+				score += summaryTitle * float64(text.Similarity(titleText))
+			}
+			if i == 0 {
+				score += summaryLead
+			}
+			if len(keywordSet) > 0 {
+				found := make(map[string]bool)
+				for _, field := range fields {
+					if word := normalizeWord(field); keywordSet[word] {
+						found[word] = true
+					}
+				}
+				score += summaryKeywords * float64(len(found)) / float64(len(keywordSet))
+			}
+			candidates = append(candidates, sentence{s, score, index})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].index < candidates[j].index
+	})
+	result := make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.text
+	}
+	return result
+}
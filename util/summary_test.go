@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestSentences(t *testing.T) {
+	got := Sentences(`Dr. Smith arrived at 5 p.m. yesterday. "Is it over?" she asked. Nobody knew`, English)
+	want := []string{`Dr. Smith arrived at 5 p.m. yesterday.`, `"Is it over?"`, `she asked.`, `Nobody knew`}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sentence %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	paragraphs := []string{
+		"The city council approved the new budget on Monday evening. It was a long meeting.",
+		"Some members of the audience left early to catch the last bus home.",
+		"The budget of the city council includes more money for schools and roads.",
+	}
+	summary := Summarize(paragraphs, "City council approves budget", []string{"budget", "city council"}, English, 2)
+	if len(summary) != 2 {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	if summary[0] != "The city council approved the new budget on Monday evening." ||
+		summary[1] != "The budget of the city council includes more money for schools and roads." {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	if Summarize(paragraphs, "", nil, English, 0) != nil {
+		t.Error("expected no summary for zero sentences")
+	}
+}
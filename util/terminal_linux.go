@@ -0,0 +1,5 @@
+package util
+
+import "syscall"
+
+const ioctlReadTermios = syscall.TCGETS
@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package util
+
+import (
+	"os"
+)
+
+// IsTerminal always returns false on platforms without terminal detection.
+func IsTerminal(file *os.File) bool {
+	return false
+}
@@ -0,0 +1,17 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package util
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal returns true if file refers to a terminal. Only terminals accept
+// the ioctl request for reading their termios settings.
+func IsTerminal(file *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlReadTermios, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
@@ -0,0 +1,13 @@
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsTerminal returns true if file refers to a console. Only consoles have
+// a console mode.
+func IsTerminal(file *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(file.Fd()), &mode) == nil
+}
@@ -1,25 +1,73 @@
 package util
 
 import (
-	"bytes"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Text struct {
 	Words     int
 	Sentences int
+	Stopwords int
 	// Unexported fields.
-	buffer bytes.Buffer
-	words  *Stringset
+	buffer strings.Builder // String returns the text without copying it
+	words  Stringset
+	tokens int       // number of whitespace-separated tokens
+	lang   *Language // language used for stopwords and abbreviations
 }
 
 func NewText() *Text {
+	return NewTextLanguage(nil)
+}
+
+// NewTextLanguage creates a Text which counts stopwords and detects sentences
+// using the rules of lang. If lang is nil, no stopwords are counted and every
+// word ending with a period, question or exclamation mark ends a sentence.
+func NewTextLanguage(lang *Language) *Text {
 	text := new(Text)
-	text.words = NewStringset()
+	text.lang = lang
 	return text
 }
 
+// NewTexts returns n empty texts using the rules of lang. The texts are
+// allocated at once, which saves allocations when creating many texts.
+func NewTexts(n int, lang *Language) []Text {
+	texts := make([]Text, n)
+	for i := range texts {
+		texts[i].lang = lang
+	}
+	return texts
+}
+
+// nextToken returns the first whitespace-separated token of s and the rest
+// of s following it. The token is empty if s holds whitespace only.
+func nextToken(s string) (token string, rest string) {
+	start := 0
+	for start < len(s) {
+		r, size := rune(s[start]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[start:])
+		}
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	end := start
+	for end < len(s) {
+		r, size := rune(s[end]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[end:])
+		}
+		if unicode.IsSpace(r) {
+			break
+		}
+		end += size
+	}
+	return s[start:end], s[end:]
+}
+
 // isWord returns true if the passed text seems to be an actual word and not
 // clutter like email addresses or URLs.
 func isWord(text string) bool {
@@ -35,20 +83,22 @@ func isWord(text string) bool {
 }
 
 func (t *Text) WriteText(s *Text) {
-        t.WriteString(s.String())
+	t.WriteString(s.String())
 }
 
 func (t *Text) WriteString(s string) {
 	// If buffer contains text, write a space first to avoid joining words
 	// accidentally.
 	needSpace := t.buffer.Len() > 0
+	t.buffer.Grow(len(s) + 1)
 
 	// Split sentence into words. Count number of words and sentences and add
 	// each word to the string set, so we can compare texts based on the number
-	// of identical words they contain.
-	for _, word := range strings.Fields(s) {
+	// of identical words they contain. The words are sliced from s instead
+	// of being split into a new slice.
+	for word, rest := nextToken(s); word != ""; word, rest = nextToken(rest) {
 		if needSpace {
-			t.buffer.WriteRune(' ')
+			t.buffer.WriteByte(' ')
 		}
 		t.buffer.WriteString(word)
 		// Check if the current word is a "real" word.
@@ -56,13 +106,86 @@ func (t *Text) WriteString(s string) {
 			t.words.Add(word)
 			t.Words += 1
 		}
+		// Stopwords tend to be short and fail the isWord test, so every token
+		// is checked.
+		if t.tokens += 1; t.lang != nil && t.lang.IsStopword(word) {
+			t.Stopwords += 1
+		}
 		// Check if the current text part ends a sentence.
+		t.Sentences += sentenceEnds(word, t.lang)
+		needSpace = true
+	}
+}
+
+// isCloser returns true if r is a closing bracket or quotation mark. These
+// may follow the punctuation ending a sentence.
+func isCloser(r rune) bool {
+	return r == '"' || r == '\'' || unicode.In(r, unicode.Pe, unicode.Pf, unicode.Pi)
+}
+
+// isAbbreviation returns true if the period at the end of word belongs to an
+// abbreviation of lang. Languages having abbreviations also abbreviate with
+// initials like "J." and words containing periods like "U.S." or "z.B.".
+// Ellipses like "Wait..." are no abbreviations.
+func isAbbreviation(word string, lang *Language) bool {
+	word = strings.TrimRightFunc(word, isCloser)
+	word = strings.TrimSuffix(word, ".")
+	switch {
+	case len(lang.abbreviations) == 0 || strings.HasSuffix(word, "."):
+		return false
+	case utf8.RuneCountInString(word) == 1 || strings.ContainsRune(word, '.'):
+		return true
+	}
+	return lang.IsAbbreviation(word)
+}
+
+// sentenceEnds returns the number of sentences ended by word. Usually a word
+// ends a sentence if its last character, ignoring closing quotation marks and
+// brackets, is a sentence terminator or an ellipsis. Languages like Chinese
+// and Japanese don't separate sentences by whitespace, so terminators
+// followed by non-ASCII characters end sentences as well. If lang is nil,
+// only the last character counts, as before languages were detected.
+func sentenceEnds(word string, lang *Language) int {
+	if lang == nil {
 		switch word[len(word)-1] {
 		case '!', '.', '?':
-			t.Sentences += 1
+			return 1
 		}
-		needSpace = true
+		return 0
+	}
+	count := 0
+	pending := false
+	last := rune(0)
+	for _, r := range word {
+		switch {
+		case r == '…' || unicode.Is(unicode.Sentence_Terminal, r):
+			pending, last = true, r
+		case isCloser(r):
+		default:
+			if pending && r >= utf8.RuneSelf {
+				count += 1
+			}
+			pending = false
+		}
+	}
+	if pending && !(last == '.' && isAbbreviation(word, lang)) {
+		count += 1
 	}
+	return count
+}
+
+// StopwordRatio returns the fraction of words that are stopwords. It returns
+// zero if the text has no language.
+func (t *Text) StopwordRatio() float32 {
+	if t.tokens == 0 {
+		return 0.0
+	}
+	return float32(t.Stopwords) / float32(t.tokens)
+}
+
+// Language returns the language of the text or nil if unknown.
+func (t *Text) Language() *Language {
+	return t.lang
 }
 
 // Calculate a word-based similarity to a given text. This function returns
@@ -79,6 +202,21 @@ func (t *Text) Similarity(u *Text) float32 {
 	}
 }
 
+// EachToken calls fn for every whitespace-separated token of the text, in
+// order. Unlike strings.Fields, it doesn't allocate.
+func (t *Text) EachToken(fn func(token string)) {
+	s := t.buffer.String()
+	for s != "" {
+		i := strings.IndexByte(s, ' ')
+		if i < 0 {
+			fn(s)
+			return
+		}
+		fn(s[:i])
+		s = s[i+1:]
+	}
+}
+
 func (t *Text) String() string {
 	return t.buffer.String()
 }
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextWriteString(t *testing.T) {
+	text := NewTextLanguage(LookupLanguage("en"))
+	text.WriteString("  The council met\n on Tuesday. ")
+	text.WriteString("It didn't decide.")
+	if got := text.String(); got != "The council met on Tuesday. It didn't decide." {
+		t.Errorf("got text %q", got)
+	}
+	if text.Words != 6 || text.Sentences != 2 {
+		t.Errorf("got %d words and %d sentences, want 6 and 2", text.Words, text.Sentences)
+	}
+	if text.Len() != len(text.String()) {
+		t.Errorf("got length %d, want %d", text.Len(), len(text.String()))
+	}
+}
+
+func BenchmarkTextWriteString(b *testing.B) {
+	s := strings.Repeat("The council met on Tuesday to discuss the budget. ", 20)
+	lang := LookupLanguage("en")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		text := NewTextLanguage(lang)
+		text.WriteString(s)
+		if text.String() == "" {
+			b.Fatal("empty text")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Host prefixes of the mobile and AMP variants of pages.
+var variantHostPrefixes = []string{"m.", "mobile.", "amp."}
+
+// DesktopURL returns the location of the canonical desktop page guessed
+// from the location of its mobile or AMP variant, following the common URL
+// patterns: hosts like m.example.com and amp.example.com, paths ending in
+// /amp or .amp, amp query parameters and the Google AMP cache. It returns
+// location unchanged if it doesn't look like a variant.
+func DesktopURL(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return location
+	}
+	// AMP cache locations look like cdn.ampproject.org/c/s/example.com/path,
+	// where the s stands for https.
+	if strings.HasSuffix(u.Host, ".cdn.ampproject.org") || u.Host == "cdn.ampproject.org" {
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+		if len(parts) >= 3 && (parts[0] == "c" || parts[0] == "v") {
+			scheme, rest := "http", parts[1:]
+			if rest[0] == "s" {
+				scheme, rest = "https", rest[1:]
+			}
+			u.Scheme, u.Host, u.Path = scheme, rest[0], ""
+			if len(rest) > 1 {
+				u.Path = "/" + rest[1]
+			}
+		}
+	}
+	host := strings.ToLower(u.Host)
+	for _, prefix := range variantHostPrefixes {
+		if strings.HasPrefix(host, prefix) && strings.Contains(host[len(prefix):], ".") {
+			u.Host = u.Host[len(prefix):]
+			break
+		}
+	}
+	switch path := u.Path; {
+	case strings.HasSuffix(path, "/amp"), strings.HasSuffix(path, "/amp/"):
+		u.Path = path[:strings.LastIndex(path, "/amp")+1]
+	case strings.HasPrefix(path, "/amp/"):
+		u.Path = path[len("/amp"):]
+	case strings.HasSuffix(path, ".amp"):
+		u.Path = strings.TrimSuffix(path, ".amp")
+	case strings.HasSuffix(path, ".amp.html"):
+		u.Path = strings.TrimSuffix(path, ".amp.html") + ".html"
+	}
+	if query := u.Query(); len(query) > 0 {
+		changed := false
+		for key, values := range query {
+			if key == "amp" || (len(values) == 1 && strings.EqualFold(values[0], "amp")) {
+				query.Del(key)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.String()
+}
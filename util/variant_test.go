@@ -0,0 +1,24 @@
+package util
+
+import "testing"
+
+func TestDesktopURL(t *testing.T) {
+	for location, expected := range map[string]string{
+		"https://example.com/news/story":                               "https://example.com/news/story",
+		"https://m.example.com/news/story":                             "https://example.com/news/story",
+		"https://amp.example.com/news/story":                           "https://example.com/news/story",
+		"https://m.com/news/story":                                     "https://m.com/news/story",
+		"https://example.com/news/story/amp/":                          "https://example.com/news/story/",
+		"https://example.com/amp/news/story":                           "https://example.com/news/story",
+		"https://example.com/news/12345.amp":                           "https://example.com/news/12345",
+		"https://example.com/news/story.amp.html":                      "https://example.com/news/story.html",
+		"https://example.com/news/story?amp=1&page=2":                  "https://example.com/news/story?page=2",
+		"https://example.com/news/story?outputType=amp":                "https://example.com/news/story",
+		"https://example-com.cdn.ampproject.org/c/s/example.com/story": "https://example.com/story",
+		"example.html": "example.html",
+	} {
+		if got := DesktopURL(location); got != expected {
+			t.Errorf("DesktopURL(%q) = %q, expected %q", location, got, expected)
+		}
+	}
+}